@@ -0,0 +1,214 @@
+package backend
+
+import (
+	"errors"
+
+	"github.com/phoenixdevelops/fliw/data"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/*
+###########################
+# Section: Backend
+###########################
+*/
+
+// Backend is the behavior part of a module.
+// It holds the variables and functions used in the xml files
+// and gets notified about the lifecycle of the window.
+type Backend interface {
+	// GetVariable gets the value of a variable
+	GetVariable(name string) (string, error)
+
+	// CallFunction calls a function with already evaluated
+	// arguments and returns its return value
+	CallFunction(name string, args []string) (string, error)
+
+	// CheckVariable checks if the backend has a variable
+	// with that name which can be read by GetVariable
+	CheckVariable(name string) error
+
+	// CheckFunction checks if the backend has a function with that name
+	// which can be called by CallFunction. If hasReturn is set,
+	// the function also has to return a string
+	CheckFunction(name string, hasArgs bool, hasReturn bool) error
+
+	// Init is called once before the first frame is drawn
+	Init(*data.BaseContainer, *bool)
+
+	// Update is called every frame
+	Update()
+
+	// HandleEvent is called for every sdl event the window receives
+	HandleEvent(sdl.Event)
+
+	// Close releases the resources of the backend
+	Close()
+}
+
+// unused function that will fail to compile
+// if any of the listed structs are not in the interface
+func checkInterfaceSatisfaction() {
+	var _ Backend = (*Plugin)(nil)
+	var _ Backend = (*Remote)(nil)
+	var _ Backend = (*Memory)(nil)
+
+	var _ SymbolResolver = (*Plugin)(nil)
+	var _ SymbolResolver = (*Memory)(nil)
+}
+
+// Call calls a function given as string, e.g.
+// "GetName($index, 3*2)". The arguments get evaluated first.
+func Call(b Backend, function string) (returned string, err error) {
+	expr, err := ParseExpression("@" + function)
+	if err != nil {
+		return
+	}
+
+	if _, ok := expr.(callExpr); !ok {
+		return "", errors.New("Invalid function call: " + function)
+	}
+
+	value, err := expr.Eval(b)
+	if err != nil {
+		return
+	}
+
+	return value.String(), nil
+}
+
+// PreParseString preparses a string
+// $ or @ prefix will evaluate the string as expression,
+// e.g. "$name" or "@GetWidth() - 10"
+// Everything else will return the original value
+func PreParseString(b Backend, str string) (val string, err error) {
+	// return if string is empty
+	if str == "" {
+		return "", nil
+	}
+
+	if string(str[0]) == "$" || string(str[0]) == "@" {
+		return CalculateValue(str, b)
+	}
+
+	return str, nil
+}
+
+// OpenBackend opens a backend. Paths ending with .so are opened
+// as go plugin, everything else is started as remote backend
+// (see OpenRemote)
+func OpenBackend(path string) (b Backend, err error) {
+	if !IsRemotePath(path) {
+		plug, err := OpenPluginFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return plug, nil
+	}
+
+	remote, err := OpenRemote(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return remote, nil
+}
+
+/*
+###########################
+# Section: Memory
+###########################
+*/
+
+// Memory is a backend holding its variables and functions in maps.
+// It can be used to test xml files without building a plugin.
+// All fields are optional.
+type Memory struct {
+	Variables map[string]*string
+	Functions map[string]func(...string) string
+
+	InitFunc        func(*data.BaseContainer, *bool)
+	UpdateFunc      func()
+	HandleEventFunc func(sdl.Event)
+}
+
+// GetVariable gets a variable from the variables map
+func (m *Memory) GetVariable(name string) (value string, err error) {
+	if variable, ok := m.Variables[name]; ok {
+		return *variable, nil
+	}
+
+	return "", errors.New("No variable named " + name)
+}
+
+// CallFunction calls a function from the functions map
+func (m *Memory) CallFunction(name string, args []string) (returned string, err error) {
+	if function, ok := m.Functions[name]; ok {
+		return function(args...), nil
+	}
+
+	return "", errors.New("No function named " + name)
+}
+
+// ResolveVariable gets a function reading a variable from the variables map
+func (m *Memory) ResolveVariable(name string) (get func() (string, error), err error) {
+	variable, ok := m.Variables[name]
+	if !ok {
+		return nil, errors.New("No variable named " + name)
+	}
+
+	return func() (string, error) { return *variable, nil }, nil
+}
+
+// ResolveFunction gets a function calling a function from the functions map
+func (m *Memory) ResolveFunction(name string) (call func([]string) (string, error), err error) {
+	function, ok := m.Functions[name]
+	if !ok {
+		return nil, errors.New("No function named " + name)
+	}
+
+	return func(args []string) (string, error) { return function(args...), nil }, nil
+}
+
+// CheckVariable checks if the variables map contains the variable
+func (m *Memory) CheckVariable(name string) (err error) {
+	if _, ok := m.Variables[name]; !ok {
+		return errors.New("No variable named " + name)
+	}
+
+	return nil
+}
+
+// CheckFunction checks if the functions map contains the function.
+// All functions in the map take any number of arguments and return a string.
+func (m *Memory) CheckFunction(name string, hasArgs bool, hasReturn bool) (err error) {
+	if _, ok := m.Functions[name]; !ok {
+		return errors.New("No function named " + name)
+	}
+
+	return nil
+}
+
+// Init calls InitFunc
+func (m *Memory) Init(cont *data.BaseContainer, running *bool) {
+	if m.InitFunc != nil {
+		m.InitFunc(cont, running)
+	}
+}
+
+// Update calls UpdateFunc
+func (m *Memory) Update() {
+	if m.UpdateFunc != nil {
+		m.UpdateFunc()
+	}
+}
+
+// HandleEvent calls HandleEventFunc
+func (m *Memory) HandleEvent(event sdl.Event) {
+	if m.HandleEventFunc != nil {
+		m.HandleEventFunc(event)
+	}
+}
+
+// Close does nothing, as there is nothing to release
+func (m *Memory) Close() {}
@@ -0,0 +1,70 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+)
+
+func newTestMemory() *Memory {
+	radius := "3"
+	name := "fliw"
+	number := "007"
+
+	return &Memory{
+		Variables: map[string]*string{"r": &radius, "name": &name, "number": &number},
+		Functions: map[string]func(...string) string{
+			"GetTwo": func(...string) string { return "2" },
+			"Upper":  func(args ...string) string { return strings.ToUpper(strings.Join(args, "")) },
+		},
+	}
+}
+
+func TestPreParseString(t *testing.T) {
+	mem := newTestMemory()
+
+	tests := map[string]string{
+		`$name`:         `fliw`,
+		`@GetTwo()`:     `2`,
+		`@Upper(ab)`:    `AB`,
+		`$number`:       `007`,
+		`@Upper(3-1)`:   `2`,
+		`@Upper('3-1')`: `3-1`,
+		`plain`:         `plain`,
+		``:              ``,
+	}
+
+	for str, expected := range tests {
+		result, err := PreParseString(mem, str)
+		if err != nil {
+			t.Error(err)
+		}
+		if result != expected {
+			t.Error("Expected ", expected, ", gave ", result)
+		}
+	}
+
+	// missing variables and functions don't stop the program
+	if _, err := PreParseString(mem, `$missing`); err == nil {
+		t.Error("Expected an error for a missing variable")
+	}
+	if _, err := PreParseString(mem, `@Missing()`); err == nil {
+		t.Error("Expected an error for a missing function")
+	}
+}
+
+func TestMemoryCheck(t *testing.T) {
+	mem := newTestMemory()
+
+	if err := mem.CheckVariable("r"); err != nil {
+		t.Error(err)
+	}
+	if err := mem.CheckVariable("missing"); err == nil {
+		t.Error("Expected an error for a missing variable")
+	}
+	if err := mem.CheckFunction("GetTwo", false, true); err != nil {
+		t.Error(err)
+	}
+	if err := mem.CheckFunction("missing", false, true); err == nil {
+		t.Error("Expected an error for a missing function")
+	}
+}
@@ -1,9 +1,9 @@
 package backend
 
 import (
+	"errors"
 	"log"
 	"regexp"
-	"runtime/debug"
 
 	"github.com/phoenixdevelops/fliw/data"
 	"github.com/phoenixdevelops/fliw/input"
@@ -26,19 +26,21 @@ const (
 type Event struct {
 	Name          EventName
 	MousePosition data.Vector
+
+	// Key is the name of the key for keyboard events
+	Key string
 }
 
-var plugins map[string]*Plugin = make(map[string]*Plugin)
+var backends map[string]Backend = make(map[string]Backend)
 var baseContainer *data.BaseContainer
 
-var pathRegex *regexp.Regexp
+var pathRegex = regexp.MustCompile(`([[:graph:]]+/)+`)
 
 // Init should be called before using any other funtions
-// in this package
+// in this package. It is called again with the container
+// of every frame, so events go to the items drawn
 func Init(basecontainer *data.BaseContainer) {
 	baseContainer = basecontainer
-
-	pathRegex = regexp.MustCompile(`([[:graph:]]+/)+`)
 }
 
 // Invoke invokes an event
@@ -73,9 +75,11 @@ func Invoke(event Event) {
 				}
 			}
 
+			// the position is relative to the item from now on
+			position := data.ItemPosition(container, item)
 			container = val
-			event.MousePosition.X -= item.GetPosition().X
-			event.MousePosition.Y -= item.GetPosition().Y
+			event.MousePosition.X -= position.X
+			event.MousePosition.Y -= position.Y
 
 		} else {
 			if ev := item.GetEvent(string(event.Name)); ev != "" {
@@ -86,33 +90,38 @@ func Invoke(event Event) {
 	}
 }
 
-// AddPlugin adds a plugin file
+// AddPlugin adds a backend file (see OpenBackend)
 // if the specified path was not added before
-func AddPlugin(path string) {
-	if _, ok := plugins[path]; !ok {
-		plugin, err := OpenPluginFile(path)
+func AddPlugin(path string) (err error) {
+	if _, ok := backends[path]; !ok {
+		backend, err := OpenBackend(path)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 
-		plugins[path] = plugin
+		backends[path] = backend
 	}
+
+	return nil
 }
 
-// GetPlugin gets you the plugin matching a go .so file
-func GetPlugin(path string) (plugin *Plugin) {
+// RegisterBackend registers a backend under a path,
+// so it can be used as if it was loaded from that path
+func RegisterBackend(path string, backend Backend) {
+	backends[path] = backend
+}
+
+// GetBackend gets you the backend matching a path
+func GetBackend(path string) (backend Backend, err error) {
 	if path == "" {
-		debug.PrintStack()
-		log.Fatal("Tried to get plugin from empty string")
+		return nil, errors.New("Tried to get backend from empty string")
 	}
 
-	if result, ok := plugins[path]; ok {
-		return result
+	if result, ok := backends[path]; ok && result != nil {
+		return result, nil
 	}
 
-	debug.PrintStack()
-	log.Fatal("Tried to get plugin where no plugin was registered: ", path)
-	return
+	return nil, errors.New("Tried to get backend where no backend was registered: " + path)
 }
 
 var isMouseClicked = false
@@ -123,24 +132,24 @@ func InvokeSDLEvent(event sdl.Event) {
 	case *sdl.MouseButtonEvent:
 		if t.Button == sdl.BUTTON_LEFT && !isMouseClicked {
 			isMouseClicked = true
-			Invoke(Event{MouseclickEvent, data.Vector{X: t.X, Y: t.Y}})
+			Invoke(Event{Name: MouseclickEvent, MousePosition: data.Vector{X: t.X, Y: t.Y}})
 		} else if t.Button == sdl.BUTTON_RIGHT && !isMouseClicked {
 			isMouseClicked = true
-			Invoke(Event{MouserightclickEvent, data.Vector{X: t.X, Y: t.Y}})
+			Invoke(Event{Name: MouserightclickEvent, MousePosition: data.Vector{X: t.X, Y: t.Y}})
 		} else if isMouseClicked {
 			isMouseClicked = false
-			Invoke(Event{MousereleaseEvent, data.Vector{X: t.X, Y: t.Y}})
+			Invoke(Event{Name: MousereleaseEvent, MousePosition: data.Vector{X: t.X, Y: t.Y}})
 		}
 	case *sdl.KeyboardEvent:
 		if t.GetType() == sdl.KEYDOWN {
 			if isevent := input.PressKey(t.Keysym.Sym); isevent {
 				x, y, _ := sdl.GetMouseState()
-				Invoke(Event{KeydownEvent, data.Vector{X: x, Y: y}})
+				Invoke(Event{Name: KeydownEvent, MousePosition: data.Vector{X: x, Y: y}, Key: sdl.GetKeyName(t.Keysym.Sym)})
 			}
 		} else if t.GetType() == sdl.KEYUP {
 			if isevent := input.PressKey(t.Keysym.Sym); isevent {
 				x, y, _ := sdl.GetMouseState()
-				Invoke(Event{KeyupEvent, data.Vector{X: x, Y: y}})
+				Invoke(Event{Name: KeyupEvent, MousePosition: data.Vector{X: x, Y: y}, Key: sdl.GetKeyName(t.Keysym.Sym)})
 			}
 		}
 	}
@@ -149,6 +158,50 @@ func InvokeSDLEvent(event sdl.Event) {
 // calls a function based on its path and its name
 func callFunction(function string) {
 	path := pathRegex.FindString(function)
+	if path == "" {
+		log.Println("Function without a backend: " + function)
+		return
+	}
+
+	backend, err := GetBackend(path[:len(path)-1])
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	_, err = Call(backend, function[len(path):])
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// ToEvent converts an sdl event to an event.
+// ok is false if the sdl event has no equivalent
+func ToEvent(event sdl.Event) (ev Event, ok bool) {
+	switch t := event.(type) {
+	case *sdl.MouseButtonEvent:
+		ev.MousePosition = data.Vector{X: t.X, Y: t.Y}
+		if t.State == sdl.RELEASED {
+			ev.Name = MousereleaseEvent
+		} else if t.Button == sdl.BUTTON_LEFT {
+			ev.Name = MouseclickEvent
+		} else if t.Button == sdl.BUTTON_RIGHT {
+			ev.Name = MouserightclickEvent
+		} else {
+			return ev, false
+		}
+	case *sdl.KeyboardEvent:
+		x, y, _ := sdl.GetMouseState()
+		ev.MousePosition = data.Vector{X: x, Y: y}
+		ev.Key = sdl.GetKeyName(t.Keysym.Sym)
+		if t.GetType() == sdl.KEYDOWN {
+			ev.Name = KeydownEvent
+		} else {
+			ev.Name = KeyupEvent
+		}
+	default:
+		return ev, false
+	}
 
-	plugins[path[:len(path)-1]].CallFunction(function[len(path):])
+	return ev, true
 }
@@ -1 +1,22 @@
 package backend
+
+import "testing"
+
+func TestGetBackend(t *testing.T) {
+	memory := &Memory{}
+	RegisterBackend("/test/event.so", memory)
+
+	if backend, err := GetBackend("/test/event.so"); err != nil || backend != memory {
+		t.Error("Expected the registered backend, gave ", backend, err)
+	}
+
+	for _, path := range []string{"", "/test/missing.so"} {
+		if _, err := GetBackend(path); err == nil {
+			t.Error("Expected an error for the backend at '", path, "'")
+		}
+	}
+
+	// functions without a backend are not called
+	callFunction("Missing()")
+	callFunction("/test/missing.so/Missing()")
+}
@@ -1,105 +1,150 @@
 package backend
 
 import (
-	"log"
+	"errors"
+	"os"
+	"path/filepath"
 	"plugin"
-	"strings"
+
+	"github.com/phoenixdevelops/fliw/data"
+	"github.com/veandco/go-sdl2/sdl"
 )
 
 /*
 handles app.so data
 */
 
+// the name of the plugin fliw build builds each package to
+const packagePlugin = "app.so"
+
 // Plugin holds a golang plugin
 type Plugin struct {
 	plug plugin.Plugin
+
+	// optional lifecycle functions of the plugin
+	initialize  func(*data.BaseContainer, *bool)
+	update      func()
+	handleevent func(sdl.Event)
 }
 
 // OpenPluginFile opens a go plugin file.
 // Default file ending is .so
 func OpenPluginFile(path string) (plug *Plugin, err error) {
-	plugpointer, err := plugin.Open(path)
+	plugpointer, err := plugin.Open(findPluginFile(path))
 	// not checking before dereferencing the pointer
 	// results in a segmentation violation
 	if err != nil {
 		return
 	}
 
-	return &Plugin{*plugpointer}, err
+	plug = &Plugin{plug: *plugpointer}
+
+	// Get a function for initializing, updating and eventhandling
+	var ok bool
+
+	if symInitializer, err := plug.plug.Lookup("Init"); err == nil {
+		plug.initialize, ok = symInitializer.(func(*data.BaseContainer, *bool))
+		if !ok {
+			return nil, errors.New("Could not cast Init() function in " + path)
+		}
+	}
+
+	if symUpdater, err := plug.plug.Lookup("Update"); err == nil {
+		plug.update, ok = symUpdater.(func())
+		if !ok {
+			return nil, errors.New("Could not cast Update() function in " + path)
+		}
+	}
+
+	if symHandler, err := plug.plug.Lookup("HandleEvent"); err == nil {
+		plug.handleevent, ok = symHandler.(func(sdl.Event))
+		if !ok {
+			return nil, errors.New("Could not cast HandleEvent() function in " + path)
+		}
+	}
+
+	return plug, nil
+}
+
+// gets the file a plugin path refers to.
+// fliw build used to build every source file to its own plugin
+// (battery.go to battery.so), now every package is built to app.so.
+// Paths to such old plugins fall back to the app.so next to them
+func findPluginFile(path string) string {
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+
+	fallback := filepath.Join(filepath.Dir(path), packagePlugin)
+	if _, err := os.Stat(fallback); err == nil {
+		return fallback
+	}
+
+	return path
 }
 
 // GetVariable gets a variable from the plugin file.
-func (p *Plugin) GetVariable(name string) (value string) {
-	symVal, err := p.plug.Lookup(name)
+func (p *Plugin) GetVariable(name string) (value string, err error) {
+	get, err := p.ResolveVariable(name)
 	if err != nil {
-		log.Fatal(err)
 		return
 	}
 
-	valuepointer, ok := symVal.(*string)
-	if !ok {
-		log.Fatal("Could not cast value of variable ", name)
-	}
-
-	return *valuepointer
+	return get()
 }
 
 // CallFunction calls a function inside the plugin file.
 // The function has to return a string
-func (p *Plugin) CallFunction(function string) (returned string) {
-	var name string
-	var args []string
-
-	// separate function name and function arguments
-	for i, c := range function {
-		if c == '(' {
-			name = function[:i]
-			args = strings.Split(function[i+1:len(function)-1], ",")
-			break
-		}
+func (p *Plugin) CallFunction(name string, args []string) (returned string, err error) {
+	symFunc, err := p.plug.Lookup(name)
+	if err != nil {
+		return
 	}
 
-	// clean up arguments (in case there are spaces before the name)
-	// and evaluate them
-	for i, arg := range args {
-		for strings.HasPrefix(arg, " ") {
-			arg = arg[1:]
-		}
+	return callSymbol(name, symFunc, args)
+}
 
-		// if the argument is empty, remove it from the list
-		// and skip it
-		if arg == "" {
-			args = append(args[:i], args[i+1:]...)
-			continue
-		}
+// ResolveVariable looks up a variable once and gets a function reading it
+func (p *Plugin) ResolveVariable(name string) (get func() (string, error), err error) {
+	symVal, err := p.plug.Lookup(name)
+	if err != nil {
+		return
+	}
 
-		// if there are any operands in the string
-		// or the string starts with brackets
-		if strings.ContainsAny(arg, "+-*/^") || string(arg[0]) == "(" {
-			// evaluate the argument
-			args[i] = CalculateValue(arg, p)
-		}
+	valuepointer, ok := symVal.(*string)
+	if !ok {
+		return nil, errors.New("Could not cast value of variable " + name)
 	}
 
+	return func() (string, error) { return *valuepointer, nil }, nil
+}
+
+// ResolveFunction looks up a function once and gets a function calling it
+func (p *Plugin) ResolveFunction(name string) (call func([]string) (string, error), err error) {
 	symFunc, err := p.plug.Lookup(name)
 	if err != nil {
-		log.Fatal(err)
 		return
 	}
 
+	return func(args []string) (string, error) {
+		return callSymbol(name, symFunc, args)
+	}, nil
+}
+
+// calls a function symbol of a plugin
+func callSymbol(name string, symFunc plugin.Symbol, args []string) (returned string, err error) {
 	if len(args) > 0 {
 		value, ok := symFunc.(func(...string) string)
 		if !ok {
 			value, ok := symFunc.(func(...string))
 			if !ok {
-				log.Fatal("Could not cast function with arguments: ", name)
-				return ""
+				return "", errors.New("Could not cast function with arguments: " + name)
 			}
 
 			value(args...)
-			return ""
+			return "", nil
 		}
-		return value(args...)
+		return value(args...), nil
 
 	}
 
@@ -107,37 +152,89 @@ func (p *Plugin) CallFunction(function string) (returned string) {
 	if !ok {
 		value, ok := symFunc.(func())
 		if !ok {
-			log.Fatal("Could not cast function: ", name)
-			return ""
+			return "", errors.New("Could not cast function: " + name)
 		}
 
 		value()
-		return ""
+		return "", nil
 	}
 
-	return value()
+	return value(), nil
 }
 
+// Init calls the Init function of the plugin, if there is one
+func (p *Plugin) Init(cont *data.BaseContainer, running *bool) {
+	if p.initialize != nil {
+		p.initialize(cont, running)
+	}
+}
+
+// Update calls the Update function of the plugin, if there is one
+func (p *Plugin) Update() {
+	if p.update != nil {
+		p.update()
+	}
+}
+
+// HandleEvent calls the HandleEvent function of the plugin, if there is one
+func (p *Plugin) HandleEvent(event sdl.Event) {
+	if p.handleevent != nil {
+		p.handleevent(event)
+	}
+}
+
+// Close does nothing, go plugins can't be closed.
+func (p *Plugin) Close() {}
+
 // GetPlugin gets you a pointer to the go plugin
 func (p *Plugin) GetPlugin() *plugin.Plugin {
 	return &p.plug
 }
 
-// PreParseString preparses a string
-// $ prefix will return the value of a variable with that name
-// @ prefix will return the return value of a function with that name
-// Everything else will return the original value
-func (p *Plugin) PreParseString(str string) (val string) {
-	// return if string is empty
-	if str == "" {
-		return ""
+// CheckVariable checks if the plugin has a variable
+// with that name which can be read by GetVariable
+func (p *Plugin) CheckVariable(name string) (err error) {
+	symVal, err := p.plug.Lookup(name)
+	if err != nil {
+		return
+	}
+
+	if _, ok := symVal.(*string); !ok {
+		return errors.New("Variable " + name + " is not of type string")
+	}
+
+	return nil
+}
+
+// CheckFunction checks if the plugin has a function with that name
+// which can be called by CallFunction. If hasReturn is set,
+// the function also has to return a string
+func (p *Plugin) CheckFunction(name string, hasArgs bool, hasReturn bool) (err error) {
+	symFunc, err := p.plug.Lookup(name)
+	if err != nil {
+		return
 	}
 
-	if string(str[0]) == "$" {
-		return p.GetVariable(str[1:])
-	} else if string(str[0]) == "@" {
-		return p.CallFunction(str[1:])
+	var ok bool
+	var signature string
+
+	if hasArgs {
+		signature = "func(...string) string"
+		_, ok = symFunc.(func(...string) string)
+		if !ok && !hasReturn {
+			_, ok = symFunc.(func(...string))
+		}
+	} else {
+		signature = "func() string"
+		_, ok = symFunc.(func() string)
+		if !ok && !hasReturn {
+			_, ok = symFunc.(func())
+		}
+	}
+
+	if !ok {
+		return errors.New("Function " + name + " does not have the signature " + signature)
 	}
 
-	return str
+	return nil
 }
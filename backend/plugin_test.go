@@ -1 +1,35 @@
 package backend
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindPluginFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fliwplugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	apppath := filepath.Join(dir, "app.so")
+	oldpath := filepath.Join(dir, "battery.so")
+
+	// nothing built yet, the path is kept for the error message
+	if result := findPluginFile(oldpath); result != oldpath {
+		t.Error("Expected: ", oldpath, ", Gave: ", result)
+	}
+
+	ioutil.WriteFile(apppath, nil, 0644)
+	if result := findPluginFile(oldpath); result != apppath {
+		t.Error("Expected: ", apppath, ", Gave: ", result)
+	}
+
+	// plugins which still exist under their old name are preferred
+	ioutil.WriteFile(oldpath, nil, 0644)
+	if result := findPluginFile(oldpath); result != oldpath {
+		t.Error("Expected: ", oldpath, ", Gave: ", result)
+	}
+}
@@ -0,0 +1,507 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phoenixdevelops/fliw/data"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/*
+handles backends running as their own process.
+fliw and the backend talk to each other by sending one json
+object per line, either over stdin/stdout of the backend process
+or over a unix socket.
+A backend can stop the window by setting stop in any response.
+*/
+
+// remote methods
+const (
+	MethodGetVariable   = "getvariable"
+	MethodCallFunction  = "callfunction"
+	MethodCheckVariable = "checkvariable"
+	MethodCheckFunction = "checkfunction"
+	MethodEvent         = "event"
+	MethodInit          = "init"
+	MethodUpdate        = "update"
+)
+
+// prefix of backend paths leading to a unix socket
+const unixSocketPrefix = "unix:"
+
+// how often the backend executable is checked for changes
+const remoteCheckInterval = time.Second
+
+// how long a backend can take to respond to a request
+// before it is taken as hanging and restarted
+var remoteTimeout = 2 * time.Second
+
+// RemoteRequest is a request sent from fliw to a remote backend
+type RemoteRequest struct {
+	Method string   `json:"method"`
+	Name   string   `json:"name,omitempty"`
+	Args   []string `json:"args,omitempty"`
+	Event  *Event   `json:"event,omitempty"`
+}
+
+// RemoteResponse is the answer of a remote backend to a request
+type RemoteResponse struct {
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+
+	// Stop closes the window, like setting the running flag
+	// passed to the Init function of a go plugin to false
+	Stop bool `json:"stop,omitempty"`
+}
+
+// Remote is a connection to a backend running as its own process.
+// If the backend executable changes, the connection breaks or
+// the backend doesn't respond in time, the backend is restarted.
+type Remote struct {
+	path string
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	conn    io.ReadWriteCloser
+	encoder *json.Encoder
+	decoder *json.Decoder
+
+	// modification time of the executable when it was started
+	modtime   time.Time
+	lastCheck time.Time
+
+	// set to false when the backend asks to stop the window
+	running *bool
+}
+
+// IsRemotePath tells wether the backend at path runs as its own process.
+// Go plugins end with .so, everything else is either a unix socket
+// (unix:/path/to/socket) or an executable
+func IsRemotePath(path string) bool {
+	return !strings.HasSuffix(path, ".so")
+}
+
+// OpenRemote starts the backend executable at path or connects to
+// the unix socket if path starts with "unix:"
+func OpenRemote(path string) (remote *Remote, err error) {
+	remote = &Remote{path: path}
+
+	err = remote.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	return remote, nil
+}
+
+// connects to the backend, starting it if needed
+func (r *Remote) connect() (err error) {
+	if strings.HasPrefix(r.path, unixSocketPrefix) {
+		conn, err := net.Dial("unix", r.path[len(unixSocketPrefix):])
+		if err != nil {
+			return err
+		}
+
+		r.setConn(conn)
+		return nil
+	}
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return
+	}
+
+	cmd := exec.Command(r.path)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return
+	}
+
+	r.cmd = cmd
+	r.modtime = info.ModTime()
+	r.lastCheck = time.Now()
+	r.setConn(stdioConn{stdout, stdin})
+
+	return nil
+}
+
+func (r *Remote) setConn(conn io.ReadWriteCloser) {
+	r.conn = conn
+	r.encoder = json.NewEncoder(conn)
+	r.decoder = json.NewDecoder(conn)
+}
+
+// closes the connection and stops the backend process
+func (r *Remote) disconnect() {
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+
+	if r.cmd != nil {
+		r.cmd.Process.Kill()
+		r.cmd.Wait()
+		r.cmd = nil
+	}
+}
+
+// Restart restarts the backend and initializes it again
+func (r *Remote) Restart() (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.restart()
+}
+
+func (r *Remote) restart() (err error) {
+	r.disconnect()
+
+	err = r.connect()
+	if err != nil {
+		return
+	}
+
+	log.Println("Restarted backend " + r.path)
+
+	_, err = r.send(RemoteRequest{Method: MethodInit})
+	return
+}
+
+// Close stops the backend
+func (r *Remote) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.disconnect()
+}
+
+// tells wether the backend executable changed since it was started
+func (r *Remote) hasChanged() bool {
+	if r.cmd == nil || time.Since(r.lastCheck) < remoteCheckInterval {
+		return false
+	}
+	r.lastCheck = time.Now()
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		// the executable is probably being rebuilt
+		return false
+	}
+
+	return !info.ModTime().Equal(r.modtime)
+}
+
+// sends a request to the backend and waits for the response
+func (r *Remote) send(req RemoteRequest) (result string, err error) {
+	if r.conn == nil {
+		return "", errors.New("Backend " + r.path + " is not running")
+	}
+
+	err = r.encoder.Encode(req)
+	if err != nil {
+		return
+	}
+
+	resp, err := r.receive()
+	if err != nil {
+		// a partial response may still be read, so the connection
+		// can't be used anymore
+		r.disconnect()
+		return
+	}
+
+	if resp.Stop && r.running != nil {
+		*r.running = false
+	}
+
+	if resp.Error != "" {
+		return resp.Result, remoteError(resp.Error)
+	}
+
+	return resp.Result, nil
+}
+
+// reads the response to a request, giving up after remoteTimeout
+func (r *Remote) receive() (resp RemoteResponse, err error) {
+	if conn, ok := r.conn.(net.Conn); ok {
+		conn.SetReadDeadline(time.Now().Add(remoteTimeout))
+		err = r.decoder.Decode(&resp)
+		if neterr, ok := err.(net.Error); ok && neterr.Timeout() {
+			return resp, r.timeoutError()
+		}
+		return
+	}
+
+	// pipes have no deadlines, the read is stopped
+	// by disconnecting if it takes too long
+	type received struct {
+		resp RemoteResponse
+		err  error
+	}
+	done := make(chan received, 1)
+	decoder := r.decoder
+
+	go func() {
+		var resp RemoteResponse
+		err := decoder.Decode(&resp)
+		done <- received{resp, err}
+	}()
+
+	timer := time.NewTimer(remoteTimeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-done:
+		return result.resp, result.err
+	case <-timer.C:
+		return resp, r.timeoutError()
+	}
+}
+
+func (r *Remote) timeoutError() error {
+	return errors.New("Backend " + r.path + " did not respond within " + remoteTimeout.String())
+}
+
+// Request sends a request to the backend. If the backend changed
+// or the connection broke, the backend is restarted first.
+func (r *Remote) Request(req RemoteRequest) (result string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.hasChanged() {
+		err = r.restart()
+		if err != nil {
+			return
+		}
+	}
+
+	result, err = r.send(req)
+	if _, ok := err.(remoteError); err == nil || ok {
+		return
+	}
+
+	// the connection broke, try again once
+	err = r.restart()
+	if err != nil {
+		return
+	}
+
+	return r.send(req)
+}
+
+// GetVariable gets a variable from the backend
+func (r *Remote) GetVariable(name string) (value string, err error) {
+	return r.Request(RemoteRequest{Method: MethodGetVariable, Name: name})
+}
+
+// CallFunction calls a function of the backend with already evaluated arguments
+func (r *Remote) CallFunction(name string, args []string) (returned string, err error) {
+	return r.Request(RemoteRequest{Method: MethodCallFunction, Name: name, Args: args})
+}
+
+// CheckVariable checks if the backend has a variable with that name
+func (r *Remote) CheckVariable(name string) (err error) {
+	_, err = r.Request(RemoteRequest{Method: MethodCheckVariable, Name: name})
+	return
+}
+
+// CheckFunction checks if the backend has a function with that name.
+// Remote functions always take any number of arguments and return a string.
+func (r *Remote) CheckFunction(name string, hasArgs bool, hasReturn bool) (err error) {
+	_, err = r.Request(RemoteRequest{Method: MethodCheckFunction, Name: name})
+	return
+}
+
+// Init initializes the backend.
+// The container can't be passed on to the backend process,
+// running is set to false once the backend responds with stop.
+func (r *Remote) Init(cont *data.BaseContainer, running *bool) {
+	r.mu.Lock()
+	r.running = running
+	r.mu.Unlock()
+
+	r.requestLogged(RemoteRequest{Method: MethodInit})
+}
+
+// Update lets the backend update
+func (r *Remote) Update() {
+	r.requestLogged(RemoteRequest{Method: MethodUpdate})
+}
+
+// HandleEvent passes an event to the backend,
+// if it can be converted (see ToEvent)
+func (r *Remote) HandleEvent(event sdl.Event) {
+	ev, ok := ToEvent(event)
+	if !ok {
+		return
+	}
+
+	r.requestLogged(RemoteRequest{Method: MethodEvent, Event: &ev})
+}
+
+// sends a request, logging errors instead of returning them,
+// so a failing backend doesn't stop the window
+func (r *Remote) requestLogged(req RemoteRequest) (result string) {
+	result, err := r.Request(req)
+	if err != nil {
+		log.Println(err)
+	}
+
+	return
+}
+
+// an error returned by the backend itself
+type remoteError string
+
+func (e remoteError) Error() string {
+	return string(e)
+}
+
+// stdioConn combines stdout and stdin of a process to a connection
+type stdioConn struct {
+	io.ReadCloser
+	in io.WriteCloser
+}
+
+func (c stdioConn) Write(p []byte) (int, error) {
+	return c.in.Write(p)
+}
+
+func (c stdioConn) Close() error {
+	c.in.Close()
+	return c.ReadCloser.Close()
+}
+
+/*
+###########################
+# Section: Serving
+###########################
+*/
+
+// RemoteModule describes the behavior of a module running as its own process.
+// All fields are optional.
+type RemoteModule struct {
+	Variables   map[string]*string
+	Functions   map[string]func(...string) string
+	Init        func()
+	Update      func()
+	HandleEvent func(Event)
+
+	// Running stops the window once it is set to false
+	// by any of the functions above
+	Running *bool
+}
+
+// handles a single request
+func (m *RemoteModule) handle(req RemoteRequest) (resp RemoteResponse) {
+	switch req.Method {
+	case MethodGetVariable, MethodCheckVariable:
+		value, ok := m.Variables[req.Name]
+		if !ok {
+			return RemoteResponse{Error: "No variable named " + req.Name}
+		}
+		if req.Method == MethodGetVariable {
+			resp.Result = *value
+		}
+	case MethodCallFunction, MethodCheckFunction:
+		function, ok := m.Functions[req.Name]
+		if !ok {
+			return RemoteResponse{Error: "No function named " + req.Name}
+		}
+		if req.Method == MethodCallFunction {
+			resp.Result = function(req.Args...)
+		}
+	case MethodInit:
+		if m.Init != nil {
+			m.Init()
+		}
+	case MethodUpdate:
+		if m.Update != nil {
+			m.Update()
+		}
+	case MethodEvent:
+		if m.HandleEvent != nil && req.Event != nil {
+			m.HandleEvent(*req.Event)
+		}
+	default:
+		return RemoteResponse{Error: "Unknown method " + req.Method}
+	}
+
+	resp.Stop = m.Running != nil && !*m.Running
+	return
+}
+
+// Serve answers the requests read from r by writing to w
+// until r is closed
+func (m RemoteModule) Serve(r io.Reader, w io.Writer) (err error) {
+	decoder := json.NewDecoder(r)
+	encoder := json.NewEncoder(w)
+
+	for {
+		var req RemoteRequest
+		err = decoder.Decode(&req)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return
+		}
+
+		err = encoder.Encode(m.handle(req))
+		if err != nil {
+			return
+		}
+	}
+}
+
+// ServeStdio answers requests on stdin and stdout.
+// Stdout can't be used for anything else while serving,
+// use stderr for logging.
+func (m RemoteModule) ServeStdio() error {
+	return m.Serve(os.Stdin, os.Stdout)
+}
+
+// ServeUnix answers requests on a unix socket at path,
+// one connection at a time
+func (m RemoteModule) ServeUnix(path string) (err error) {
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return
+	}
+	defer listener.Close()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		err = m.Serve(conn, conn)
+		conn.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package backend
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRemote(t *testing.T) {
+	greeting := "hello"
+	updates := 0
+	var events []Event
+
+	module := RemoteModule{
+		Variables: map[string]*string{"Greeting": &greeting},
+		Functions: map[string]func(...string) string{
+			"Join": func(args ...string) string { return strings.Join(args, "-") },
+		},
+		Update:      func() { updates++ },
+		HandleEvent: func(ev Event) { events = append(events, ev) },
+	}
+
+	client, server := net.Pipe()
+	go module.Serve(server, server)
+
+	remote := &Remote{path: "test"}
+	remote.setConn(client)
+	defer remote.Close()
+
+	if value, err := remote.GetVariable("Greeting"); err != nil || value != greeting {
+		t.Error("Expected ", greeting, ", gave ", value, err)
+	}
+
+	if value, err := remote.CallFunction("Join", []string{"a", "b"}); err != nil || value != "a-b" {
+		t.Error("Expected a-b, gave ", value, err)
+	}
+
+	// errors of the backend are passed on
+	if _, err := remote.GetVariable("Missing"); err == nil {
+		t.Error("Expected an error for a missing variable")
+	}
+	if err := remote.CheckVariable("Missing"); err == nil {
+		t.Error("Expected an error for a missing variable")
+	}
+	if err := remote.CheckFunction("Join", true, true); err != nil {
+		t.Error(err)
+	}
+
+	if remote.Update(); updates != 1 {
+		t.Error("Expected 1 update, gave ", updates)
+	}
+
+	_, err := remote.Request(RemoteRequest{Method: MethodEvent, Event: &Event{Name: KeydownEvent, Key: "A"}})
+	if err != nil || len(events) != 1 || events[0].Key != "A" {
+		t.Error("Expected the event to be passed on, gave ", events, err)
+	}
+}
+
+func TestRemoteStop(t *testing.T) {
+	moduleRunning := true
+
+	module := RemoteModule{
+		Update:  func() { moduleRunning = false },
+		Running: &moduleRunning,
+	}
+
+	client, server := net.Pipe()
+	go module.Serve(server, server)
+
+	remote := &Remote{path: "test"}
+	remote.setConn(client)
+	defer remote.Close()
+
+	running := true
+	remote.Init(nil, &running)
+	if !running {
+		t.Error("Expected the window to keep running after init")
+	}
+
+	remote.Update()
+	if running {
+		t.Error("Expected the backend to stop the window")
+	}
+}
+
+func TestIsRemotePath(t *testing.T) {
+	if IsRemotePath("/module/app.so") {
+		t.Error("Expected go plugins not to be remote")
+	}
+	if !IsRemotePath("/module/app") || !IsRemotePath("unix:/tmp/app.sock") {
+		t.Error("Expected executables and sockets to be remote")
+	}
+}
+
+func TestRemoteTimeout(t *testing.T) {
+	defer func(prev time.Duration) { remoteTimeout = prev }(remoteTimeout)
+	remoteTimeout = 50 * time.Millisecond
+
+	// a socket whose backend reads requests, but never responds
+	client, server := net.Pipe()
+	go io.Copy(ioutil.Discard, server)
+
+	// stdin and stdout of a backend process that hangs
+	stdout, hanging := io.Pipe()
+	stdin, requests := io.Pipe()
+	go io.Copy(ioutil.Discard, stdin)
+	defer hanging.Close()
+
+	for name, conn := range map[string]io.ReadWriteCloser{"socket": client, "stdio": stdioConn{stdout, requests}} {
+		remote := &Remote{path: "missing"}
+		remote.setConn(conn)
+
+		start := time.Now()
+		if _, err := remote.GetVariable("Greeting"); err == nil {
+			t.Error("Expected an error for a ", name, " backend that doesn't respond")
+		}
+		if time.Since(start) > time.Second {
+			t.Error("Expected the ", name, " request to time out, took ", time.Since(start))
+		}
+
+		// the backend is restarted, which fails as there is no executable
+		if remote.conn != nil {
+			t.Error("Expected the ", name, " backend to be disconnected")
+		}
+	}
+}
@@ -1,269 +1,1137 @@
 package backend
 
 import (
-	"log"
+	"errors"
 	"math"
-	"regexp"
 	"strconv"
 	"strings"
 )
 
+/*
+evaluates the expressions used in attribute values, e.g.
+"clamp(50% - 2*$padding, 10, @GetMaxWidth())"
+
+Numbers may be relative to the size of the parent ("50%") and
+relative and absolute parts can be mixed ("50% - 10").
+Values returned by the backend are strings, so they are shown
+unchanged when used as text. Where a number is needed, strings
+looking like one (including "50%" and expressions like "100-20")
+are converted to it.
+*/
+
 /*
 ###########################
-# Section: Structs
+# Section: Values
 ###########################
 */
 
-// Stack is a stack of values that operations can be performed on
-type Stack struct {
-	Items  []float64
-	Length int
+// ValueKind is the type of a value
+type ValueKind int
+
+// value kinds
+const (
+	NumberValue ValueKind = iota
+	StringValue
+	BoolValue
+)
+
+// Value is the result of an expression
+type Value struct {
+	Kind ValueKind
+
+	// Number is the absolute part of a number value,
+	// Percent the part relative to the size of the parent
+	Number  float64
+	Percent float64
+
+	Str  string
+	Bool bool
 }
 
-// Push pushes an item onto the stack
-func (s *Stack) Push(item float64) {
-	s.Items = append(s.Items, item)
-	s.Length++
+// NumberOf makes an absolute number value
+func NumberOf(number float64) Value {
+	return Value{Kind: NumberValue, Number: number}
 }
 
-// Pop pops an item from the stack
-func (s *Stack) Pop() (item float64) {
-	s.Length--
-	item = s.Items[s.Length]
-	s.Items = s.Items[:s.Length]
-	return
+// StringOf makes a string value
+func StringOf(str string) Value {
+	return Value{Kind: StringValue, Str: str}
 }
 
-// PerformOperation performs an operation with the two
-// top values as input and pushes the result to the stack
-func (s *Stack) PerformOperation(op rune) {
-	var res float64
+// BoolOf makes a boolean value
+func BoolOf(b bool) Value {
+	return Value{Kind: BoolValue, Bool: b}
+}
 
-	var2 := s.Pop()
-	var1 := s.Pop()
+// String formats a value, so it can be read again by ParseValue
+func (v Value) String() string {
+	switch v.Kind {
+	case StringValue:
+		return v.Str
+	case BoolValue:
+		return strconv.FormatBool(v.Bool)
+	}
 
-	// apply operation
-	switch op {
-	case '+':
-		res = var1 + var2
-	case '-':
-		res = var1 - var2
-	case '*':
-		res = var1 * var2
-	case '/':
-		res = var1 / var2
-	case '^':
-		res = math.Pow(var1, var2)
+	if v.Percent == 0 {
+		return formatNumber(v.Number)
+	}
+
+	percent := formatNumber(v.Percent) + "%"
+	if v.Number > 0 {
+		return percent + "+" + formatNumber(v.Number)
+	} else if v.Number < 0 {
+		return percent + formatNumber(v.Number)
+	}
+
+	return percent
+}
+
+// IsRelative tells wether a number depends on the size of the parent
+func (v Value) IsRelative() bool {
+	return v.Kind == NumberValue && v.Percent != 0
+}
+
+// Resolve gets the absolute value of a number
+// with parent being the size of the parent
+func (v Value) Resolve(parent float64) float64 {
+	return v.Number + v.Percent/100*parent
+}
+
+// Truthy tells wether a value counts as true in conditions.
+// Zero (also as string), empty strings and "false" are false
+func (v Value) Truthy() bool {
+	switch v.Kind {
+	case BoolValue:
+		return v.Bool
+	case StringValue:
+		if number := numeric(v); number.Kind == NumberValue {
+			return number.Truthy()
+		}
+		return v.Str != "" && v.Str != "false"
+	}
+
+	return v.Number != 0 || v.Percent != 0
+}
+
+// ToNumber converts a value to a number.
+// Strings are parsed as expression without references
+func (v Value) ToNumber() (number Value, err error) {
+	switch v.Kind {
+	case NumberValue:
+		return v, nil
+	case BoolValue:
+		return v, errors.New("Expected a number, got " + v.String())
+	}
+
+	number = ParseValue(v.Str)
+	if number.Kind != NumberValue {
+		return number, errors.New("Expected a number, got \"" + v.Str + "\"")
+	}
+
+	return number, nil
+}
+
+// ParseValue converts a string to the value it holds.
+// Numbers (also relative ones like "50%-10") and booleans
+// are converted, everything else stays a string
+func ParseValue(str string) (value Value) {
+	trimmed := strings.TrimSpace(str)
+
+	switch trimmed {
+	case "true":
+		return BoolOf(true)
+	case "false":
+		return BoolOf(false)
+	case "":
+		return StringOf(str)
+	}
+
+	// ParseFloat would also accept "inf" or "nan"
+	if isDigit(trimmed[len(trimmed)-1]) {
+		if number, err := strconv.ParseFloat(trimmed, 64); err == nil {
+			return NumberOf(number)
+		}
+	}
+
+	if len(trimmed) > 1 && strings.HasSuffix(trimmed, "%") && isDigit(trimmed[len(trimmed)-2]) {
+		if number, err := strconv.ParseFloat(trimmed[:len(trimmed)-1], 64); err == nil {
+			return Value{Kind: NumberValue, Percent: number}
+		}
+	}
+
+	// expressions like "50%-10"
+	if !strings.ContainsAny(trimmed, "$@'\"") {
+		if expr, err := ParseExpression(trimmed); err == nil {
+			if value, err := expr.Eval(nil); err == nil && value.Kind == NumberValue {
+				return value
+			}
+		}
+	}
+
+	return StringOf(str)
+}
+
+// converts strings holding a number (like the values of
+// the backend) to that number, everything else is kept
+func numeric(v Value) Value {
+	if v.Kind != StringValue {
+		return v
 	}
 
-	// push the operation result to the value stack
-	s.Push(res)
+	if number, err := v.ToNumber(); err == nil {
+		return number
+	}
+
+	return v
+}
+
+// formats a number without unnecessary decimals
+func formatNumber(number float64) string {
+	return strconv.FormatFloat(number, 'f', -1, 64)
 }
 
 /*
-########################
-# Section: Code
-########################
+###########################
+# Section: Tokenizer
+###########################
 */
 
-// Regular expressions
-var alphaNumericRegex *regexp.Regexp
-var functionRegex *regexp.Regexp
+type tokenKind int
 
-var prefixValueRegex *regexp.Regexp
-var suffixValueRegex *regexp.Regexp
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenVariable
+	tokenFunction
+	tokenIdent
+	tokenOperator
+)
 
-func init() {
-	alphaNumericRegex = regexp.MustCompile(`^[a-zA-Z0-9]+`)
-	functionRegex = regexp.MustCompile(`^[a-zA-Z0-9]+\((\$[a-zA-Z0-9], ?)*(\$[a-zA-Z0-9])?\)`)
+type token struct {
+	kind tokenKind
+	text string
+	// byte offset in the source
+	pos int
+}
+
+// operators consisting of two characters
+var doubleOperators = []string{"==", "!=", "<=", ">=", "&&", "||"}
+
+// operators consisting of one character
+const singleOperators = "+-*/^%!?:,()<>"
+
+// splits an expression into tokens
+func tokenize(src string) (tokens []token, err error) {
+	i := 0
+
+tokenLoop:
+	for i < len(src) {
+		c := src[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+
+		case isDigit(c) || c == '.':
+			start := i
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, src[start:i], start})
+			continue
+
+		case c == '\'' || c == '"':
+			start := i
+			var str strings.Builder
+
+			for i++; i < len(src); i++ {
+				switch src[i] {
+				case c:
+					i++
+					tokens = append(tokens, token{tokenString, str.String(), start})
+					continue tokenLoop
+				case '\\':
+					i++
+					if i == len(src) {
+						break
+					}
+					str.WriteByte(src[i])
+				default:
+					str.WriteByte(src[i])
+				}
+			}
+
+			return nil, syntaxError(src, start, "string is not closed")
+
+		case c == '$' || c == '@':
+			start := i
+			i++
+			for i < len(src) && isNameChar(src[i]) {
+				i++
+			}
+			if i == start+1 {
+				return nil, syntaxError(src, start, "expected a name after "+string(c))
+			}
+
+			kind := tokenVariable
+			if c == '@' {
+				kind = tokenFunction
+			}
+			tokens = append(tokens, token{kind, src[start+1 : i], start})
+			continue
 
-	prefixValueRegex = regexp.MustCompile(`^((\(.*\))|([0-9]+)|([0-9]+\.[0-9]+))`)
-	suffixValueRegex = regexp.MustCompile(`((\(.*\))|([0-9]+)|([0-9]+\.[0-9]+))$`)
+		case isNameChar(c):
+			start := i
+			for i < len(src) && isNameChar(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, src[start:i], start})
+			continue
+		}
+
+		for _, op := range doubleOperators {
+			if strings.HasPrefix(src[i:], op) {
+				tokens = append(tokens, token{tokenOperator, op, i})
+				i += len(op)
+				continue tokenLoop
+			}
+		}
+
+		if strings.IndexByte(singleOperators, c) >= 0 {
+			tokens = append(tokens, token{tokenOperator, string(c), i})
+			i++
+			continue
+		}
+
+		return nil, syntaxError(src, i, "unexpected character "+strconv.Quote(string(c)))
+	}
+
+	return append(tokens, token{tokenEOF, "", len(src)}), nil
 }
 
-// CalculateValue calculates a integer value of a string, e.g
-// "(4/3)*@GetPi()*($r^3)"
-func CalculateValue(op string, plug *Plugin) (result string) {
-	// temporarely remove % suffix if there is one
-	isPercentage := false
-	if strings.HasSuffix(op, "%") {
-		op = op[:len(op)-1]
-		isPercentage = true
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+/*
+###########################
+# Section: Parser
+###########################
+*/
+
+// SyntaxError is an error in the syntax of an expression
+type SyntaxError struct {
+	Source string
+	// byte offset of the error in the source
+	Pos     int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return "Syntax error in \"" + e.Source + "\" at position " +
+		strconv.Itoa(e.Pos) + ": " + e.Message
+}
+
+func syntaxError(src string, pos int, message string) error {
+	return &SyntaxError{Source: src, Pos: pos, Message: message}
+}
+
+// binding powers of the infix and postfix operators,
+// operators with a higher binding power are evaluated first
+var bindingPowers = map[string]int{
+	"?":  1,
+	"||": 2,
+	"&&": 3,
+	"==": 4, "!=": 4,
+	"<": 5, "<=": 5, ">": 5, ">=": 5,
+	"+": 6, "-": 6,
+	"*": 7, "/": 7,
+	"^": 9,
+	"%": 10,
+}
+
+// binding power of the prefix operators (-, + and !)
+const prefixBindingPower = 8
+
+// a pratt parser for expressions
+type exprParser struct {
+	src    string
+	tokens []token
+	pos    int
+}
+
+// ParseExpression parses an expression, so it can be evaluated
+// any number of times. Invalid expressions return a *SyntaxError
+func ParseExpression(src string) (expr Expr, err error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return
+	}
+
+	p := &exprParser{src: src, tokens: tokens}
+
+	expr, err = p.parseExpr(0)
+	if err != nil {
+		return nil, err
 	}
 
-	// in case of variables or functions evaluate them.
-	for i, c := range op {
-		prev := op[:i+1]
-		if c == '@' {
-			op = prev + evalNextFunction(op[i+1:], plug)
-		} else if c == '$' {
-			op = prev + evalNextVariable(op[i+1:], plug)
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.unexpected(tok)
+	}
+
+	return expr, nil
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+
+	return tok
+}
+
+// consumes the next token if it is the operator op
+func (p *exprParser) accept(op string) bool {
+	if tok := p.peek(); tok.kind == tokenOperator && tok.text == op {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *exprParser) expect(op string) (err error) {
+	if !p.accept(op) {
+		tok := p.peek()
+		if tok.kind == tokenEOF {
+			return syntaxError(p.src, tok.pos, "expected "+op)
 		}
+		return syntaxError(p.src, tok.pos, "expected "+op+", got "+tok.text)
 	}
 
-	// build the command stack
-	// the last item should be a value
-	// containing the result
-	flresult := buildStack(op).Pop()
+	return nil
+}
+
+func (p *exprParser) unexpected(tok token) error {
+	if tok.kind == tokenEOF {
+		return syntaxError(p.src, tok.pos, "unexpected end of expression")
+	}
 
-	// convert the result to a string
-	result = strconv.Itoa(int(math.Round(flresult)))
+	return syntaxError(p.src, tok.pos, "unexpected "+tok.text)
+}
 
-	// reattach percentage sign
-	if isPercentage {
-		result = result + "%"
+// parses an expression until an operator with a binding power
+// lower or equal to minPower is found
+func (p *exprParser) parseExpr(minPower int) (expr Expr, err error) {
+	expr, err = p.parsePrefix()
+	if err != nil {
+		return
 	}
-	return
+
+	for {
+		tok := p.peek()
+		if tok.kind != tokenOperator {
+			return expr, nil
+		}
+
+		power, ok := bindingPowers[tok.text]
+		if !ok || power <= minPower {
+			return expr, nil
+		}
+		p.next()
+
+		switch tok.text {
+		case "%":
+			expr = percentExpr{expr}
+
+		case "?":
+			then, err := p.parseExpr(0)
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(":"); err != nil {
+				return nil, err
+			}
+			// right associative
+			otherwise, err := p.parseExpr(power - 1)
+			if err != nil {
+				return nil, err
+			}
+
+			expr = ternaryExpr{expr, then, otherwise}
+
+		case "^":
+			// right associative
+			right, err := p.parseExpr(power - 1)
+			if err != nil {
+				return nil, err
+			}
+
+			expr = binaryExpr{tok.text, expr, right}
+
+		default:
+			right, err := p.parseExpr(power)
+			if err != nil {
+				return nil, err
+			}
+
+			expr = binaryExpr{tok.text, expr, right}
+		}
+	}
+}
+
+// parses literals, references, brackets and prefix operators
+func (p *exprParser) parsePrefix() (expr Expr, err error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenNumber:
+		number, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, syntaxError(p.src, tok.pos, "invalid number "+tok.text)
+		}
+		return literalExpr{NumberOf(number)}, nil
+
+	case tokenString:
+		return literalExpr{StringOf(tok.text)}, nil
+
+	case tokenVariable:
+		return variableExpr{tok.text}, nil
+
+	case tokenFunction:
+		args, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		return callExpr{tok.text, args}, nil
+
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			return literalExpr{BoolOf(true)}, nil
+		case "false":
+			return literalExpr{BoolOf(false)}, nil
+		}
+
+		// other names are strings, e.g. @SetMode(dark)
+		if next := p.peek(); next.kind != tokenOperator || next.text != "(" {
+			return literalExpr{StringOf(tok.text)}, nil
+		}
+
+		fn, ok := builtins[tok.text]
+		if !ok {
+			return nil, syntaxError(p.src, tok.pos, "unknown function "+tok.text+
+				" (prefix functions of the backend with @)")
+		}
+
+		args, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+			return nil, syntaxError(p.src, tok.pos, "wrong number of arguments for "+tok.text)
+		}
+		return builtinExpr{tok.text, fn.call, args}, nil
+
+	case tokenOperator:
+		switch tok.text {
+		case "(":
+			expr, err = p.parseExpr(0)
+			if err != nil {
+				return
+			}
+			return expr, p.expect(")")
+
+		case "-", "+", "!":
+			operand, err := p.parseExpr(prefixBindingPower)
+			if err != nil {
+				return nil, err
+			}
+			return unaryExpr{tok.text, operand}, nil
+		}
+	}
+
+	return nil, p.unexpected(tok)
+}
+
+// parses the bracketed argument list of a function call
+func (p *exprParser) parseArgs() (args []Expr, err error) {
+	err = p.expect("(")
+	if err != nil {
+		return
+	}
+
+	if p.accept(")") {
+		return nil, nil
+	}
+
+	for {
+		arg, err := p.parseExpr(0)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+
+		if p.accept(")") {
+			return args, nil
+		}
+		if err = p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+/*
+###########################
+# Section: Expressions
+###########################
+*/
+
+// Expr is a parsed expression
+type Expr interface {
+	// Eval evaluates the expression, getting the values
+	// of variables and functions from the backend
+	Eval(b Backend) (Value, error)
 }
 
-// builds a stack from a string containing operations
-// (not variables or functions!)
-func buildStack(op string) (result *Stack) {
-	// initialize result
-	result = &Stack{[]float64{}, 0}
+type literalExpr struct {
+	value Value
+}
+
+func (e literalExpr) Eval(b Backend) (Value, error) {
+	return e.value, nil
+}
 
-	// "bracketize":
-	// a*b*c -> (a*b)*c
-	i := -1
-	for i != 0 {
-		i = getOperandIndex(op)
+type variableExpr struct {
+	name string
+}
 
-		leftVal := suffixValueRegex.FindString(op[:i])
-		rightVal := prefixValueRegex.FindString(op[i+1:])
+func (e variableExpr) Eval(b Backend) (value Value, err error) {
+	if b == nil {
+		return value, errors.New("No backend to get variable " + e.name + " from")
+	}
+
+	str, err := b.GetVariable(e.name)
+	if err != nil {
+		return
+	}
+
+	return StringOf(str), nil
+}
+
+type callExpr struct {
+	name string
+	args []Expr
+}
 
-		if len(leftVal) > 0 && len(rightVal) > 0 {
-			// append brackets
-			op = op[:i-len(leftVal)] + "(" +
-				op[i-len(leftVal):i+len(rightVal)+1] + ")" +
-				op[i+len(rightVal)+1:]
+func (e callExpr) Eval(b Backend) (value Value, err error) {
+	if b == nil {
+		return value, errors.New("No backend to call function " + e.name + " from")
+	}
+
+	args := make([]string, len(e.args))
+	for i, arg := range e.args {
+		argvalue, err := arg.Eval(b)
+		if err != nil {
+			return value, err
 		}
+		args[i] = argvalue.String()
 	}
 
-	// get rid of outer brackets
-	// while the first bracket matches the last index
-	for getMatchingBracketIndex(op, 0) == len(op)-1 && len(op) > 2 {
-		op = op[1 : len(op)-1]
+	str, err := b.CallFunction(e.name, args)
+	if err != nil {
+		return
 	}
 
-	// evaluate operation
-	i = getOperandIndex(op)
+	return StringOf(str), nil
+}
 
-	leftVal := suffixValueRegex.FindString(op[:i])
-	rightVal := prefixValueRegex.FindString(op[i+1:])
+type builtinExpr struct {
+	name string
+	call func([]Value) (Value, error)
+	args []Expr
+}
 
-	result.Push(evalValue(leftVal))
-	result.Push(evalValue(rightVal))
+func (e builtinExpr) Eval(b Backend) (value Value, err error) {
+	args := make([]Value, len(e.args))
+	for i, arg := range e.args {
+		args[i], err = arg.Eval(b)
+		if err != nil {
+			return
+		}
+	}
 
-	result.PerformOperation([]rune(op)[i])
+	value, err = e.call(args)
+	if err != nil {
+		return value, errors.New(e.name + ": " + err.Error())
+	}
 
 	return
 }
 
-// gets the next operand which is not in brackets, sorted by operand tier
-func getOperandIndex(op string) (index int) {
-	// get tier 3 operands
-	for i, c := range op {
-		if c == '^' {
-			if !isOpInBrackets(op[i:]) {
-				return i
-			}
+type percentExpr struct {
+	operand Expr
+}
+
+func (e percentExpr) Eval(b Backend) (value Value, err error) {
+	value, err = evalNumber(e.operand, b)
+	if err != nil {
+		return
+	}
+	if value.IsRelative() {
+		return value, errors.New("Can't take a percentage of a relative value")
+	}
+
+	return Value{Kind: NumberValue, Percent: value.Number}, nil
+}
+
+type ternaryExpr struct {
+	condition Expr
+	then      Expr
+	otherwise Expr
+}
+
+func (e ternaryExpr) Eval(b Backend) (value Value, err error) {
+	condition, err := e.condition.Eval(b)
+	if err != nil {
+		return
+	}
+
+	if condition.Truthy() {
+		return e.then.Eval(b)
+	}
+
+	return e.otherwise.Eval(b)
+}
+
+type unaryExpr struct {
+	op      string
+	operand Expr
+}
+
+func (e unaryExpr) Eval(b Backend) (value Value, err error) {
+	if e.op == "!" {
+		value, err = e.operand.Eval(b)
+		return BoolOf(!value.Truthy()), err
+	}
+
+	value, err = evalNumber(e.operand, b)
+	if err != nil {
+		return
+	}
+
+	if e.op == "-" {
+		value.Number = -value.Number
+		value.Percent = -value.Percent
+	}
+
+	return value, nil
+}
+
+type binaryExpr struct {
+	op    string
+	left  Expr
+	right Expr
+}
+
+func (e binaryExpr) Eval(b Backend) (value Value, err error) {
+	left, err := e.left.Eval(b)
+	if err != nil {
+		return
+	}
+
+	// short circuit
+	switch e.op {
+	case "&&":
+		if !left.Truthy() {
+			return BoolOf(false), nil
+		}
+		right, err := e.right.Eval(b)
+		return BoolOf(right.Truthy()), err
+	case "||":
+		if left.Truthy() {
+			return BoolOf(true), nil
 		}
+		right, err := e.right.Eval(b)
+		return BoolOf(right.Truthy()), err
 	}
 
-	// get tier 2 operands
-	for i, c := range op {
-		if c == '*' || c == '/' {
-			if !isOpInBrackets(op[i:]) {
-				return i
+	right, err := e.right.Eval(b)
+	if err != nil {
+		return
+	}
+
+	return applyOperator(e.op, left, right)
+}
+
+// evaluates an expression which has to result in a number
+func evalNumber(expr Expr, b Backend) (value Value, err error) {
+	value, err = expr.Eval(b)
+	if err != nil {
+		return
+	}
+
+	return value.ToNumber()
+}
+
+// applies a binary operator on two values
+func applyOperator(op string, left Value, right Value) (value Value, err error) {
+	switch op {
+	case "+":
+		// concatenate if any of the values is a string not holding a number
+		if numeric(left).Kind == StringValue || numeric(right).Kind == StringValue {
+			return StringOf(left.String() + right.String()), nil
+		}
+	case "==", "!=":
+		// strings are compared as they are, unless compared to a number
+		equal := left.String() == right.String()
+		if left.Kind != StringValue || right.Kind != StringValue {
+			if left, right := numeric(left), numeric(right); left.Kind != StringValue && right.Kind != StringValue {
+				equal = left == right
 			}
 		}
+		return BoolOf(equal == (op == "==")), nil
+	case "<", "<=", ">", ">=":
+		if numeric(left).Kind == StringValue && numeric(right).Kind == StringValue {
+			return BoolOf(compare(op, float64(strings.Compare(left.Str, right.Str)), 0)), nil
+		}
 	}
 
-	// get tier 1 operands
-	for i, c := range op {
-		if c == '+' || c == '-' {
-			if !isOpInBrackets(op[i:]) {
-				return i
-			}
+	if left, err = left.ToNumber(); err != nil {
+		return
+	}
+	if right, err = right.ToNumber(); err != nil {
+		return
+	}
+
+	switch op {
+	case "+":
+		left.Number += right.Number
+		left.Percent += right.Percent
+		return left, nil
+	case "-":
+		left.Number -= right.Number
+		left.Percent -= right.Percent
+		return left, nil
+	case "*":
+		if left.IsRelative() && right.IsRelative() {
+			return value, errors.New("Can't multiply two relative values")
+		}
+		if right.IsRelative() {
+			left, right = right, left
+		}
+		left.Number *= right.Number
+		left.Percent *= right.Number
+		return left, nil
+	case "/":
+		if right.IsRelative() {
+			return value, errors.New("Can't divide by a relative value")
+		}
+		if right.Number == 0 {
+			return value, errors.New("Division by zero")
+		}
+		left.Number /= right.Number
+		left.Percent /= right.Number
+		return left, nil
+	}
+
+	if left.IsRelative() || right.IsRelative() {
+		return value, errors.New("Operator " + op + " can't be used with relative values")
+	}
+
+	switch op {
+	case "^":
+		return NumberOf(math.Pow(left.Number, right.Number)), nil
+	case "<", "<=", ">", ">=":
+		return BoolOf(compare(op, left.Number, right.Number)), nil
+	}
+
+	return value, errors.New("Unknown operator " + op)
+}
+
+// compares two numbers with a comparison operator
+func compare(op string, a float64, b float64) bool {
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	}
+
+	return a >= b
+}
+
+/*
+###########################
+# Section: Builtins
+###########################
+*/
+
+type builtin struct {
+	// maxArgs is -1 for any number of arguments
+	minArgs int
+	maxArgs int
+	call    func([]Value) (Value, error)
+}
+
+// functions that can be called without @ prefix
+var builtins map[string]builtin
+
+func init() {
+	builtins = map[string]builtin{
+		"min":   {1, -1, builtinMin},
+		"max":   {1, -1, builtinMax},
+		"clamp": {3, 3, builtinClamp},
+		"floor": {1, 1, builtinFloor},
+	}
+}
+
+// converts the arguments of a builtin to absolute numbers
+func absoluteArgs(args []Value) (numbers []float64, err error) {
+	numbers = make([]float64, len(args))
+
+	for i, arg := range args {
+		arg, err = arg.ToNumber()
+		if err != nil {
+			return
+		}
+		if arg.IsRelative() {
+			return nil, errors.New("Relative values can't be used here")
 		}
+
+		numbers[i] = arg.Number
 	}
 
-	// nothing was found
-	return 0
+	return
 }
 
-// evaluates a value (or operations) and puts the result on given stack
-func evalValue(value string) (result float64) {
-	// if value is enclosed in brackets
-	if strings.HasPrefix(value, "(") {
-		// build the stack of the expression without the surrounding brackets
-		valStack := buildStack(value[1 : len(value)-1])
+func builtinMin(args []Value) (value Value, err error) {
+	numbers, err := absoluteArgs(args)
+	if err != nil {
+		return
+	}
 
-		return valStack.Pop()
+	result := numbers[0]
+	for _, number := range numbers[1:] {
+		result = math.Min(result, number)
 	}
 
-	// cast the value to float64 and put it onto the stack
-	floatval, err := strconv.ParseFloat(value, 64)
+	return NumberOf(result), nil
+}
+
+func builtinMax(args []Value) (value Value, err error) {
+	numbers, err := absoluteArgs(args)
+	if err != nil {
+		return
+	}
+
+	result := numbers[0]
+	for _, number := range numbers[1:] {
+		result = math.Max(result, number)
+	}
+
+	return NumberOf(result), nil
+}
+
+func builtinClamp(args []Value) (value Value, err error) {
+	numbers, err := absoluteArgs(args)
+	if err != nil {
+		return
+	}
+
+	return NumberOf(math.Max(numbers[1], math.Min(numbers[2], numbers[0]))), nil
+}
+
+func builtinFloor(args []Value) (value Value, err error) {
+	numbers, err := absoluteArgs(args)
+	if err != nil {
+		return
+	}
+
+	return NumberOf(math.Floor(numbers[0])), nil
+}
+
+/*
+###########################
+# Section: Compiling
+###########################
+*/
+
+// SymbolResolver is implemented by backends that can look up their
+// variables and functions once, so reading them later is faster
+type SymbolResolver interface {
+	// ResolveVariable gets a function reading the variable
+	ResolveVariable(name string) (func() (string, error), error)
+
+	// ResolveFunction gets a function calling the function
+	// with already evaluated arguments
+	ResolveFunction(name string) (func([]string) (string, error), error)
+}
+
+type boundVariableExpr struct {
+	get func() (string, error)
+}
+
+func (e boundVariableExpr) Eval(b Backend) (value Value, err error) {
+	str, err := e.get()
 	if err != nil {
-		log.Fatal(err)
 		return
 	}
-	return floatval
+
+	return StringOf(str), nil
 }
 
-// gets if the first char in an equation is in brackets
-func isOpInBrackets(abOp string) (isInBrackets bool) {
-	// opening brackets - closing brackets after abOp[ind]
-	openBracketCount := 0
-	for _, c := range abOp {
-		if c == '(' {
-			openBracketCount++
-		} else if c == ')' {
-			openBracketCount--
+type boundCallExpr struct {
+	call func([]string) (string, error)
+	args []Expr
+}
+
+func (e boundCallExpr) Eval(b Backend) (value Value, err error) {
+	args := make([]string, len(e.args))
+	for i, arg := range e.args {
+		argvalue, err := arg.Eval(b)
+		if err != nil {
+			return value, err
 		}
+		args[i] = argvalue.String()
 	}
 
-	// if more were closed than opened,
-	// there must be an 'active' opening bracket
-	// before ind
-	if openBracketCount < 0 {
-		return true
+	str, err := e.call(args)
+	if err != nil {
+		return
 	}
 
-	return false
+	return StringOf(str), nil
 }
 
-// gets the matching bracket of bracket br
-func getMatchingBracketIndex(op string, brindex int) (index int) {
-	openBracketCount := 0
-	for i, c := range op[brindex:] {
-		if c == '(' {
-			openBracketCount++
-		} else if c == ')' {
-			openBracketCount--
+// Compile folds the constant parts of an expression and, if the
+// backend is a SymbolResolver, binds the variables and functions to it.
+// The result can only be evaluated with that backend.
+// References that can't be resolved are left as they are,
+// so evaluating them reports the error.
+func Compile(expr Expr, b Backend) Expr {
+	resolver, _ := b.(SymbolResolver)
+
+	switch e := expr.(type) {
+	case variableExpr:
+		if resolver != nil {
+			if get, err := resolver.ResolveVariable(e.name); err == nil {
+				return boundVariableExpr{get}
+			}
+		}
+	case callExpr:
+		args := compileAll(e.args, b)
+		if resolver != nil {
+			if call, err := resolver.ResolveFunction(e.name); err == nil {
+				return boundCallExpr{call, args}
+			}
+		}
+		return callExpr{e.name, args}
+	case builtinExpr:
+		return fold(builtinExpr{e.name, e.call, compileAll(e.args, b)})
+	case percentExpr:
+		return fold(percentExpr{Compile(e.operand, b)})
+	case unaryExpr:
+		return fold(unaryExpr{e.op, Compile(e.operand, b)})
+	case binaryExpr:
+		return fold(binaryExpr{e.op, Compile(e.left, b), Compile(e.right, b)})
+	case ternaryExpr:
+		condition := Compile(e.condition, b)
+		if lit, ok := condition.(literalExpr); ok {
+			if lit.value.Truthy() {
+				return Compile(e.then, b)
+			}
+			return Compile(e.otherwise, b)
 		}
+		return ternaryExpr{condition, Compile(e.then, b), Compile(e.otherwise, b)}
+	}
+
+	return expr
+}
 
-		// if the bracket count is 0, it means the bracket requested
-		// was closed (or never opened)
-		if openBracketCount == 0 {
-			return i
+func compileAll(exprs []Expr, b Backend) (compiled []Expr) {
+	compiled = make([]Expr, len(exprs))
+	for i, expr := range exprs {
+		compiled[i] = Compile(expr, b)
+	}
+
+	return
+}
+
+// replaces an expression by its value if all its operands are literals.
+// Expressions failing to evaluate are kept, so the error is reported
+// when they are evaluated
+func fold(expr Expr) Expr {
+	var operands []Expr
+
+	switch e := expr.(type) {
+	case builtinExpr:
+		operands = e.args
+	case percentExpr:
+		operands = []Expr{e.operand}
+	case unaryExpr:
+		operands = []Expr{e.operand}
+	case binaryExpr:
+		operands = []Expr{e.left, e.right}
+	}
+
+	for _, operand := range operands {
+		if _, ok := operand.(literalExpr); !ok {
+			return expr
 		}
 	}
 
-	// nothing was found
-	return 0
+	value, err := expr.Eval(nil)
+	if err != nil {
+		return expr
+	}
+
+	return literalExpr{value}
+}
+
+// IsConstant tells wether an expression always has the same value
+func IsConstant(expr Expr) bool {
+	_, ok := expr.(literalExpr)
+	return ok
 }
 
-// evaluates the variable at pos 0 in given string and returns
-// the rest of the string
-func evalNextVariable(str string, plug *Plugin) (result string) {
-	varname := alphaNumericRegex.FindString(str)
-	return plug.GetVariable(varname) + str[len(varname):]
+/*
+###########################
+# Section: Evaluation
+###########################
+*/
+
+// Evaluate parses and evaluates an expression
+func Evaluate(src string, b Backend) (value Value, err error) {
+	expr, err := ParseExpression(src)
+	if err != nil {
+		return
+	}
+
+	return expr.Eval(b)
 }
 
-// evaluates the function at pos 0 in given string and returns
-// the rest of the string
-func evalNextFunction(str string, plug *Plugin) (result string) {
-	funcname := functionRegex.FindString(str)
-	return plug.CallFunction(funcname) + str[len(funcname):]
+// CalculateValue calculates the value of an expression
+// and formats it as string, e.g.
+// "(4/3)*@GetPi()*($r^3)"
+func CalculateValue(op string, plug Backend) (result string, err error) {
+	value, err := Evaluate(op, plug)
+	if err != nil {
+		return
+	}
+
+	return value.String(), nil
 }
@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"strconv"
 	"testing"
 )
 
@@ -11,16 +12,111 @@ func TestInit(t *testing.T) {
 var calculateValueTest = map[string]string{
 	`(6/2)*((9+11)-(3*3)+5)`: `48`,
 	`3*3`:                    `9`,
-	`3.1*(4/3)*5^3`:          `517`,
-	`81/3%`:                  `27%`,
+	`2.5*3`:                  `7.5`,
+	`2^3^2`:                  `512`,
+	`-2^2`:                   `-4`,
+	`10-4-3`:                 `3`,
+	`(81/3)%`:                `27%`,
+	`50% - 10`:               `50%-10`,
+	`(50% + 10) * 2`:         `100%+20`,
+	`3 > 2 ? 'yes' : 'no'`:   `yes`,
+	`1 == 2 || !false`:       `true`,
+	`min(4, 2, 8)`:           `2`,
+	`max(4, 2, 8)`:           `8`,
+	`clamp(12, 0, 10)`:       `10`,
+	`floor(2.7)`:             `2`,
+	`'fl' + "iw"`:            `fliw`,
+	`'n=' + 3`:               `n=3`,
 }
 
 func TestCalculateValue(t *testing.T) {
 	for op, expected := range calculateValueTest {
-		result := CalculateValue(op, nil)
+		result, err := CalculateValue(op, nil)
+		if err != nil {
+			t.Error(err)
+		}
 
 		if result != expected {
 			t.Error("Expected ", expected, ", gave ", result)
 		}
 	}
 }
+
+func TestCalculateValueBackend(t *testing.T) {
+	mem := newTestMemory()
+	date := "2024-01-05"
+	zero := "0"
+	mem.Variables["date"] = &date
+	mem.Variables["zero"] = &zero
+	mem.Functions["Double"] = func(args ...string) string {
+		value, _ := strconv.Atoi(args[0])
+		return strconv.Itoa(2 * value)
+	}
+
+	tests := map[string]string{
+		`2*$r`:                      `6`,
+		`@Double($r, 2*$r)`:         `6`,
+		`@Double(@Double($r)) + 1`:  `13`,
+		`$name + '!'`:               `fliw!`,
+		`$r > @GetTwo() ? $r : 0`:   `3`,
+		`@Upper($name, ' ', 'gui')`: `FLIW GUI`,
+		`$r + 1`:                    `4`,
+		`$r == 3 && $r != '3.0'`:    `true`,
+		`$date`:                     `2024-01-05`,
+		`$date + '!'`:               `2024-01-05!`,
+		`$date == '2024-01-05'`:     `true`,
+		`$zero ? 'yes' : 'no'`:      `no`,
+	}
+
+	for op, expected := range tests {
+		result, err := CalculateValue(op, mem)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if result != expected {
+			t.Error("Expected ", expected, ", gave ", result)
+		}
+	}
+}
+
+func TestCalculateValueErrors(t *testing.T) {
+	// % binds like a unit to the number before it, so 81/3% divides
+	// by a relative value. Use (81/3)% for a percentage of a quotient
+	tests := []string{`3*`, `(1+2`, `1 ? 2`, `'open`, `$`, `unknown(1)`, `min()`, `1/0`, `50% * 10%`, `2^50%`, `81/3%`}
+
+	for _, op := range tests {
+		if _, err := CalculateValue(op, &Memory{}); err == nil {
+			t.Error("Expected an error for ", op)
+		}
+	}
+
+	_, err := CalculateValue(`2 + * 3`, nil)
+	if serr, ok := err.(*SyntaxError); !ok || serr.Pos != 4 {
+		t.Error("Expected a syntax error at position 4, gave ", err)
+	}
+}
+
+func TestCalculateValueMissingVariable(t *testing.T) {
+	if _, err := CalculateValue(`2*$missing`, &Memory{}); err == nil {
+		t.Error("Expected an error for a missing variable")
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := map[string]Value{
+		`12`:     NumberOf(12),
+		`-1.5`:   NumberOf(-1.5),
+		`50%`:    {Kind: NumberValue, Percent: 50},
+		`50%-10`: {Kind: NumberValue, Number: -10, Percent: 50},
+		`true`:   BoolOf(true),
+		`nan`:    StringOf(`nan`),
+		`a b`:    StringOf(`a b`),
+	}
+
+	for str, expected := range tests {
+		if result := ParseValue(str); result != expected {
+			t.Error("Expected ", expected, ", gave ", result)
+		}
+	}
+}
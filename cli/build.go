@@ -1,43 +1,373 @@
 package cli
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
+
+	"github.com/phoenixdevelops/fliw/backend"
+	fliwparser "github.com/phoenixdevelops/fliw/parser"
 )
 
-func build(args []string) {
-	if validAbsoluteDirPathRegex.MatchString(args[0]) {
-		// recursively walk through the directory
-		filepath.Walk(args[0], func(path string, info os.FileInfo, err error) error {
-			// skip directories
-			if info.IsDir() {
-				return nil
-			}
+// the name of the plugin file each package is built to
+const pluginName = "app.so"
+
+// the suffix of the file holding the source hash of a built plugin
+const hashSuffix = ".hash"
+
+func build(args []string) (err error) {
+	flags := newFlagSet("build", BuildHelp)
+	force := flags.Bool("force", false, "rebuild all packages, even if they are up to date")
+
+	moduledir, err := parseFlags(flags, args)
+	if err != nil {
+		return
+	}
+
+	return buildModule(moduledir, *force)
+}
+
+// builds all go packages of a module in parallel.
+// Every directory containing a main package is built to a plugin,
+// as well as the backends of linked extensions outside of the module.
+func buildModule(moduledir string, force bool) (err error) {
+	packages, err := getPackageDirs(moduledir)
+	if err != nil {
+		return
+	}
+
+	linked, err := getLinkedPackageDirs(moduledir)
+	if err != nil {
+		return
+	}
+	packages = append(packages, linked...)
+
+	errs := make([]error, len(packages))
+
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, runtime.NumCPU())
+
+	for i, dir := range packages {
+		wg.Add(1)
+		go func(i int, dir string) {
+			defer wg.Done()
+
+			limit <- struct{}{}
+			errs[i] = buildPackage(dir, force)
+			<-limit
+		}(i, dir)
+	}
+
+	wg.Wait()
+
+	// collect the errors of all packages
+	var messages []string
+	for _, err := range errs {
+		if err != nil {
+			messages = append(messages, err.Error())
+		}
+	}
+
+	if len(messages) > 0 {
+		return errors.New(strings.Join(messages, "\n"))
+	}
+
+	return nil
+}
+
+// builds a single package to a plugin if its sources changed
+func buildPackage(dir string, force bool) (err error) {
+	sopath := getSO(dir)
+
+	hash, err := hashSources(dir)
+	if err != nil {
+		return
+	}
 
-			// if it's a go source file
-			if strings.HasSuffix(path, ".go") {
-				// build the file
-				cmd := exec.Command("go", "build", "-o", getSO(path), "-buildmode=plugin", path)
-				err = cmd.Run()
-				if err != nil {
-					log.Fatal("Could not build ", path)
-					return err
-				}
-
-				log.Println("Built file: " + getSO(path))
+	// skip the package if the plugin was built from the same sources
+	if !force && isUpToDate(sopath, hash) {
+		log.Println("Up to date: " + sopath)
+		return nil
+	}
+
+	var stderr bytes.Buffer
+
+	cmd := exec.Command("go", "build", "-o", sopath, "-buildmode=plugin", ".")
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if err != nil {
+		return errors.New("Could not build " + dir + ":\n" + strings.TrimSpace(stderr.String()))
+	}
+
+	err = ioutil.WriteFile(sopath+hashSuffix, []byte(hash), 0644)
+	if err != nil {
+		return
+	}
+
+	log.Println("Built package: " + sopath)
+	return nil
+}
+
+// gets all directories in a module containing a main package
+func getPackageDirs(moduledir string) (dirs []string, err error) {
+	isPackage := make(map[string]bool)
+
+	// recursively walk through the directory
+	err = filepath.Walk(moduledir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// skip hidden directories and directories ignored by go
+		if info.IsDir() {
+			name := info.Name()
+			if path != moduledir && (strings.HasPrefix(name, ".") ||
+				strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor") {
+				return filepath.SkipDir
 			}
+			return nil
+		}
 
+		if !isSourceFile(path) {
 			return nil
-		})
-	} else {
-		log.Fatal("The specified path does not lead to a directory.")
+		}
+
+		dir := getDir(path)
+		if _, ok := isPackage[dir]; ok {
+			return nil
+		}
+
+		// only main packages can be built to a plugin
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.PackageClauseOnly)
+		if err != nil {
+			return err
+		}
+		isPackage[dir] = file.Name.Name == "main"
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	for dir, ok := range isPackage {
+		if ok {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+
+	return
+}
+
+// gets the package directories of the go plugin backends used by linked
+// extension files which are outside of the module directory. The
+// packages inside of the module are already found by getPackageDirs
+func getLinkedPackageDirs(moduledir string) (dirs []string, err error) {
+	// modules without a style.xml don't link anything
+	if _, err := os.Stat(filepath.Join(moduledir, "style.xml")); err != nil {
+		return nil, nil
+	}
+
+	backends, err := fliwparser.LinkedBackends(moduledir)
+	if err != nil {
+		return
+	}
+
+	prefix := filepath.Clean(moduledir) + "/"
+	added := make(map[string]bool)
+
+	for _, path := range backends {
+		// remote backends are not built by fliw
+		if backend.IsRemotePath(path) {
+			continue
+		}
+
+		dir := getDir(filepath.Clean(path))
+		if strings.HasPrefix(dir, prefix) || added[dir] {
+			continue
+		}
+
+		if !isMainPackage(dir) {
+			return nil, errors.New("Could not build linked backend " + path + ": " +
+				dir + " contains no main package")
+		}
+
+		added[dir] = true
+		dirs = append(dirs, dir)
+	}
+
+	return dirs, nil
+}
+
+// tells wether dir contains the sources of a main package
+func isMainPackage(dir string) bool {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false
 	}
+
+	for _, info := range infos {
+		if info.IsDir() || !isSourceFile(info.Name()) {
+			continue
+		}
+
+		file, err := parser.ParseFile(token.NewFileSet(), dir+info.Name(), nil, parser.PackageClauseOnly)
+		return err == nil && file.Name.Name == "main"
+	}
+
+	return false
+}
+
+// gets a hash over all sources of the package in dir, the sources of
+// the packages of its module it imports and the go.mod and go.sum files it uses
+func hashSources(dir string) (hash string, err error) {
+	files, err := getSourceFiles(dir)
+	if err != nil {
+		return
+	}
+
+	if gomod := findGoMod(dir); gomod != "" {
+		files = append(files, gomod)
+		if _, err := os.Stat(getDir(gomod) + "go.sum"); err == nil {
+			files = append(files, getDir(gomod)+"go.sum")
+		}
+	}
+
+	hasher := sha256.New()
+	hasher.Write([]byte(runtime.Version()))
+
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+
+		hasher.Write([]byte(file))
+		hasher.Write(content)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// listedPackage is a package as listed by go list
+type listedPackage struct {
+	Dir      string
+	GoFiles  []string
+	CgoFiles []string
+}
+
+// gets the source files of the package in dir and of all packages
+// of the same module it depends on, as changing them changes the plugin.
+// Packages outside of a module only have their own sources
+func getSourceFiles(dir string) (files []string, err error) {
+	gomod := findGoMod(dir)
+	if gomod == "" {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			if !info.IsDir() && isSourceFile(info.Name()) {
+				files = append(files, dir+info.Name())
+			}
+		}
+		return files, nil
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("go", "list", "-deps", "-json", ".")
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err = cmd.Run(); err != nil {
+		return nil, errors.New("Could not list the dependencies of " + dir + ":\n" + strings.TrimSpace(stderr.String()))
+	}
+
+	moduledir := getDir(gomod)
+	decoder := json.NewDecoder(&stdout)
+	for decoder.More() {
+		var pkg listedPackage
+		if err = decoder.Decode(&pkg); err != nil {
+			return
+		}
+
+		// the standard library and other modules are covered by go.sum
+		pkgdir := filepath.Clean(pkg.Dir) + "/"
+		if !strings.HasPrefix(pkgdir, moduledir) {
+			continue
+		}
+
+		for _, name := range append(pkg.GoFiles, pkg.CgoFiles...) {
+			files = append(files, pkgdir+name)
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// tells wether the plugin at sopath exists and was built from sources with hash
+func isUpToDate(sopath string, hash string) bool {
+	if _, err := os.Stat(sopath); err != nil {
+		return false
+	}
+
+	prevhash, err := ioutil.ReadFile(sopath + hashSuffix)
+	if err != nil {
+		return false
+	}
+
+	return string(prevhash) == hash
+}
+
+// gets the go.mod file of the go module dir is in.
+// Returns an empty string if there is none
+func findGoMod(dir string) (path string) {
+	dir = filepath.Clean(dir)
+
+	for {
+		path = filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// tells wether a file is a go source file which is part of a build
+func isSourceFile(path string) bool {
+	return strings.HasSuffix(path, ".go") && !strings.HasSuffix(path, "_test.go")
+}
+
+// gets the directory of a file (including the trailing slash)
+func getDir(path string) (dir string) {
+	return path[:strings.LastIndex(path, "/")+1]
 }
 
-// gets the so name of the file
-func getSO(path string) (sopath string) {
-	return path[:len(path)-2] + "so"
+// gets the so name of a package directory
+func getSO(dir string) (sopath string) {
+	return dir + pluginName
 }
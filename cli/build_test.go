@@ -1,6 +1,12 @@
 package cli
 
-import "testing"
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
 
 func TestGetDir(t *testing.T) {
 	input := "/home/test/go/src/testproject/testfile.go"
@@ -12,3 +18,145 @@ func TestGetDir(t *testing.T) {
 		t.Error("Expected: ", expected, ", Gave: ", result)
 	}
 }
+
+func TestGetPackageDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fliwbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"app.go":           "package main",
+		"util.go":          "package main",
+		"ext/battery.go":   "package main",
+		"helper/helper.go": "package helper",
+		".hidden/app.go":   "package main",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := getPackageDirs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{dir + "/", dir + "/ext/"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Error("Expected: ", expected, ", Gave: ", result)
+	}
+}
+
+func TestHashSources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fliwbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dir += "/"
+
+	ioutil.WriteFile(dir+"app.go", []byte("package main"), 0644)
+
+	first, err := hashSources(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// test files are not part of the plugin
+	ioutil.WriteFile(dir+"app_test.go", []byte("package main"), 0644)
+	if second, _ := hashSources(dir); second != first {
+		t.Error("Expected test files to not change the hash")
+	}
+
+	ioutil.WriteFile(dir+"go.mod", []byte("module app"), 0644)
+	if third, _ := hashSources(dir); third == first {
+		t.Error("Expected go.mod to change the hash")
+	}
+}
+
+func TestGetLinkedPackageDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fliwbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"module/style.xml":  "<window><link>ext.xml</link><link>../clock/clock.xml</link></window>",
+		"module/ext.xml":    `<extension backend="ext/app.so"/>`,
+		"module/ext/ext.go": "package main",
+		"clock/clock.xml":   `<extension backend="../clock/app.so"><link>../empty/empty.xml</link></extension>`,
+		"clock/clock.go":    "package main",
+		"empty/empty.xml":   `<extension backend="../empty/app.so"/>`,
+		"empty/doc.txt":     "no sources",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// backends outside of the module without a package are rejected
+	_, err = getLinkedPackageDirs(dir + "/module")
+	if err == nil {
+		t.Error("Expected an error for a linked backend without a package")
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "empty/empty.xml"), []byte(`<extension backend="../remote/backend.py"/>`), 0644)
+
+	result, err := getLinkedPackageDirs(dir + "/module")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{dir + "/clock/"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Error("Expected: ", expected, ", Gave: ", result)
+	}
+}
+
+func TestHashSourcesDependencies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fliwbuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dir += "/"
+
+	files := map[string]string{
+		"go.mod":         "module app\n\ngo 1.16\n",
+		"backend/app.go": "package main\n\nimport \"app/lib\"\n\nvar Value = lib.Value\n",
+		"lib/lib.go":     "package lib\n\nconst Value = 1\n",
+		"other/other.go": "package other\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first, err := hashSources(dir + "backend/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// packages of the module that are not imported don't change the plugin
+	ioutil.WriteFile(dir+"other/other.go", []byte("package other\n\nconst Value = 2\n"), 0644)
+	if second, _ := hashSources(dir + "backend/"); second != first {
+		t.Error("Expected packages that are not imported to not change the hash")
+	}
+
+	ioutil.WriteFile(dir+"lib/lib.go", []byte("package lib\n\nconst Value = 2\n"), 0644)
+	if third, _ := hashSources(dir + "backend/"); third == first {
+		t.Error("Expected imported packages of the module to change the hash")
+	}
+}
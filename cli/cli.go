@@ -1,13 +1,20 @@
 package cli
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-	"regexp"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 const MainHelp = `
 FLIW - Framework for Low-Interaction Windows
 
+Usage: fliw <command> [arguments]
+
 The FLIW command-line interface supports the following commands:
 
 run   - runs a fliw package[1]. See "fliw help run"
@@ -22,61 +29,197 @@ For more information on how to make a fliw package, please visit the fliw github
 `
 
 const RunHelp = `
-WIP
+Usage: fliw run [-no-build] [-display N] [-watch] [-backend path] <moduledir>
+
+Builds the fliw package in moduledir and shows its window.
+Relative paths are resolved against the current working directory.
+
+Flags:
+  -no-build   don't build the package before running it
+  -display N  show the window on display N (default 0)
+  -watch      reload the window when style.xml, a linked extension file
+              or a texture changes. If the changed files contain errors,
+              the previous window is kept
+  -backend    the backend of style.xml, relative to moduledir
+              (default app.so). Paths not ending with .so are started
+              as a separate process talking to fliw over stdin/stdout,
+              paths starting with unix: are connected to as unix socket.
+              Separate backends are restarted when they are rebuilt.
 `
 
 const BuildHelp = `
-WIP
+Usage: fliw build [-force] <moduledir>
+
+Builds every main package in moduledir and its subdirectories as a
+go plugin named app.so inside the package directory. The go plugin
+backends of linked extension files outside of moduledir are built as
+well. Packages are built in parallel. A package is only rebuilt if its
+sources, go.mod or go.sum changed since the last build.
+Relative paths are resolved against the current working directory.
+
+Older versions built every source file to its own plugin, e.g.
+ext/battery.go to ext/battery.so. Backends still referring to such a
+plugin fall back to the app.so in the same directory, so existing
+backend="ext/battery.so" attributes keep working. New files should
+refer to ext/app.so instead.
+
+Flags:
+  -force  rebuild all packages, even if they are up to date
 `
 
 const TestHelp = `
-WIP
+Usage: fliw test [-backend path] <moduledir>
+
+Tests the fliw package in moduledir for errors and reports them.
+Relative paths are resolved against the current working directory.
+
+The following is checked:
+  - style.xml and all linked extension files are valid
+  - all variables ($var) and functions (@func()) used exist in the
+    backend and have the right signature
+  - all event functions exist in the backend
+  - all textures exist
+
+The backends have to be built (see "fliw help build").
+
+Flags:
+  -backend  the backend of style.xml, see "fliw help run"
 `
 
 const DepsHelp = `
 WIP
 `
 
-var validAbsoluteDirPathRegex *regexp.Regexp
+// usageError is returned when a command was called
+// with invalid arguments
+type usageError struct {
+	msg string
+}
 
-// initialize.
-func init() {
-	validAbsoluteDirPathRegex = regexp.MustCompile(`/([[:graph:]]+/?)*`)
+func (e usageError) Error() string {
+	return e.msg
+}
+
+// ExitCode gets the exit code the executable should
+// exit with after RunCommand returned err
+func ExitCode(err error) int {
+	switch err.(type) {
+	case nil:
+		return 0
+	case usageError:
+		return 2
+	default:
+		return 1
+	}
 }
 
 // RunCommand runs a command given the subcommand and its arguments.
 // If the command is unknown it displays a help instead.
-func RunCommand(command ...string) {
-	if len(command) > 1 {
-		switch command[0] {
-		case "run":
-			run(command[1:])
-		case "build":
-			build(command[1:])
-		case "test":
-			test(command[1:])
-		case "help":
-			displayHelp(command[1])
-		}
-	} else {
+func RunCommand(command ...string) (err error) {
+	if len(command) == 0 {
 		displayHelp()
+		return usageError{"no command given"}
+	}
+
+	switch command[0] {
+	case "run":
+		err = run(command[1:])
+	case "build":
+		err = build(command[1:])
+	case "test":
+		err = test(command[1:])
+	case "help", "-h", "-help", "--help":
+		displayHelp(command[1:]...)
+	default:
+		displayHelp()
+		err = usageError{"unknown command: " + command[0]}
+	}
+
+	// the help was requested and has been displayed
+	if err == flag.ErrHelp {
+		return nil
 	}
+
+	return
 }
 
 // displayHelp displays help in form of text.
 func displayHelp(subcommand ...string) {
+	if len(subcommand) == 0 {
+		fmt.Print(MainHelp)
+		return
+	}
+
 	switch subcommand[0] {
-	case "":
-		fmt.Println(MainHelp)
 	case "run":
-		fmt.Println(RunHelp)
+		fmt.Print(RunHelp)
 	case "build":
-		fmt.Println(BuildHelp)
+		fmt.Print(BuildHelp)
 	case "test":
-		fmt.Println(TestHelp)
+		fmt.Print(TestHelp)
 	case "deps":
-		fmt.Println(DepsHelp)
+		fmt.Print(DepsHelp)
 	default:
-		fmt.Println(MainHelp)
+		fmt.Print(MainHelp)
+	}
+}
+
+// newFlagSet creates a flag set for a subcommand
+// which displays the subcommand help on error
+func newFlagSet(name string, help string) (flags *flag.FlagSet) {
+	flags = flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.Usage = func() {
+		fmt.Print(help)
+	}
+
+	return
+}
+
+// parseFlags parses the arguments of a subcommand and
+// gets the module directory, which is the only positional argument
+func parseFlags(flags *flag.FlagSet, args []string) (moduledir string, err error) {
+	// the flag set displays the help by itself on error
+	err = flags.Parse(args)
+	if err == flag.ErrHelp {
+		return "", err
+	}
+	if err != nil {
+		return "", usageError{err.Error()}
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return "", usageError{"expected exactly one module directory"}
 	}
+
+	return resolveModuleDir(flags.Arg(0))
+}
+
+// resolveBackend makes a backend path relative to the module directory absolute
+func resolveBackend(moduledir string, path string) (backendpath string) {
+	if path == "" || filepath.IsAbs(path) || strings.HasPrefix(path, "unix:") {
+		return path
+	}
+
+	return filepath.Join(moduledir, path)
+}
+
+// resolveModuleDir makes a module path absolute (relative to
+// the working directory) and makes sure it leads to a directory
+func resolveModuleDir(path string) (moduledir string, err error) {
+	moduledir, err = filepath.Abs(path)
+	if err != nil {
+		return
+	}
+
+	info, err := os.Stat(moduledir)
+	if err != nil {
+		return
+	}
+	if !info.IsDir() {
+		return "", errors.New("The specified path does not lead to a directory: " + moduledir)
+	}
+
+	return moduledir, nil
 }
@@ -1 +1,43 @@
 package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveModuleDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := resolveModuleDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != wd {
+		t.Error("Expected: ", wd, ", Gave: ", result)
+	}
+
+	// files are not module directories
+	_, err = resolveModuleDir(filepath.Join(wd, "cli.go"))
+	if err == nil {
+		t.Error("Expected an error for a file path")
+	}
+}
+
+func TestRunCommandUsage(t *testing.T) {
+	if code := ExitCode(RunCommand()); code != 2 {
+		t.Error("Expected exit code 2 without command, gave ", code)
+	}
+	if code := ExitCode(RunCommand("unknown")); code != 2 {
+		t.Error("Expected exit code 2 for unknown command, gave ", code)
+	}
+	if code := ExitCode(RunCommand("build")); code != 2 {
+		t.Error("Expected exit code 2 for missing module directory, gave ", code)
+	}
+	if code := ExitCode(RunCommand("help", "run")); code != 0 {
+		t.Error("Expected exit code 0 for help, gave ", code)
+	}
+}
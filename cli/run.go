@@ -1,20 +1,53 @@
 package cli
 
 import (
-	"log"
-	"runtime/debug"
-
 	"github.com/phoenixdevelops/fliw/launcher"
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
 )
 
-func run(args []string) {
-	if validAbsoluteDirPathRegex.MatchString(args[0]) {
-		err := launcher.ShowWindow(args[0])
+func run(args []string) (err error) {
+	flags := newFlagSet("run", RunHelp)
+	nobuild := flags.Bool("no-build", false, "don't build the package before running it")
+	display := flags.Int("display", 0, "the display to show the window on")
+	watch := flags.Bool("watch", false, "reload the window when its files change")
+	backendpath := flags.String("backend", "", "the backend of the window")
+
+	moduledir, err := parseFlags(flags, args)
+	if err != nil {
+		return
+	}
+
+	if !*nobuild {
+		err = buildModule(moduledir, false)
 		if err != nil {
-			debug.PrintStack()
-			log.Fatal(err)
+			return
 		}
-	} else {
-		log.Fatal("Specified path does not lead to a directory.")
 	}
+
+	err = initialize()
+	if err != nil {
+		return
+	}
+
+	return launcher.ShowWindow(moduledir, launcher.Options{
+		Display: *display,
+		Watch:   *watch,
+		Backend: resolveBackend(moduledir, *backendpath),
+	})
+}
+
+// initializes sdl and sdl_ttf
+func initialize() (err error) {
+	err = sdl.Init(sdl.INIT_EVERYTHING)
+	if err != nil {
+		return err
+	}
+
+	err = ttf.Init()
+	if err != nil {
+		return err
+	}
+
+	return nil
 }
@@ -1,5 +1,31 @@
 package cli
 
-func test(args []string) {
+import (
+	"errors"
+	"fmt"
+	"strconv"
 
+	"github.com/phoenixdevelops/fliw/parser"
+)
+
+func test(args []string) (err error) {
+	flags := newFlagSet("test", TestHelp)
+	backendpath := flags.String("backend", "", "the backend of style.xml")
+
+	moduledir, err := parseFlags(flags, args)
+	if err != nil {
+		return
+	}
+
+	problems := parser.CheckModule(moduledir, resolveBackend(moduledir, *backendpath))
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+
+	if len(problems) > 0 {
+		return errors.New("Found " + strconv.Itoa(len(problems)) + " problem(s) in " + moduledir)
+	}
+
+	fmt.Println("No problems found in " + moduledir)
+	return nil
 }
@@ -1,9 +1,12 @@
 package data
 
 import (
+	"math"
+	"strings"
+
+	"github.com/phoenixdevelops/fliw/font"
 	"github.com/phoenixdevelops/fliw/image"
 	"github.com/veandco/go-sdl2/sdl"
-	"github.com/veandco/go-sdl2/ttf"
 )
 
 /*
@@ -41,6 +44,9 @@ const (
 	CENTER Align = 1
 	RIGHT  Align = 2
 	BOTTOM Align = 2
+
+	// only used by boxes, fills the whole height (width) of a row (column)
+	STRETCH Align = 3
 )
 
 // unused function that will fail to compile
@@ -48,9 +54,20 @@ const (
 func checkIntefraceSatisfaction() {
 	var _ Container = (*BaseContainer)(nil)
 	var _ Container = (*ListContainer)(nil)
+	var _ Container = (*Box)(nil)
+	var _ Container = (*Grid)(nil)
 	var _ Item = (*Label)(nil)
+	var _ Item = (*Text)(nil)
 	var _ Item = (*Texture)(nil)
 	var _ Item = (*Unicolor)(nil)
+	var _ Item = (*Gradient)(nil)
+	var _ Item = (*Rectangle)(nil)
+	var _ Item = (*Ellipse)(nil)
+	var _ Item = (*Line)(nil)
+	var _ Item = (*Polygon)(nil)
+	var _ Item = (*Progress)(nil)
+	var _ Item = (*Gauge)(nil)
+	var _ Item = (*LevelBar)(nil)
 }
 
 /*
@@ -71,6 +88,10 @@ type Item interface {
 	GetEvents() map[string]string
 	SetHasChanged(bool)
 	HasChanged() bool
+	GetFlex() Flex
+	GetGridCell() GridCell
+	GetStyle() Style
+	SetStyle(Style)
 }
 
 // Container is an item containing other items.
@@ -86,6 +107,11 @@ type Container interface {
 	GetItemAt(Vector) Item
 	GetIsLink() bool
 	SetLink(bool)
+	GetBGcolor() uint32
+	SetBGcolor(uint32)
+	Layout() []Vector
+	GetScrollMode() ScrollMode
+	HasScrollbar() bool
 }
 
 type ItemBase struct {
@@ -94,6 +120,25 @@ type ItemBase struct {
 	Size     Vector
 	Events   map[string]string
 	Changed  bool
+	Flex     Flex
+	GridCell GridCell
+	Style    Style
+}
+
+// Flex tells how an item in a box grows or shrinks
+// to fill the space of the box
+type Flex struct {
+	// the share of the free space the item gets
+	Grow float64
+
+	// how much the item shrinks compared to the others,
+	// if the items don't fit the box
+	Shrink float64
+
+	// the size of the item before growing or shrinking.
+	// It is given for both directions, as the item doesn't
+	// know the direction of its box. The item's size is used if 0
+	Basis Vector
 }
 
 // GetUID returns the unique identifier of the item
@@ -143,12 +188,27 @@ func (base *ItemBase) HasChanged() (hasChanged bool) {
 	return base.Changed
 }
 
+// GetFlex gets how the item is sized inside of a box
+func (base *ItemBase) GetFlex() Flex {
+	return base.Flex
+}
+
+// GetGridCell gets where the item is placed inside of a grid
+func (base *ItemBase) GetGridCell() GridCell {
+	return base.GridCell
+}
+
 // ContainerBase is the base for every container struct
 type ContainerBase struct {
 	ItemBase
 	BGcolor uint32
 	Items   []Item
 	IsLink  bool
+
+	// the directions the items can be scrolled in
+	// and wether a scrollbar is drawn
+	Scroll    ScrollMode
+	Scrollbar bool
 }
 
 // MoveItem moves the item to a pixel position
@@ -196,6 +256,235 @@ func (cont *ContainerBase) SetLink(isLink bool) {
 	cont.IsLink = isLink
 }
 
+// GetBGcolor gets the color the items of the container are drawn on
+func (cont *ContainerBase) GetBGcolor() uint32 {
+	return cont.BGcolor
+}
+
+// SetBGcolor sets the color the items of the container are drawn on
+func (cont *ContainerBase) SetBGcolor(color uint32) {
+	cont.BGcolor = color
+}
+
+// GetScrollMode gets the directions the container can be scrolled in
+func (cont *ContainerBase) GetScrollMode() ScrollMode {
+	return cont.Scroll
+}
+
+// HasScrollbar tells wether the container draws a scrollbar when it can be scrolled
+func (cont *ContainerBase) HasScrollbar() bool {
+	return cont.Scrollbar
+}
+
+// draws the items of a container onto a surface filled with its color.
+// Each item draws onto its own surface, which is then blended onto the main surface
+// at the position the container's layout gives it
+func drawItems(cont Container, surf *sdl.Surface) (err error) {
+	layout := scrolledLayout(cont)
+
+	// flip bytes for sdl
+	surf.FillRect(nil, image.UInt32ToColor(cont.GetBGcolor()).Uint32())
+
+	// let each item draw onto the surface
+	for i, item := range cont.GetItems() {
+		frame := frameRect(item, layout[i])
+
+		// if not in picure don't draw
+		if frame.X > surf.W || frame.Y > surf.H || frame.X+frame.W < 0 || frame.Y+frame.H < 0 {
+			continue
+		}
+
+		isurface, err := image.NewSurface(frame.W, frame.H)
+		if err != nil {
+			return err
+		}
+
+		err = item.Draw(isurface)
+		if err != nil {
+			isurface.Free()
+			return err
+		}
+		decorate(item.GetStyle(), isurface)
+		isurface.SetAlphaMod(item.GetStyle().alpha())
+
+		// draw the item surface onto the container surface
+		srcRect := sdl.Rect{X: 0, Y: 0, W: frame.W, H: frame.H}
+		isurface.Blit(&srcRect, surf, &frame)
+
+		isurface.Free()
+	}
+
+	drawScrollbars(cont, surf)
+	return nil
+}
+
+// gets the item of a container at position pos
+func getItemAt(cont Container, pos Vector) Item {
+	layout := scrolledLayout(cont)
+
+	for i, item := range cont.GetItems() {
+		frame := frameRect(item, layout[i])
+
+		// Check if pos is inside item, the margin and
+		// the area outside of rounded corners are not
+		if frame.X <= pos.X && frame.Y <= pos.Y {
+			if (frame.X+frame.W) > pos.X && (frame.Y+frame.H) > pos.Y &&
+				item.GetStyle().contains(item.GetSize(), Vector{X: pos.X - frame.X, Y: pos.Y - frame.Y}) {
+				return item
+			}
+		}
+	}
+
+	// if nothing was found, the area must be the container itself
+	return cont
+}
+
+// ItemAt gets the innermost item at position pos of root
+// and the position relative to the item
+func ItemAt(root Container, pos Vector) (item Item, local Vector) {
+	cont := root
+
+	for {
+		item = cont.GetItemAt(pos)
+		child, isContainer := item.(Container)
+		if isContainer && child == cont {
+			return item, pos
+		}
+
+		position := ItemPosition(cont, item)
+		pos.X -= position.X
+		pos.Y -= position.Y
+
+		if !isContainer {
+			return item, pos
+		}
+		cont = child
+	}
+}
+
+// SameContent tells wether two items look the same if they are drawn.
+// The position is not compared, as it is up to the parent
+// where an item is drawn. The items of a container are not compared either.
+func SameContent(a Item, b Item) bool {
+	if a.GetUID() != b.GetUID() || a.GetSize() != b.GetSize() || a.GetStyle() != b.GetStyle() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *BaseContainer:
+		b, ok := b.(*BaseContainer)
+		return ok && a.BGcolor == b.BGcolor && a.IsLink == b.IsLink
+	case *ListContainer:
+		b, ok := b.(*ListContainer)
+		return ok && a.BGcolor == b.BGcolor && a.IsLink == b.IsLink
+	case *Box:
+		b, ok := b.(*Box)
+		return ok && a.BGcolor == b.BGcolor && a.IsLink == b.IsLink
+	case *Grid:
+		b, ok := b.(*Grid)
+		return ok && a.BGcolor == b.BGcolor && a.IsLink == b.IsLink
+	case *Label:
+		b, ok := b.(*Label)
+		return ok && a.Text == b.Text && a.Textsize == b.Textsize &&
+			a.Valign == b.Valign && a.Halign == b.Halign &&
+			a.Color == b.Color && a.BGcolor == b.BGcolor && a.Bold == b.Bold &&
+			a.Font == b.Font && a.Weight == b.Weight && a.Italic == b.Italic &&
+			sameSpans(a.Spans, b.Spans)
+	case *Text:
+		b, ok := b.(*Text)
+		return ok && SameContent(&a.Label, &b.Label) && a.Maxlines == b.Maxlines &&
+			a.LineHeight == b.LineHeight && a.Justify == b.Justify
+	case *Texture:
+		b, ok := b.(*Texture)
+		return ok && a.Texture == b.Texture && a.Fit == b.Fit &&
+			a.Valign == b.Valign && a.Halign == b.Halign && a.Slice == b.Slice
+	case *Unicolor:
+		b, ok := b.(*Unicolor)
+		return ok && a.Color == b.Color
+	case *Gradient:
+		b, ok := b.(*Gradient)
+		return ok && a.Radial == b.Radial && a.Angle == b.Angle && sameStops(a.Stops, b.Stops)
+	case *Rectangle:
+		b, ok := b.(*Rectangle)
+		return ok && a.Shape == b.Shape
+	case *Ellipse:
+		b, ok := b.(*Ellipse)
+		return ok && a.Shape == b.Shape
+	case *Line:
+		b, ok := b.(*Line)
+		return ok && a.Shape == b.Shape && a.From == b.From && a.To == b.To
+	case *Polygon:
+		b, ok := b.(*Polygon)
+		return ok && a.Shape == b.Shape && samePoints(a.Points, b.Points)
+	case *Progress:
+		b, ok := b.(*Progress)
+		return ok && sameMeter(a.Meter, b.Meter) && a.Vertical == b.Vertical
+	case *Gauge:
+		b, ok := b.(*Gauge)
+		return ok && sameMeter(a.Meter, b.Meter) && a.Thickness == b.Thickness &&
+			a.Start == b.Start && a.Sweep == b.Sweep
+	case *LevelBar:
+		b, ok := b.(*LevelBar)
+		return ok && sameMeter(a.Meter, b.Meter) && a.Vertical == b.Vertical &&
+			a.Blocks == b.Blocks && a.Spacing == b.Spacing
+	case *Entry:
+		b, ok := b.(*Entry)
+		return ok && a.Text == b.Text && a.Textsize == b.Textsize &&
+			a.Color == b.Color && a.BGcolor == b.BGcolor &&
+			a.Font == b.Font && a.Weight == b.Weight && a.Italic == b.Italic &&
+			a.Placeholder == b.Placeholder && a.PlaceholderColor == b.PlaceholderColor &&
+			a.Password == b.Password && a.Focused == b.Focused && a.Cursor == b.Cursor &&
+			a.SelStart == b.SelStart && a.SelEnd == b.SelEnd && a.SelectionColor == b.SelectionColor
+	}
+
+	return false
+}
+
+// tells wether two lists of spans are the same
+func sameSpans(a []Span, b []Span) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// tells wether two lists of gradient stops are the same
+func sameStops(a []Stop, b []Stop) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// tells wether two lists of points are the same
+func samePoints(a []Vector, b []Vector) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
 /*
 ####################################################################
 # Section: Basic item types
@@ -217,32 +506,19 @@ type BaseContainer struct {
 // Draw draws a container onto a surface
 // The container will let each item draw onto its own surface and then draw that onto the main surface
 func (cont *BaseContainer) Draw(surf *sdl.Surface) (err error) {
-	// let each item draw onto the surface
-	for _, val := range cont.Items {
-		// get size and position of the item
-		pos := val.GetPosition()
-		size := val.GetSize()
-
-		isurface, err := sdl.CreateRGBSurface(0, size.X, size.Y, 32, 0, 0, 0, 0)
-		if err != nil {
-			return err
-		}
-
-		// also apply background color
-		// flip bytes for sdl
-		isurface.FillRect(nil, image.UInt32ToColor(cont.BGcolor).Uint32())
-
-		err = val.Draw(isurface)
-		if err != nil {
-			return err
-		}
+	return drawItems(cont, surf)
+}
 
-		// draw the item surface onto the container surface
-		srcRect := sdl.Rect{X: 0, Y: 0, W: size.X, H: size.Y}
-		dstRect := sdl.Rect{X: pos.X, Y: pos.Y, W: size.X, H: size.Y}
-		isurface.Blit(&srcRect, surf, &dstRect)
+// Layout gets the position of each item inside the container.
+// In a container, all items are drawn at their own position,
+// relative to the content area
+func (cont *BaseContainer) Layout() (positions []Vector) {
+	content := cont.Style.Content(cont.Size)
 
-		isurface.Free()
+	positions = make([]Vector, len(cont.Items))
+	for i, item := range cont.Items {
+		pos := item.GetPosition()
+		positions[i] = Vector{X: content.X + pos.X, Y: content.Y + pos.Y}
 	}
 
 	return
@@ -250,20 +526,7 @@ func (cont *BaseContainer) Draw(surf *sdl.Surface) (err error) {
 
 // GetItemAt gets you the item at position pos
 func (cont *BaseContainer) GetItemAt(pos Vector) Item {
-	for _, item := range cont.Items {
-		position := item.GetPosition()
-		size := item.GetSize()
-
-		// Check if pos is inside item
-		if position.X <= pos.X && position.Y <= pos.Y {
-			if (position.X+size.X) > pos.X && (position.Y+size.Y) > pos.Y {
-				return item
-			}
-		}
-	}
-
-	// if nothing was found, the area must be the container itself
-	return cont
+	return getItemAt(cont, pos)
 }
 
 /*
@@ -284,66 +547,418 @@ type ListContainer struct {
 // The container will let each item draw onto its own surface and then draw that onto the main surface
 // in a listcontainer all items are drawn below each other with item pos y as offset
 func (cont *ListContainer) Draw(surf *sdl.Surface) (err error) {
+	return drawItems(cont, surf)
+}
 
-	yoffset := int32(0)
+// Layout gets the position of each item inside the container.
+// The items are listed below each other, their y position
+// is used as space to the previous item
+func (cont *ListContainer) Layout() (positions []Vector) {
+	content := cont.Style.Content(cont.Size)
+	positions = make([]Vector, len(cont.Items))
 
-	// let each item draw onto the surface
-	for _, item := range cont.Items {
+	yoffset := content.Y
+	for i, item := range cont.Items {
 		pos := item.GetPosition()
 		size := item.GetSize()
 
-		// if not in picure don't draw
-		if yoffset+pos.Y > surf.H {
-			continue
+		positions[i] = Vector{X: content.X + pos.X, Y: pos.Y + yoffset}
+		yoffset += pos.Y + size.Y
+	}
+
+	return
+}
+
+// GetItemAt gets you the item at position pos
+func (cont *ListContainer) GetItemAt(pos Vector) Item {
+	return getItemAt(cont, pos)
+}
+
+/*
+########################
+# Subsection: Box
+########################
+*/
+
+// Justify tells how a box distributes the space its items don't fill
+type Justify int
+
+// the ways of distributing space in a box
+const (
+	// the items are packed at the start, center or end of the box
+	JustifyStart Justify = iota
+	JustifyCenter
+	JustifyEnd
+
+	// the space is put between the items, also around
+	// them or as equal gaps including the borders
+	JustifySpaceBetween
+	JustifySpaceAround
+	JustifySpaceEvenly
+)
+
+// heightMeasurer is implemented by items whose height depends on
+// their width, so boxes can get it for the width they give the item
+type heightMeasurer interface {
+	heightAt(width int32) (height int32, ok bool)
+}
+
+// gets the size of an item with its height measured at width,
+// if its height depends on its width
+func measuredSize(item Item, width int32) (size Vector) {
+	size = item.GetSize()
+	if measurer, ok := item.(heightMeasurer); ok {
+		if height, ok := measurer.heightAt(width); ok {
+			size.Y = height
 		}
+	}
 
-		isurface, err := sdl.CreateRGBSurface(0, size.X, size.Y, 32, 0, 0, 0, 0)
-		if err != nil {
-			return err
+	return
+}
+
+// Box lists its items in a row (hbox) or column (vbox).
+// The items are sized by their Flex to fill the box
+type Box struct {
+	ContainerBase
+
+	Vertical bool
+
+	// the space between items
+	Spacing int32
+
+	// the alignment of the items across the direction of the box
+	Align Align
+
+	// the distribution of the space along the direction of the box
+	Justify Justify
+}
+
+// Draw draws a box onto a surface
+func (box *Box) Draw(surf *sdl.Surface) (err error) {
+	return drawItems(box, surf)
+}
+
+// Layout gets the position of each item inside the box.
+// It also sets the size of each item to the size it gets in the box
+func (box *Box) Layout() (positions []Vector) {
+	positions = make([]Vector, len(box.Items))
+	if len(box.Items) == 0 {
+		return
+	}
+
+	// the items are placed in the content area
+	content := box.Style.Content(box.Size)
+	startmain, startcross := box.axes(Vector{X: content.X, Y: content.Y})
+	innermain, innercross := box.axes(Vector{X: content.W, Y: content.H})
+
+	// get the sizes before growing or shrinking
+	sizes := make([]float64, len(box.Items))
+	free := float64(innermain - box.Spacing*int32(len(box.Items)-1))
+	var grow, shrink float64
+
+	for i, item := range box.Items {
+		flex := item.GetFlex()
+
+		basis, _ := box.axes(flex.Basis)
+		if basis == 0 {
+			size := item.GetSize()
+
+			// the height of items in a vbox may depend on the width they get
+			if box.Vertical {
+				width := size.X
+				if box.Align == STRETCH {
+					width = innercross
+				}
+				size = measuredSize(item, width)
+			}
+
+			basis, _ = box.axes(size)
 		}
 
-		// also apply background color
-		// flip bytes for sdl
-		isurface.FillRect(nil, image.UInt32ToColor(cont.BGcolor).Uint32())
+		sizes[i] = float64(basis)
+		free -= sizes[i]
+		grow += flex.Grow
+		shrink += flex.Shrink * sizes[i]
+	}
 
-		err = item.Draw(isurface)
-		if err != nil {
-			return err
+	// give the free space to the growing items or
+	// take the missing space from the shrinking ones
+	switch {
+	case free > 0 && grow > 0:
+		for i, item := range box.Items {
+			sizes[i] += free * item.GetFlex().Grow / grow
+		}
+		free = 0
+	case free < 0 && shrink > 0:
+		for i, item := range box.Items {
+			sizes[i] = math.Max(0, sizes[i]+free*item.GetFlex().Shrink*sizes[i]/shrink)
 		}
+		free = 0
+	case free < 0:
+		free = 0
+	}
 
-		// draw the item surface onto the container surface
-		srcRect := sdl.Rect{X: 0, Y: 0, W: size.X, H: size.Y}
-		dstRect := sdl.Rect{X: pos.X, Y: pos.Y + yoffset, W: size.X, H: size.Y}
-		isurface.Blit(&srcRect, surf, &dstRect)
+	// distribute the remaining space
+	count := float64(len(box.Items))
+	offset, gap := 0.0, float64(box.Spacing)
+
+	switch box.Justify {
+	case JustifyCenter:
+		offset = free / 2
+	case JustifyEnd:
+		offset = free
+	case JustifySpaceBetween:
+		if count > 1 {
+			gap += free / (count - 1)
+		}
+	case JustifySpaceAround:
+		offset = free / count / 2
+		gap += free / count
+	case JustifySpaceEvenly:
+		offset = free / (count + 1)
+		gap += free / (count + 1)
+	}
 
-		isurface.Free()
+	// place the items, rounding their borders so there are no gaps
+	position := float64(startmain) + offset
+	for i, item := range box.Items {
+		start := math.Round(position)
+		main := int32(math.Round(position+sizes[i]) - start)
 
-		yoffset += pos.Y + size.Y
+		// the height of items in an hbox may depend on the width they get
+		size := item.GetSize()
+		if !box.Vertical {
+			size = measuredSize(item, main)
+		}
+
+		_, cross := box.axes(size)
+		crossposition := startcross
+		switch box.Align {
+		case CENTER:
+			crossposition += (innercross - cross) / 2
+		case RIGHT:
+			crossposition += innercross - cross
+		case STRETCH:
+			cross = innercross
+		}
+
+		item.SetSize(box.vector(main, cross))
+		positions[i] = box.vector(int32(start), crossposition)
+
+		position += sizes[i] + gap
 	}
 
-	return nil
+	return
 }
 
 // GetItemAt gets you the item at position pos
-func (cont *ListContainer) GetItemAt(pos Vector) Item {
-	var yoffset int32
+func (box *Box) GetItemAt(pos Vector) Item {
+	return getItemAt(box, pos)
+}
 
-	for _, item := range cont.Items {
-		position := item.GetPosition()
-		size := item.GetSize()
+// splits a vector into the part along the direction of the box
+// and the part across it
+func (box *Box) axes(v Vector) (main int32, cross int32) {
+	if box.Vertical {
+		return v.Y, v.X
+	}
+	return v.X, v.Y
+}
 
-		// Check if pos is inside item
-		if position.X <= pos.X && position.Y+yoffset <= pos.Y {
-			if (position.X+size.X) > pos.X && (position.Y+size.Y+yoffset) > pos.Y {
-				return item
+// makes a vector from the parts along and across the direction of the box
+func (box *Box) vector(main int32, cross int32) Vector {
+	if box.Vertical {
+		return Vector{X: cross, Y: main}
+	}
+	return Vector{X: main, Y: cross}
+}
+
+/*
+########################
+# Subsection: Grid
+########################
+*/
+
+// GridCell tells where an item is placed in a grid.
+// Columns and rows are counted from 1, items without a
+// column or row are placed in the next free cells
+type GridCell struct {
+	Column int
+	Row    int
+
+	// the count of columns and rows the item covers, 1 if 0
+	ColumnSpan int
+	RowSpan    int
+}
+
+// Grid places its items in the cells of a table,
+// each item filling the cells it covers
+type Grid struct {
+	ContainerBase
+
+	// the count of columns. Rows are added as needed,
+	// but there are at least Rows rows
+	Columns int
+	Rows    int
+
+	// the space between columns and rows
+	ColumnGap int32
+	RowGap    int32
+}
+
+// Draw draws a grid onto a surface
+func (grid *Grid) Draw(surf *sdl.Surface) (err error) {
+	return drawItems(grid, surf)
+}
+
+// Layout gets the position of each item inside the grid.
+// It also sets the size of each item to the size of its cells
+func (grid *Grid) Layout() (positions []Vector) {
+	positions = make([]Vector, len(grid.Items))
+
+	cells, rows := grid.place()
+	columns := grid.columns()
+	content := grid.Style.Content(grid.Size)
+
+	width := float64(content.W-grid.ColumnGap*int32(columns-1)) / float64(columns)
+	height := float64(content.H-grid.RowGap*int32(rows-1)) / float64(rows)
+
+	// gets the start and end of a span of cells, rounded so there are no gaps
+	span := func(start int, count int, size float64, gap int32) (int32, int32) {
+		from := math.Round(float64(start) * (size + float64(gap)))
+		to := math.Round(float64(start+count)*(size+float64(gap)) - float64(gap))
+		return int32(from), int32(to - from)
+	}
+
+	for i, item := range grid.Items {
+		x, w := span(cells[i].Column, cells[i].ColumnSpan, width, grid.ColumnGap)
+		y, h := span(cells[i].Row, cells[i].RowSpan, height, grid.RowGap)
+
+		positions[i] = Vector{X: content.X + x, Y: content.Y + y}
+		item.SetSize(Vector{X: w, Y: h})
+	}
+
+	return
+}
+
+// GetItemAt gets you the item at position pos
+func (grid *Grid) GetItemAt(pos Vector) Item {
+	return getItemAt(grid, pos)
+}
+
+// gets the count of columns, at least 1
+func (grid *Grid) columns() int {
+	if grid.Columns < 1 {
+		return 1
+	}
+	return grid.Columns
+}
+
+// places the items in cells, counted from 0.
+// Items with a column and row are placed first, the other items
+// are placed in the first free cells, row by row.
+// It also gets the count of rows needed
+func (grid *Grid) place() (cells []GridCell, rows int) {
+	columns := grid.columns()
+	cells = make([]GridCell, len(grid.Items))
+	occupied := make(map[[2]int]bool)
+
+	fits := func(cell GridCell) bool {
+		if cell.Column+cell.ColumnSpan > columns {
+			return false
+		}
+		for c := cell.Column; c < cell.Column+cell.ColumnSpan; c++ {
+			for r := cell.Row; r < cell.Row+cell.RowSpan; r++ {
+				if occupied[[2]int{c, r}] {
+					return false
+				}
 			}
 		}
+		return true
+	}
 
-		yoffset += position.Y + size.Y
+	mark := func(i int, cell GridCell) {
+		cells[i] = cell
+		for c := cell.Column; c < cell.Column+cell.ColumnSpan; c++ {
+			for r := cell.Row; r < cell.Row+cell.RowSpan; r++ {
+				occupied[[2]int{c, r}] = true
+			}
+		}
+		if cell.Row+cell.RowSpan > rows {
+			rows = cell.Row + cell.RowSpan
+		}
 	}
 
-	// if nothing was found, the area must be the container itself
-	return cont
+	// the cells as given by the items, counted from 0
+	wanted := make([]GridCell, len(grid.Items))
+	for i, item := range grid.Items {
+		cell := item.GetGridCell()
+		if cell.ColumnSpan < 1 {
+			cell.ColumnSpan = 1
+		}
+		if cell.ColumnSpan > columns {
+			cell.ColumnSpan = columns
+		}
+		if cell.RowSpan < 1 {
+			cell.RowSpan = 1
+		}
+		if cell.Column > columns-cell.ColumnSpan+1 {
+			cell.Column = columns - cell.ColumnSpan + 1
+		}
+		cell.Column--
+		cell.Row--
+		wanted[i] = cell
+
+		if cell.Column >= 0 && cell.Row >= 0 {
+			mark(i, cell)
+		}
+	}
+
+	cursor := 0
+	for i, cell := range wanted {
+		switch {
+		case cell.Column >= 0 && cell.Row >= 0:
+			continue
+
+		// only the column is given, use the first row it fits in
+		case cell.Column >= 0:
+			for cell.Row = 0; !fits(cell); cell.Row++ {
+			}
+
+		// only the row is given, use the first column it fits in
+		// or the first column if it doesn't fit anywhere
+		case cell.Row >= 0:
+			for cell.Column = 0; cell.Column+cell.ColumnSpan <= columns; cell.Column++ {
+				if fits(cell) {
+					break
+				}
+			}
+			if !fits(cell) {
+				cell.Column = 0
+			}
+
+		// continue after the last automatically placed item
+		default:
+			for ; ; cursor++ {
+				cell.Column = cursor % columns
+				cell.Row = cursor / columns
+				if fits(cell) {
+					break
+				}
+			}
+		}
+
+		mark(i, cell)
+	}
+
+	if rows < grid.Rows {
+		rows = grid.Rows
+	}
+	if rows < 1 {
+		rows = 1
+	}
+
+	return
 }
 
 /*
@@ -363,6 +978,26 @@ type Label struct {
 	Color    uint32
 	BGcolor  uint32
 	Bold     bool
+
+	// a comma separated list of font families or files,
+	// the first one having a glyph for a character draws it
+	Font   string
+	Weight int
+	Italic bool
+
+	// styled parts of the text. If there are any, labels draw
+	// them instead of Text. Texts always draw the plain Text
+	Spans []Span
+}
+
+// Span is a part of a label's text with its own style
+type Span struct {
+	Text     string
+	Textsize int
+	Color    uint32
+	Font     string
+	Weight   int
+	Italic   bool
 }
 
 // Draw draws the item onto the parent surface
@@ -373,83 +1008,428 @@ func (label *Label) Draw(surf *sdl.Surface) (err error) {
 	// is sdl compatible (no bytes flipped)
 	surf.FillRect(nil, image.UInt32ToColor(label.BGcolor).Uint32())
 
-	var font *ttf.Font
-
-	// load font
-	if label.Bold {
-		font, err = ttf.OpenFont("/usr/share/fonts/TTF/DejaVuSans-Bold.ttf", label.Textsize)
-	} else {
-		font, err = ttf.OpenFont("/usr/share/fonts/TTF/DejaVuSans.ttf", label.Textsize)
-	}
-	if err != nil {
-		return err
-	}
-	defer font.Close()
-
 	// if text is not empty
-	if label.Text != "" {
+	if label.Text != "" || len(label.Spans) > 0 {
 
-		// Render text to surface
-		textSurface, err := font.RenderUTF8Shaded(label.Text, image.UInt32ToColor(label.Color), image.UInt32ToColor(label.BGcolor))
+		// Render text to surface, blended if the background is transparent
+		textSurface, err := label.render()
 		if err != nil {
 			return err
 		}
 		defer textSurface.Free()
 
-		// Calculate vertical and horizontal position on surface
-		var coordinateX int32
-		var coordinateY int32
+		// Calculate vertical and horizontal position in the content area
+		content := label.Style.Content(label.Size)
+		coordinateX := content.X
+		coordinateY := content.Y
 
 		switch label.Halign {
-		case LEFT:
-			coordinateX = 0
 		case CENTER:
-			coordinateX = (int32(label.Size.X) - textSurface.W) / 2
+			coordinateX += (content.W - textSurface.W) / 2
 		case RIGHT:
-			coordinateX = int32(label.Size.X) - textSurface.W
+			coordinateX += content.W - textSurface.W
 		}
 
 		switch label.Valign {
-		case TOP:
-			coordinateY = 0
 		case CENTER:
-			coordinateY = (int32(label.Size.Y) - textSurface.H) / 2
+			coordinateY += (content.H - textSurface.H) / 2
 		case BOTTOM:
-			coordinateY = int32(label.Size.Y) - textSurface.H
+			coordinateY += content.H - textSurface.H
 		}
 
-		dstRect := sdl.Rect{X: coordinateX, Y: coordinateY, W: coordinateX + textSurface.W, H: coordinateY + textSurface.H}
+		dstRect := sdl.Rect{X: coordinateX, Y: coordinateY, W: textSurface.W, H: textSurface.H}
 
-		// Draw onto final surface (Text aligned)
+		// Draw onto final surface (Text aligned),
+		// the text doesn't cover the padding and border
+		surf.SetClipRect(&content)
 		textSurface.Blit(&sdl.Rect{X: 0, Y: 0, W: textSurface.W, H: textSurface.H}, surf, &dstRect)
+		surf.SetClipRect(nil)
 
 	}
 
 	return
 }
 
+// renders the text or the spans of the label
+func (label *Label) render() (surface *sdl.Surface, err error) {
+	bg := image.UInt32ToColor(label.BGcolor)
+
+	if len(label.Spans) == 0 {
+		fonts, err := font.OpenChain(label.Font, label.FontWeight(), label.Italic, label.Textsize)
+		if err != nil {
+			return nil, err
+		}
+
+		return fonts.Render(label.Text, image.UInt32ToColor(label.Color), bg)
+	}
+
+	// render each span in its own style and put them on one baseline
+	surfaces := make([]*sdl.Surface, 0, len(label.Spans))
+	ascents := make([]int32, 0, len(label.Spans))
+	defer func() {
+		for _, s := range surfaces {
+			s.Free()
+		}
+	}()
+
+	for _, span := range label.Spans {
+		if span.Text == "" {
+			continue
+		}
+
+		fonts, err := font.OpenChain(span.Font, span.Weight, span.Italic, span.Textsize)
+		if err != nil {
+			return nil, err
+		}
+
+		s, err := fonts.Render(span.Text, image.UInt32ToColor(span.Color), bg)
+		if err != nil {
+			return nil, err
+		}
+		surfaces = append(surfaces, s)
+		ascents = append(ascents, int32(fonts[0].Ascent()))
+	}
+
+	return font.JoinBaseline(surfaces, ascents, bg)
+}
+
+// FontWeight gets the font weight, bold overriding lighter weights
+func (label *Label) FontWeight() int {
+	weight := label.Weight
+	if weight == 0 {
+		weight = font.Normal
+	}
+	if label.Bold && weight < font.Bold {
+		weight = font.Bold
+	}
+
+	return weight
+}
+
+/*
+########################
+# Subsection: Text
+########################
+*/
+
+// Text is an item that holds text spanning multiple lines.
+// The text is wrapped at the width of the item
+type Text struct {
+	Label
+
+	// the maximum count of lines drawn, 0 for no limit
+	Maxlines int
+
+	// the distance of lines as a multiple of the font's line height, 0 for 1
+	LineHeight float64
+
+	// stretches lines to the full width of the item
+	Justify bool
+
+	// set if the height is the height of the lines, so
+	// it is measured again when a box changes the width
+	AutoHeight bool
+}
+
+// Draw draws the item onto the parent surface
+func (text *Text) Draw(surf *sdl.Surface) (err error) {
+	surf.FillRect(nil, image.UInt32ToColor(text.BGcolor).Uint32())
+
+	fonts, err := font.OpenChain(text.Font, text.FontWeight(), text.Italic, text.Textsize)
+	if err != nil {
+		return err
+	}
+
+	content := text.Style.Content(text.Size)
+	lines := fonts.Layout(text.Text, int(content.W), text.Maxlines)
+	lineskip := text.lineSkip(fonts)
+
+	coordinateY := content.Y
+	switch text.Valign {
+	case CENTER:
+		coordinateY += (content.H - lineskip*int32(len(lines))) / 2
+	case BOTTOM:
+		coordinateY += content.H - lineskip*int32(len(lines))
+	}
+
+	// lines outside of the content area are not drawn
+	surf.SetClipRect(&content)
+	defer surf.SetClipRect(nil)
+
+	for _, line := range lines {
+		if text.Justify && !line.End {
+			err = text.drawJustified(surf, fonts, content, line.Text, coordinateY)
+		} else {
+			err = text.drawLine(surf, fonts, content, line.Text, coordinateY)
+		}
+		if err != nil {
+			return err
+		}
+
+		coordinateY += lineskip
+	}
+
+	return
+}
+
+// draws a line aligned by Halign in the content area
+func (text *Text) drawLine(surf *sdl.Surface, fonts font.Chain, content sdl.Rect, line string, y int32) (err error) {
+	if line == "" {
+		return
+	}
+
+	lineSurface, err := fonts.Render(line, image.UInt32ToColor(text.Color), image.UInt32ToColor(text.BGcolor))
+	if err != nil {
+		return
+	}
+	defer lineSurface.Free()
+
+	coordinateX := content.X
+	switch text.Halign {
+	case CENTER:
+		coordinateX += (content.W - lineSurface.W) / 2
+	case RIGHT:
+		coordinateX += content.W - lineSurface.W
+	}
+
+	lineSurface.Blit(&sdl.Rect{X: 0, Y: 0, W: lineSurface.W, H: lineSurface.H}, surf,
+		&sdl.Rect{X: coordinateX, Y: y, W: lineSurface.W, H: lineSurface.H})
+	return
+}
+
+// draws a line with the space between words stretched to fill the content area
+func (text *Text) drawJustified(surf *sdl.Surface, fonts font.Chain, content sdl.Rect, line string, y int32) (err error) {
+	words := strings.Fields(line)
+	if len(words) < 2 {
+		return text.drawLine(surf, fonts, content, line, y)
+	}
+
+	surfaces := make([]*sdl.Surface, 0, len(words))
+	defer func() {
+		for _, s := range surfaces {
+			s.Free()
+		}
+	}()
+
+	var width int32
+	for _, word := range words {
+		wordSurface, err := fonts.Render(word, image.UInt32ToColor(text.Color), image.UInt32ToColor(text.BGcolor))
+		if err != nil {
+			return err
+		}
+		surfaces = append(surfaces, wordSurface)
+		width += wordSurface.W
+	}
+
+	// spread the remaining space over the gaps, the first gaps get the rest
+	gaps := int32(len(words) - 1)
+	space := content.W - width
+
+	coordinateX := content.X
+	for i, wordSurface := range surfaces {
+		wordSurface.Blit(&sdl.Rect{X: 0, Y: 0, W: wordSurface.W, H: wordSurface.H}, surf,
+			&sdl.Rect{X: coordinateX, Y: y, W: wordSurface.W, H: wordSurface.H})
+
+		coordinateX += wordSurface.W + space/gaps
+		if int32(i) < space%gaps {
+			coordinateX++
+		}
+	}
+
+	return
+}
+
+// gets the distance of two lines in pixels
+func (text *Text) lineSkip(fonts font.Chain) int32 {
+	lineheight := text.LineHeight
+	if lineheight == 0 {
+		lineheight = 1
+	}
+
+	return int32(math.Round(float64(fonts[0].LineSkip()) * lineheight))
+}
+
+// Measure gets the height the text needs at the width of the item,
+// including its margin, border and padding
+func (text *Text) Measure() (height int32, err error) {
+	return text.measureAt(text.Size.X)
+}
+
+// gets the height of the text at a width if its height is
+// the height of its lines
+func (text *Text) heightAt(width int32) (height int32, ok bool) {
+	if !text.AutoHeight {
+		return 0, false
+	}
+
+	height, err := text.measureAt(width)
+	return height, err == nil
+}
+
+// gets the height the text needs at a width
+func (text *Text) measureAt(width int32) (height int32, err error) {
+	fonts, err := font.OpenChain(text.Font, text.FontWeight(), text.Italic, text.Textsize)
+	if err != nil {
+		return
+	}
+
+	content := text.Style.Content(Vector{X: width, Y: text.Size.Y})
+	lines := fonts.Layout(text.Text, int(content.W), text.Maxlines)
+
+	// everything around the content area
+	style := text.Style
+	around := style.Margin.Top + style.Margin.Bottom + 2*style.Border + style.Padding.Top + style.Padding.Bottom
+
+	return text.lineSkip(fonts)*int32(len(lines)) + around, nil
+}
+
 /*
 ########################
 # Subsection: Texture
 ########################
 */
 
+// Fit is how a texture is scaled to the size of its item
+type Fit int
+
+// the ways a texture can be scaled, like the object-fit of css
+const (
+	// the texture keeps its size
+	FitNone Fit = iota
+
+	// the texture is scaled to be as big as possible while being
+	// completely visible, keeping its aspect ratio
+	FitContain
+
+	// the texture is scaled to cover the whole item,
+	// keeping its aspect ratio. Parts of it are cut off
+	FitCover
+
+	// the texture is stretched to the size of the item
+	FitFill
+
+	// like FitContain, but the texture is only made smaller
+	FitScaleDown
+)
+
+// Size gets the size a texture of size size has when fitted into area
+func (fit Fit) Size(size Vector, area Vector) Vector {
+	if size.X <= 0 || size.Y <= 0 {
+		return size
+	}
+
+	scalex := float64(area.X) / float64(size.X)
+	scaley := float64(area.Y) / float64(size.Y)
+
+	var scale float64
+	switch fit {
+	case FitFill:
+		return area
+	case FitContain:
+		scale = math.Min(scalex, scaley)
+	case FitCover:
+		scale = math.Max(scalex, scaley)
+	case FitScaleDown:
+		scale = math.Min(1, math.Min(scalex, scaley))
+	default:
+		return size
+	}
+
+	return Vector{X: int32(math.Round(float64(size.X) * scale)), Y: int32(math.Round(float64(size.Y) * scale))}
+}
+
 // Texture is an item containing a texture/picture
 type Texture struct {
 	ItemBase
 
 	Texture *sdl.Surface
+
+	// how the texture is scaled and where it is put inside of the item
+	Fit    Fit
+	Valign Align
+	Halign Align
+
+	// the widths of the edges of a nine-patch texture. If any is set,
+	// the corners keep their size, the edges are stretched along the item
+	// and the middle fills the rest. Fit and alignment are not used then
+	Slice Edges
 }
 
-// Draw the item onto the parent surface
+// Draw the item onto the parent surface, inside of its border and padding
 func (tex *Texture) Draw(surf *sdl.Surface) (err error) {
-	srcRect := sdl.Rect{X: 0, Y: 0, W: tex.Size.X, H: tex.Size.Y}
-	dstRect := sdl.Rect{X: 0, Y: 0, W: tex.Size.X, H: tex.Size.Y}
-	tex.Texture.Blit(&srcRect, surf, &dstRect)
+	content := tex.Style.Content(tex.Size)
+	full := sdl.Rect{X: 0, Y: 0, W: tex.Texture.W, H: tex.Texture.H}
+
+	// the parts of the texture outside of the content area are not drawn
+	surf.SetClipRect(&content)
+	defer surf.SetClipRect(nil)
+
+	if tex.Slice != (Edges{}) {
+		tex.drawNinePatch(surf, content)
+		return nil
+	}
+
+	size := tex.Fit.Size(Vector{X: full.W, Y: full.H}, Vector{X: content.W, Y: content.H})
+	dstRect := sdl.Rect{X: content.X, Y: content.Y, W: size.X, H: size.Y}
+
+	switch tex.Halign {
+	case CENTER:
+		dstRect.X += (content.W - size.X) / 2
+	case RIGHT:
+		dstRect.X += content.W - size.X
+	}
+
+	switch tex.Valign {
+	case CENTER:
+		dstRect.Y += (content.H - size.Y) / 2
+	case BOTTOM:
+		dstRect.Y += content.H - size.Y
+	}
+
+	if size.X != full.W || size.Y != full.H {
+		image.ScaleRect(tex.Texture, full, surf, dstRect)
+		return nil
+	}
+
+	// the surface is transparent, so the texture is copied
+	// with its alpha instead of being blended onto it
+	mode, _ := tex.Texture.GetBlendMode()
+	tex.Texture.SetBlendMode(sdl.BLENDMODE_NONE)
+	tex.Texture.Blit(&full, surf, &dstRect)
+	tex.Texture.SetBlendMode(mode)
 
 	return nil
 }
 
+// draws the texture as nine-patch stretched over the content area
+func (tex *Texture) drawNinePatch(surf *sdl.Surface, content sdl.Rect) {
+	slice := tex.Slice
+	srcx := []int32{0, slice.Left, tex.Texture.W - slice.Right, tex.Texture.W}
+	srcy := []int32{0, slice.Top, tex.Texture.H - slice.Bottom, tex.Texture.H}
+
+	// the edges shrink if the item is too small for them
+	shrink := func(start int32, end int32, length int32) (int32, int32) {
+		if start+end <= length {
+			return start, end
+		}
+		start = int32(math.Round(float64(start) * float64(length) / float64(start+end)))
+		return start, length - start
+	}
+	left, right := shrink(slice.Left, slice.Right, content.W)
+	top, bottom := shrink(slice.Top, slice.Bottom, content.H)
+
+	dstx := []int32{content.X, content.X + left, content.X + content.W - right, content.X + content.W}
+	dsty := []int32{content.Y, content.Y + top, content.Y + content.H - bottom, content.Y + content.H}
+
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			src := sdl.Rect{X: srcx[col], Y: srcy[row], W: srcx[col+1] - srcx[col], H: srcy[row+1] - srcy[row]}
+			dst := sdl.Rect{X: dstx[col], Y: dsty[row], W: dstx[col+1] - dstx[col], H: dsty[row+1] - dsty[row]}
+			image.ScaleRect(tex.Texture, src, surf, dst)
+		}
+	}
+}
+
 /*
 ########################
 # Subsection: Unicolor
@@ -463,8 +1443,11 @@ type Unicolor struct {
 	Color uint32
 }
 
-// Draw the item onto the parent surface
+// Draw the item onto the parent surface.
+// The color fills the padding too, the border is drawn over it
 func (unic *Unicolor) Draw(surf *sdl.Surface) (err error) {
-	rect := sdl.Rect{X: 0, Y: 0, W: unic.Size.X, H: unic.Size.Y}
-	return surf.FillRect(&rect, unic.Color)
+	rect := sdl.Rect{X: 0, Y: 0, W: surf.W, H: surf.H}
+
+	// flip bytes for sdl
+	return surf.FillRect(&rect, image.UInt32ToColor(unic.Color).Uint32())
 }
@@ -1 +1,372 @@
 package data
+
+import (
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+func newTestBox(justify Justify, align Align) *Box {
+	return &Box{
+		ContainerBase: ContainerBase{
+			ItemBase: ItemBase{UID: 0, Size: Vector{X: 100, Y: 20}, Style: Style{Padding: Edges{2, 2, 2, 2}}},
+			Items: []Item{
+				&Unicolor{ItemBase: ItemBase{UID: 1, Size: Vector{X: 10, Y: 10}}},
+				&Unicolor{ItemBase: ItemBase{UID: 2, Size: Vector{X: 20, Y: 10}}},
+			},
+		},
+		Spacing: 4,
+		Align:   align,
+		Justify: justify,
+	}
+}
+
+func TestBoxLayout(t *testing.T) {
+	tests := []struct {
+		justify  Justify
+		expected []Vector
+	}{
+		{JustifyStart, []Vector{{X: 2, Y: 2}, {X: 16, Y: 2}}},
+		{JustifyEnd, []Vector{{X: 64, Y: 2}, {X: 78, Y: 2}}},
+		{JustifyCenter, []Vector{{X: 33, Y: 2}, {X: 47, Y: 2}}},
+		{JustifySpaceBetween, []Vector{{X: 2, Y: 2}, {X: 78, Y: 2}}},
+	}
+
+	for _, test := range tests {
+		positions := newTestBox(test.justify, LEFT).Layout()
+		if positions[0] != test.expected[0] || positions[1] != test.expected[1] {
+			t.Error("Expected ", test.expected, ", gave ", positions)
+		}
+	}
+
+	// growing items fill the box, stretched items fill the height
+	box := newTestBox(JustifyStart, STRETCH)
+	box.Items[0].(*Unicolor).Flex = Flex{Grow: 1}
+	box.Items[1].(*Unicolor).Flex = Flex{Grow: 3}
+	box.Layout()
+
+	expected := []Vector{{X: 26, Y: 16}, {X: 66, Y: 16}}
+	for i, item := range box.Items {
+		if item.GetSize() != expected[i] {
+			t.Error("Expected ", expected[i], ", gave ", item.GetSize())
+		}
+	}
+
+	// the layout doesn't change when done again
+	positions := box.Layout()
+	if positions[1] != (Vector{X: 32, Y: 2}) || box.Items[1].GetSize() != expected[1] {
+		t.Error("Expected the same layout, gave ", positions, box.Items[1].GetSize())
+	}
+
+	// items that don't fit shrink by their size
+	box = newTestBox(JustifyStart, CENTER)
+	box.Vertical = true
+	box.Items[0].(*Unicolor).Flex = Flex{Shrink: 1, Basis: Vector{Y: 16}}
+	box.Items[1].(*Unicolor).Flex = Flex{Shrink: 1}
+	box.Layout()
+
+	if size := box.Items[0].GetSize(); size != (Vector{X: 10, Y: 7}) {
+		t.Error("Expected {10 7}, gave ", size)
+	}
+	if item := box.GetItemAt(Vector{X: 50, Y: 15}); item != box.Items[1] {
+		t.Error("Expected the second item, gave ", item)
+	}
+}
+
+// an item as high as a text of 200 square pixels wrapped at its width
+type wrappingItem struct {
+	Unicolor
+}
+
+func (item *wrappingItem) heightAt(width int32) (int32, bool) {
+	return 200 / width, width > 0
+}
+
+func TestBoxLayoutMeasured(t *testing.T) {
+	// the height is measured at the width the item grows to
+	box := newTestBox(JustifyStart, LEFT)
+	box.Items[0] = &wrappingItem{Unicolor{ItemBase: ItemBase{UID: 1, Size: Vector{X: 10, Y: 20}, Flex: Flex{Grow: 1}}}}
+	box.Layout()
+
+	if size := box.Items[0].GetSize(); size != (Vector{X: 72, Y: 2}) {
+		t.Error("Expected {72 2}, gave ", size)
+	}
+
+	// in a vbox the height is measured at the stretched width
+	box = newTestBox(JustifyStart, STRETCH)
+	box.Vertical = true
+	box.Size.Y = 100
+	box.Items[0] = &wrappingItem{Unicolor{ItemBase: ItemBase{UID: 1, Size: Vector{X: 10, Y: 20}}}}
+	box.Layout()
+
+	if size := box.Items[0].GetSize(); size != (Vector{X: 96, Y: 2}) {
+		t.Error("Expected {96 2}, gave ", size)
+	}
+}
+
+func TestGridLayout(t *testing.T) {
+	cells := []GridCell{
+		{ColumnSpan: 2},
+		{},
+		{Column: 2, Row: 2},
+		{},
+		{RowSpan: 2},
+	}
+
+	grid := &Grid{
+		ContainerBase: ContainerBase{ItemBase: ItemBase{Size: Vector{X: 100, Y: 100}}},
+		Columns:       3,
+		ColumnGap:     5,
+		RowGap:        5,
+	}
+	for i, cell := range cells {
+		grid.AddItem(&Unicolor{ItemBase: ItemBase{UID: uint(i + 1), GridCell: cell}})
+	}
+
+	positions := grid.Layout()
+
+	expected := []struct {
+		position Vector
+		size     Vector
+	}{
+		{Vector{X: 0, Y: 0}, Vector{X: 65, Y: 30}},
+		{Vector{X: 70, Y: 0}, Vector{X: 30, Y: 30}},
+		{Vector{X: 35, Y: 35}, Vector{X: 30, Y: 30}},
+		{Vector{X: 0, Y: 35}, Vector{X: 30, Y: 30}},
+		{Vector{X: 70, Y: 35}, Vector{X: 30, Y: 65}},
+	}
+	for i, item := range grid.Items {
+		if positions[i] != expected[i].position || item.GetSize() != expected[i].size {
+			t.Error("Expected ", expected[i], ", gave ", positions[i], item.GetSize())
+		}
+	}
+
+	if item := grid.GetItemAt(Vector{X: 80, Y: 90}); item != grid.Items[4] {
+		t.Error("Expected the last item, gave ", item)
+	}
+	if item := grid.GetItemAt(Vector{X: 67, Y: 10}); item != grid {
+		t.Error("Expected the grid itself in a gap, gave ", item)
+	}
+}
+
+func TestScroll(t *testing.T) {
+	list := &ListContainer{
+		ContainerBase: ContainerBase{
+			ItemBase: ItemBase{UID: 10, Position: Vector{X: 0, Y: 20}, Size: Vector{X: 100, Y: 50}},
+			Scroll:   ScrollVertical,
+		},
+	}
+	for i := 0; i < 3; i++ {
+		list.AddItem(&Unicolor{ItemBase: ItemBase{UID: uint(11 + i), Size: Vector{X: 100, Y: 30}}})
+	}
+	root := &BaseContainer{
+		ContainerBase: ContainerBase{
+			ItemBase: ItemBase{UID: 9, Size: Vector{X: 100, Y: 100}},
+			Items:    []Item{list},
+		},
+	}
+
+	scrollable, ok := ScrollableAt(root, Vector{X: 10, Y: 30})
+	if !ok || scrollable != list {
+		t.Fatal("Expected the list to be scrollable, gave ", scrollable)
+	}
+
+	// scrolling stops at the end of the content
+	if !Scroll(list, Vector{X: 20, Y: 100}) {
+		t.Error("Expected the list to scroll")
+	}
+	if offset := ScrollOffset(list); offset != (Vector{X: 0, Y: 40}) {
+		t.Error("Expected {0 40}, gave ", offset)
+	}
+	if Scroll(list, Vector{Y: 10}) {
+		t.Error("Expected the list not to scroll past its end")
+	}
+
+	// hit testing uses the scrolled positions
+	if item := list.GetItemAt(Vector{X: 10, Y: 5}); item != list.Items[1] {
+		t.Error("Expected the second item, gave ", item)
+	}
+	if position := ItemPosition(list, list.Items[2]); position != (Vector{X: 0, Y: 20}) {
+		t.Error("Expected {0 20}, gave ", position)
+	}
+
+	// the offset is kept for the container with the same uid
+	again := *list
+	if offset := ScrollOffset(&again); offset.Y != 40 {
+		t.Error("Expected the offset to be kept, gave ", offset)
+	}
+}
+
+func TestStyle(t *testing.T) {
+	style := Style{
+		Margin:  Edges{Top: 2, Right: 2, Bottom: 2, Left: 2},
+		Border:  1,
+		Padding: Edges{Top: 3, Right: 4, Bottom: 3, Left: 4},
+		Radius:  10,
+	}
+	size := Vector{X: 50, Y: 40}
+
+	if content := style.Content(size); content != (sdl.Rect{X: 5, Y: 4, W: 36, H: 28}) {
+		t.Error("Expected {5 4 36 28}, gave ", content)
+	}
+
+	// the corners are cut off
+	if style.contains(size, Vector{X: 0, Y: 0}) || !style.contains(size, Vector{X: 5, Y: 5}) {
+		t.Error("Expected only the inside of the corner to be part of the item")
+	}
+
+	cont := &BaseContainer{
+		ContainerBase: ContainerBase{
+			ItemBase: ItemBase{Size: Vector{X: 100, Y: 100}, Style: Style{Padding: Edges{Top: 10, Left: 10}}},
+			Items: []Item{
+				&Unicolor{ItemBase: ItemBase{UID: 1, Position: Vector{X: 10, Y: 10}, Size: size, Style: style}},
+			},
+		},
+	}
+
+	// items are placed in the content area, the margin is not part of them
+	if position := ItemPosition(cont, cont.Items[0]); position != (Vector{X: 22, Y: 22}) {
+		t.Error("Expected {22 22}, gave ", position)
+	}
+	if item := cont.GetItemAt(Vector{X: 21, Y: 40}); item != cont {
+		t.Error("Expected the container in the margin, gave ", item)
+	}
+	if item := cont.GetItemAt(Vector{X: 23, Y: 23}); item != cont {
+		t.Error("Expected the container outside of the corner, gave ", item)
+	}
+	if item := cont.GetItemAt(Vector{X: 22, Y: 40}); item != cont.Items[0] {
+		t.Error("Expected the item, gave ", item)
+	}
+
+	// the edges of the corners are mixed
+	if c := coverage(0, 0, 20, 20, 0, 10); c != 0 {
+		t.Error("Expected no coverage in the corner, gave ", c)
+	}
+	if c := coverage(3, 2, 20, 20, 0, 10); c <= 0 || c >= 1 {
+		t.Error("Expected a partly covered pixel, gave ", c)
+	}
+}
+
+func TestAddColors(t *testing.T) {
+	red := sdl.Color{R: 255, A: 255}
+	blue := sdl.Color{B: 255, A: 255}
+
+	// half of the pixel is covered, the rest is transparent
+	if c := addColors(red, 0.5, blue, 0); c != (sdl.Color{R: 255, A: 128}) {
+		t.Error("Expected {255 0 0 128}, gave ", c)
+	}
+	if c := addColors(red, 0.5, blue, 0.5); c != (sdl.Color{R: 128, B: 128, A: 255}) {
+		t.Error("Expected {128 0 128 255}, gave ", c)
+	}
+
+	// transparent colors don't add to the color of the pixel
+	if c := addColors(sdl.Color{G: 255}, 0.5, blue, 0.5); c != (sdl.Color{B: 255, A: 128}) {
+		t.Error("Expected {0 0 255 128}, gave ", c)
+	}
+
+	if alpha := (Style{Transparency: 0.25}).alpha(); alpha != 191 {
+		t.Error("Expected 191, gave ", alpha)
+	}
+}
+
+func TestShapes(t *testing.T) {
+	triangle := &Polygon{Points: []Vector{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 0, Y: 10}}}
+	if !triangle.contains(2, 2) || triangle.contains(8, 8) {
+		t.Error("Expected only points inside of the triangle to be in it")
+	}
+
+	if d := segmentDistance(5, 3, Vector{X: 0, Y: 0}, Vector{X: 10, Y: 0}); d != 3 {
+		t.Error("Expected 3, gave ", d)
+	}
+	if d := segmentDistance(13, 4, Vector{X: 0, Y: 0}, Vector{X: 10, Y: 0}); d != 5 {
+		t.Error("Expected the distance to the end of 5, gave ", d)
+	}
+
+	// 0xFF0000FF is red, 0xFFFF0000 is blue
+	grad := &Gradient{Stops: []Stop{{Offset: 0.25, Color: 0xFF0000FF}, {Offset: 0.75, Color: 0xFFFF0000}}}
+	tests := []struct {
+		t        float64
+		expected sdl.Color
+	}{
+		{0, sdl.Color{R: 255, A: 255}},
+		{0.5, sdl.Color{R: 128, B: 128, A: 255}},
+		{1, sdl.Color{B: 255, A: 255}},
+	}
+	for _, test := range tests {
+		if c := grad.colorAt(test.t); c != test.expected {
+			t.Error("Expected ", test.expected, " at ", test.t, ", gave ", c)
+		}
+	}
+}
+
+func TestMeter(t *testing.T) {
+	meter := Meter{Value: 30, Min: 20, Max: 60, Color: 1, Thresholds: []Threshold{{Below: 25, Color: 2}, {Below: 35, Color: 3}}}
+	if f := meter.Fraction(); f != 0.25 {
+		t.Error("Expected 0.25, gave ", f)
+	}
+	if c := meter.FillColor(); c != 3 {
+		t.Error("Expected the color of the second threshold, gave ", c)
+	}
+
+	meter.Value = 80
+	if f, c := meter.Fraction(), meter.FillColor(); f != 1 || c != 1 {
+		t.Error("Expected a full meter with its own color, gave ", f, c)
+	}
+
+	// a gauge going from the bottom clockwise over half a circle
+	gauge := &Gauge{Start: 90, Sweep: 180}
+	if f := gauge.angleFraction(-1, 0); f != 0.5 {
+		t.Error("Expected 0.5 on the left, gave ", f)
+	}
+	if f := gauge.angleFraction(1, 0.01); f <= 1 {
+		t.Error("Expected the right to be outside of the arc, gave ", f)
+	}
+}
+
+func TestFit(t *testing.T) {
+	size, area := Vector{X: 40, Y: 20}, Vector{X: 20, Y: 20}
+	tests := map[Fit]Vector{
+		FitNone:      {X: 40, Y: 20},
+		FitContain:   {X: 20, Y: 10},
+		FitCover:     {X: 40, Y: 20},
+		FitFill:      {X: 20, Y: 20},
+		FitScaleDown: {X: 20, Y: 10},
+	}
+
+	for fit, expected := range tests {
+		if result := fit.Size(size, area); result != expected {
+			t.Error("Expected ", expected, " for ", fit, ", gave ", result)
+		}
+	}
+
+	// scale-down doesn't make textures bigger
+	if result := FitScaleDown.Size(Vector{X: 5, Y: 5}, area); result != (Vector{X: 5, Y: 5}) {
+		t.Error("Expected {5 5}, gave ", result)
+	}
+}
+
+func TestEntry(t *testing.T) {
+	entry := &Entry{ItemBase: ItemBase{UID: 2, Position: Vector{X: 10, Y: 20}, Size: Vector{X: 50, Y: 20}}, Text: "pässword"}
+	root := &BaseContainer{
+		ContainerBase: ContainerBase{
+			ItemBase: ItemBase{UID: 1, Size: Vector{X: 100, Y: 100}},
+			Items:    []Item{entry},
+		},
+	}
+
+	// the position is relative to the entry clicked on
+	if item, position := ItemAt(root, Vector{X: 15, Y: 30}); item != entry || position != (Vector{X: 5, Y: 10}) {
+		t.Error("Expected the entry at {5 10}, gave ", item, " at ", position)
+	}
+	if item, _ := ItemAt(root, Vector{X: 5, Y: 5}); item != root {
+		t.Error("Expected the root container, gave ", item)
+	}
+
+	if shown := entry.Shown(); shown != "pässword" {
+		t.Error("Expected pässword, gave ", shown)
+	}
+	entry.Password = true
+	if shown := entry.Shown(); shown != "••••••••" {
+		t.Error("Expected 8 bullets, gave ", shown)
+	}
+}
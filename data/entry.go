@@ -0,0 +1,179 @@
+package data
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/phoenixdevelops/fliw/font"
+	"github.com/phoenixdevelops/fliw/image"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/*
+##############################################################
+# Section: Entry
+##############################################################
+*/
+
+// the character shown instead of each character of a password
+const passwordMask = "•"
+
+// Entry is an item text can be typed into. It shows a single line,
+// scrolled so the cursor is always visible
+type Entry struct {
+	ItemBase
+
+	Text     string
+	Textsize int
+	Color    uint32
+	BGcolor  uint32
+	Font     string
+	Weight   int
+	Italic   bool
+
+	// the text shown in PlaceholderColor while the entry is empty
+	Placeholder      string
+	PlaceholderColor uint32
+
+	// shows a bullet for every character of the text
+	Password bool
+
+	// the cursor and the selection are only drawn while the entry is focused.
+	// They are indices of runes, the selection goes from SelStart to SelEnd
+	Focused        bool
+	Cursor         int
+	SelStart       int
+	SelEnd         int
+	SelectionColor uint32
+}
+
+// Draw draws the item onto the parent surface
+func (entry *Entry) Draw(surf *sdl.Surface) (err error) {
+	// flip bytes for sdl
+	surf.FillRect(nil, image.UInt32ToColor(entry.BGcolor).Uint32())
+
+	fonts, err := font.OpenChain(entry.Font, entry.fontWeight(), entry.Italic, entry.Textsize)
+	if err != nil {
+		return
+	}
+
+	content := entry.Style.Content(entry.Size)
+	surf.SetClipRect(&content)
+	defer surf.SetClipRect(nil)
+
+	text, color := entry.Shown(), entry.Color
+	if entry.Text == "" {
+		text, color = entry.Placeholder, entry.PlaceholderColor
+	}
+
+	height := int32(fonts[0].Height())
+	x := content.X - entry.scroll(fonts, content.W)
+	y := content.Y + (content.H-height)/2
+
+	if entry.Focused && entry.SelStart != entry.SelEnd {
+		start := x + int32(fonts.Width(entry.shownPrefix(entry.SelStart)))
+		end := x + int32(fonts.Width(entry.shownPrefix(entry.SelEnd)))
+
+		selection := sdl.Rect{X: start, Y: y, W: end - start, H: height}
+		surf.FillRect(&selection, image.UInt32ToColor(entry.SelectionColor).Uint32())
+	}
+
+	if text != "" {
+		// blended, so the selection shows through
+		textSurface, err := fonts.RenderBlended(text, image.UInt32ToColor(color))
+		if err != nil {
+			return err
+		}
+		defer textSurface.Free()
+
+		dstRect := sdl.Rect{X: x, Y: y, W: textSurface.W, H: textSurface.H}
+		textSurface.Blit(&sdl.Rect{X: 0, Y: 0, W: textSurface.W, H: textSurface.H}, surf, &dstRect)
+	}
+
+	if entry.Focused {
+		cursor := sdl.Rect{X: x + int32(fonts.Width(entry.shownPrefix(entry.Cursor))), Y: y, W: entry.cursorWidth(), H: height}
+		surf.FillRect(&cursor, image.UInt32ToColor(entry.Color).Uint32())
+	}
+
+	return nil
+}
+
+// IndexAt gets the index of the rune the cursor is put in front of
+// when the entry is clicked at x, relative to the item
+func (entry *Entry) IndexAt(x int32) (index int, err error) {
+	fonts, err := font.OpenChain(entry.Font, entry.fontWeight(), entry.Italic, entry.Textsize)
+	if err != nil {
+		return
+	}
+
+	content := entry.Style.Content(entry.Size)
+	x += entry.scroll(fonts, content.W) - content.X
+
+	// the boundary between runes closest to x
+	count := utf8.RuneCountInString(entry.Text)
+	previous := int32(0)
+	for i := 1; i <= count; i++ {
+		width := int32(fonts.Width(entry.shownPrefix(i)))
+		if x < width {
+			if x-previous < width-x {
+				return i - 1, nil
+			}
+			return i, nil
+		}
+		previous = width
+	}
+
+	return count, nil
+}
+
+// Shown gets the text as it is drawn, masked if it is a password
+func (entry *Entry) Shown() string {
+	if entry.Password {
+		return strings.Repeat(passwordMask, utf8.RuneCountInString(entry.Text))
+	}
+
+	return entry.Text
+}
+
+// gets the shown text in front of the rune at index
+func (entry *Entry) shownPrefix(index int) string {
+	runes := []rune(entry.Shown())
+	if index > len(runes) {
+		index = len(runes)
+	}
+	if index < 0 {
+		index = 0
+	}
+
+	return string(runes[:index])
+}
+
+// gets how far the text is moved to the left, so the cursor stays visible
+func (entry *Entry) scroll(fonts font.Chain, width int32) int32 {
+	if !entry.Focused {
+		return 0
+	}
+
+	cursor := int32(fonts.Width(entry.shownPrefix(entry.Cursor))) + entry.cursorWidth()
+	if cursor <= width {
+		return 0
+	}
+
+	return cursor - width
+}
+
+// the cursor gets thicker with the text
+func (entry *Entry) cursorWidth() int32 {
+	if entry.Textsize < 24 {
+		return 1
+	}
+	return int32(entry.Textsize / 24)
+}
+
+// gets the font weight, normal if there is none
+func (entry *Entry) fontWeight() int {
+	if entry.Weight == 0 {
+		return font.Normal
+	}
+	return entry.Weight
+}
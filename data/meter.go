@@ -0,0 +1,229 @@
+package data
+
+import (
+	"math"
+
+	"github.com/phoenixdevelops/fliw/image"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/*
+##############################################################
+# Section: Meters
+##############################################################
+*/
+
+// Meter is a value inside of a range, shown by progress bars,
+// gauges and level bars
+type Meter struct {
+	Value float64
+	Min   float64
+	Max   float64
+
+	// the colors of the filled and the empty part
+	Color uint32
+	Track uint32
+
+	// colors replacing Color for low values, ordered by their value
+	Thresholds []Threshold
+}
+
+// Threshold gives the filled part of a meter another
+// color while its value is below Below
+type Threshold struct {
+	Below float64
+	Color uint32
+}
+
+// Fraction gets how much of the meter is filled, from 0 to 1
+func (meter Meter) Fraction() float64 {
+	if meter.Max <= meter.Min {
+		return 0
+	}
+
+	return math.Max(0, math.Min(1, (meter.Value-meter.Min)/(meter.Max-meter.Min)))
+}
+
+// FillColor gets the color of the filled part, which is the color
+// of the first threshold the value is below or Color otherwise
+func (meter Meter) FillColor() uint32 {
+	for _, threshold := range meter.Thresholds {
+		if meter.Value < threshold.Below {
+			return threshold.Color
+		}
+	}
+
+	return meter.Color
+}
+
+// tells wether two meters show the same
+func sameMeter(a Meter, b Meter) bool {
+	if a.Value != b.Value || a.Min != b.Min || a.Max != b.Max ||
+		a.Color != b.Color || a.Track != b.Track || len(a.Thresholds) != len(b.Thresholds) {
+		return false
+	}
+
+	for i := range a.Thresholds {
+		if a.Thresholds[i] != b.Thresholds[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*
+########################
+# Subsection: Progress
+########################
+*/
+
+// Progress is a bar filled from the left, or from the bottom if vertical
+type Progress struct {
+	ItemBase
+	Meter
+
+	Vertical bool
+}
+
+// Draw draws the item onto the parent surface
+func (prog *Progress) Draw(surf *sdl.Surface) (err error) {
+	content := prog.Style.Content(prog.Size)
+
+	// flip bytes for sdl
+	surf.FillRect(&content, image.UInt32ToColor(prog.Track).Uint32())
+
+	filled := content
+	if prog.Vertical {
+		filled.H = int32(math.Round(float64(content.H) * prog.Fraction()))
+		filled.Y += content.H - filled.H
+	} else {
+		filled.W = int32(math.Round(float64(content.W) * prog.Fraction()))
+	}
+
+	return surf.FillRect(&filled, image.UInt32ToColor(prog.FillColor()).Uint32())
+}
+
+/*
+########################
+# Subsection: Gauge
+########################
+*/
+
+// Gauge is an arc filled from its start in clockwise direction
+type Gauge struct {
+	ItemBase
+	Meter
+
+	// the width of the arc, a fifth of its radius if 0
+	Thickness int32
+
+	// the angle the arc starts at and the angle it covers in degrees.
+	// 0 is on the right, angles go clockwise
+	Start float64
+	Sweep float64
+}
+
+// Draw draws the item onto the parent surface
+func (gauge *Gauge) Draw(surf *sdl.Surface) (err error) {
+	content := gauge.Style.Content(gauge.Size)
+	outer := float64(min32(content.W, content.H)) / 2
+
+	thickness := float64(gauge.Thickness)
+	if thickness <= 0 {
+		thickness = outer / 5
+	}
+	inner := math.Max(0, outer-thickness)
+
+	// draws the part of the arc from the start up to a fraction of the sweep
+	arc := func(fraction float64, color uint32) {
+		shape := Shape{Fill: color}
+		shape.rasterize(surf, content, func(x float64, y float64) (fill bool, stroke bool) {
+			dx, dy := x-float64(content.W)/2, y-float64(content.H)/2
+			if r := math.Hypot(dx, dy); r > outer || r < inner {
+				return false, false
+			}
+
+			return gauge.angleFraction(dx, dy) <= fraction, false
+		})
+	}
+
+	arc(1, gauge.Track)
+	if fraction := gauge.Fraction(); fraction > 0 {
+		arc(fraction, gauge.FillColor())
+	}
+
+	return
+}
+
+// gets how far the direction dx, dy is along the arc, from 0 to 1.
+// Directions outside of the arc are above 1
+func (gauge *Gauge) angleFraction(dx float64, dy float64) float64 {
+	if gauge.Sweep <= 0 {
+		return 2
+	}
+
+	angle := math.Atan2(dy, dx)*180/math.Pi - gauge.Start
+	angle = math.Mod(math.Mod(angle, 360)+360, 360)
+
+	return angle / gauge.Sweep
+}
+
+/*
+########################
+# Subsection: LevelBar
+########################
+*/
+
+// LevelBar is a bar made of blocks, which are filled
+// from the left, or from the bottom if vertical
+type LevelBar struct {
+	ItemBase
+	Meter
+
+	Vertical bool
+
+	// the count of blocks and the space between them
+	Blocks  int
+	Spacing int32
+}
+
+// Draw draws the item onto the parent surface.
+// Only blocks that are filled completely get the fill color
+func (bar *LevelBar) Draw(surf *sdl.Surface) (err error) {
+	content := bar.Style.Content(bar.Size)
+	blocks := bar.Blocks
+	if blocks < 1 {
+		blocks = 1
+	}
+
+	length := content.W
+	if bar.Vertical {
+		length = content.H
+	}
+	size := float64(length-bar.Spacing*int32(blocks-1)) / float64(blocks)
+
+	// a small tolerance, so full meters fill all blocks
+	filled := int(math.Floor(bar.Fraction()*float64(blocks) + 1e-9))
+
+	for i := 0; i < blocks; i++ {
+		// round the borders of the blocks so they are evenly spaced
+		start := int32(math.Round(float64(i) * (size + float64(bar.Spacing))))
+		end := int32(math.Round(float64(i)*(size+float64(bar.Spacing)) + size))
+
+		block := sdl.Rect{X: content.X + start, Y: content.Y, W: end - start, H: content.H}
+		if bar.Vertical {
+			block = sdl.Rect{X: content.X, Y: content.Y + length - end, W: content.W, H: end - start}
+		}
+
+		color := bar.Track
+		if i < filled {
+			color = bar.FillColor()
+		}
+
+		// flip bytes for sdl
+		surf.FillRect(&block, image.UInt32ToColor(color).Uint32())
+	}
+
+	return
+}
@@ -0,0 +1,292 @@
+package data
+
+import (
+	"github.com/phoenixdevelops/fliw/image"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/*
+##############################################################
+# Section: Renderer
+##############################################################
+*/
+
+// Renderer draws a tree of items, keeping the rendering of each
+// item between frames. Only items that changed (see HasChanged)
+// or whose parent changed are drawn again.
+type Renderer struct {
+	root *renderNode
+}
+
+// renderNode is the retained rendering of an item
+type renderNode struct {
+	uid     uint
+	surface *sdl.Surface
+
+	// the rendered children of a container
+	// and the rectangles they were drawn at
+	children []*renderNode
+	rects    []sdl.Rect
+}
+
+// NewRenderer creates a renderer without any retained items
+func NewRenderer() *Renderer {
+	return &Renderer{}
+}
+
+// Render draws a container onto surf, drawing only what changed since
+// the last frame. It gets the areas of surf that changed, which can be passed
+// on to sdl.Window.UpdateSurfaceRects.
+func (r *Renderer) Render(root Container, surf *sdl.Surface) (dirty []sdl.Rect, err error) {
+	if r.root == nil || r.root.uid != root.GetUID() {
+		r.Invalidate()
+		r.root = &renderNode{uid: root.GetUID()}
+	}
+
+	dirty, err = r.root.render(root, false)
+	if err != nil {
+		return
+	}
+
+	// copy the changed areas of the root surface onto surf.
+	// They are copied, not blended, so surf gets the alpha of the root
+	r.root.surface.SetBlendMode(sdl.BLENDMODE_NONE)
+	dirty = mergeRects(dirty)
+	for i := range dirty {
+		rect := dirty[i]
+		r.root.surface.Blit(&rect, surf, &rect)
+	}
+
+	return dirty, nil
+}
+
+// Invalidate forgets all retained renderings,
+// so the next frame is drawn completely
+func (r *Renderer) Invalidate() {
+	if r.root != nil {
+		r.root.free()
+		r.root = nil
+	}
+}
+
+// renders an item onto the surface of the node if needed and gets
+// the changed areas of the surface.
+// force is set if the item has to be drawn even if it didn't change
+func (node *renderNode) render(item Item, force bool) (dirty []sdl.Rect, err error) {
+	size := frameSize(item)
+	full := sdl.Rect{X: 0, Y: 0, W: size.X, H: size.Y}
+
+	redraw := force || item.HasChanged()
+	if node.surface == nil || node.surface.W != size.X || node.surface.H != size.Y {
+		node.freeSurface()
+		node.surface, err = image.NewSurface(size.X, size.Y)
+		if err != nil {
+			return
+		}
+		redraw = true
+	}
+	node.surface.SetAlphaMod(item.GetStyle().alpha())
+
+	cont, ok := item.(Container)
+	if !ok {
+		if !redraw {
+			return nil, nil
+		}
+
+		// items are drawn onto a transparent surface,
+		// which is blended onto their container
+		node.surface.FillRect(nil, 0)
+
+		if err = item.Draw(node.surface); err != nil {
+			return
+		}
+		decorate(item.GetStyle(), node.surface)
+
+		return []sdl.Rect{full}, nil
+	}
+
+	// a container that changed draws all of its items again,
+	// as they are blended onto its background
+	items := cont.GetItems()
+	layout := scrolledLayout(cont)
+
+	children := make([]*renderNode, len(items))
+	rects := make([]sdl.Rect, len(items))
+	recomposite := redraw || len(items) != len(node.children)
+
+	for i, child := range items {
+		rects[i] = frameRect(child, layout[i])
+
+		// reuse the rendering of the item at the same index, if it's the same item
+		reused := i < len(node.children) && node.children[i].uid == child.GetUID()
+		if reused {
+			children[i] = node.children[i]
+			node.children[i] = nil
+		} else {
+			children[i] = &renderNode{uid: child.GetUID()}
+		}
+
+		childdirty, err := children[i].render(child, redraw)
+		if err != nil {
+			return nil, err
+		}
+
+		// items that were replaced, moved or resized leave their old area dirty
+		if !reused || node.rects[i] != rects[i] {
+			recomposite = true
+			if i < len(node.rects) {
+				dirty = append(dirty, node.rects[i])
+			}
+			dirty = append(dirty, rects[i])
+			continue
+		}
+
+		for _, rect := range childdirty {
+			rect.X += rects[i].X
+			rect.Y += rects[i].Y
+			dirty = append(dirty, rect)
+			recomposite = true
+		}
+	}
+
+	// items that are gone leave their area dirty
+	for i, old := range node.children {
+		if old != nil {
+			old.free()
+		}
+		if i >= len(items) {
+			dirty = append(dirty, node.rects[i])
+		}
+	}
+
+	node.children = children
+	node.rects = rects
+
+	if recomposite {
+		// flip bytes for sdl
+		node.surface.FillRect(nil, image.UInt32ToColor(cont.GetBGcolor()).Uint32())
+
+		for i, child := range children {
+			// items scrolled out of the container are not drawn
+			if !touches(rects[i], full) {
+				continue
+			}
+
+			src := sdl.Rect{X: 0, Y: 0, W: rects[i].W, H: rects[i].H}
+			dst := rects[i]
+			child.surface.Blit(&src, node.surface, &dst)
+		}
+
+		drawScrollbars(cont, node.surface)
+		tracks, _ := scrollbarRects(cont)
+		dirty = append(dirty, tracks...)
+
+		// the border is drawn over the items, which clips them to the rounded corners
+		decorate(cont.GetStyle(), node.surface)
+	}
+
+	if redraw {
+		return []sdl.Rect{full}, nil
+	}
+
+	return clipRects(dirty, full), nil
+}
+
+// frees the surface of the node
+func (node *renderNode) freeSurface() {
+	if node.surface != nil {
+		node.surface.Free()
+		node.surface = nil
+	}
+}
+
+// frees the surfaces of the node and all its children
+func (node *renderNode) free() {
+	node.freeSurface()
+
+	for _, child := range node.children {
+		if child != nil {
+			child.free()
+		}
+	}
+	node.children = nil
+}
+
+/*
+##############################################################
+# Section: Rectangles
+##############################################################
+*/
+
+// tells wether two rectangles overlap or touch
+func touches(a sdl.Rect, b sdl.Rect) bool {
+	return a.X <= b.X+b.W && b.X <= a.X+a.W && a.Y <= b.Y+b.H && b.Y <= a.Y+a.H
+}
+
+// gets the smallest rectangle containing both rectangles
+func union(a sdl.Rect, b sdl.Rect) sdl.Rect {
+	x := min32(a.X, b.X)
+	y := min32(a.Y, b.Y)
+
+	return sdl.Rect{
+		X: x,
+		Y: y,
+		W: max32(a.X+a.W, b.X+b.W) - x,
+		H: max32(a.Y+a.H, b.Y+b.H) - y,
+	}
+}
+
+// merges overlapping rectangles until none overlap
+func mergeRects(rects []sdl.Rect) (merged []sdl.Rect) {
+	for _, rect := range rects {
+		if rect.W <= 0 || rect.H <= 0 {
+			continue
+		}
+
+		// merging can make the rectangle overlap others,
+		// so keep merging until it doesn't
+		for i := 0; i < len(merged); {
+			if touches(rect, merged[i]) {
+				rect = union(rect, merged[i])
+				merged = append(merged[:i], merged[i+1:]...)
+				i = 0
+				continue
+			}
+			i++
+		}
+
+		merged = append(merged, rect)
+	}
+
+	return
+}
+
+// clips the rectangles to an area, dropping the ones outside of it
+func clipRects(rects []sdl.Rect, area sdl.Rect) (clipped []sdl.Rect) {
+	for _, rect := range rects {
+		x := max32(rect.X, area.X)
+		y := max32(rect.Y, area.Y)
+		w := min32(rect.X+rect.W, area.X+area.W) - x
+		h := min32(rect.Y+rect.H, area.Y+area.H) - y
+
+		if w > 0 && h > 0 {
+			clipped = append(clipped, sdl.Rect{X: x, Y: y, W: w, H: h})
+		}
+	}
+
+	return
+}
+
+func min32(a int32, b int32) int32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a int32, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
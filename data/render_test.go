@@ -0,0 +1,88 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+func newTestTree() *BaseContainer {
+	return &BaseContainer{
+		ContainerBase: ContainerBase{
+			ItemBase: ItemBase{UID: 0, Size: Vector{X: 100, Y: 100}},
+			Items: []Item{
+				&Unicolor{ItemBase: ItemBase{UID: 1, Position: Vector{X: 0, Y: 0}, Size: Vector{X: 10, Y: 10}}},
+				&Unicolor{ItemBase: ItemBase{UID: 2, Position: Vector{X: 50, Y: 50}, Size: Vector{X: 10, Y: 10}}},
+			},
+		},
+	}
+}
+
+func TestRenderer(t *testing.T) {
+	surf, _ := sdl.CreateRGBSurface(0, 100, 100, 32, 0, 0, 0, 0)
+	renderer := NewRenderer()
+
+	// the first frame is drawn completely
+	dirty, err := renderer.Render(newTestTree(), surf)
+	expected := sdl.Rect{X: 0, Y: 0, W: 100, H: 100}
+	if err != nil || len(dirty) != 1 || dirty[0] != expected {
+		t.Error("Expected ", expected, ", gave ", dirty, err)
+	}
+
+	// nothing changed
+	dirty, _ = renderer.Render(newTestTree(), surf)
+	if len(dirty) != 0 {
+		t.Error("Expected no dirty areas, gave ", dirty)
+	}
+
+	// only the changed item is drawn
+	tree := newTestTree()
+	tree.Items[1].SetHasChanged(true)
+	dirty, _ = renderer.Render(tree, surf)
+	expected = sdl.Rect{X: 50, Y: 50, W: 10, H: 10}
+	if len(dirty) != 1 || dirty[0] != expected {
+		t.Error("Expected ", expected, ", gave ", dirty)
+	}
+
+	// a moved item leaves its old area dirty
+	tree = newTestTree()
+	tree.Items[0].SetPosition(Vector{X: 5, Y: 0})
+	dirty, _ = renderer.Render(tree, surf)
+	expected = sdl.Rect{X: 0, Y: 0, W: 15, H: 10}
+	if len(dirty) != 1 || dirty[0] != expected {
+		t.Error("Expected ", expected, ", gave ", dirty)
+	}
+}
+
+func TestMergeRects(t *testing.T) {
+	rects := []sdl.Rect{
+		{X: 0, Y: 0, W: 10, H: 10},
+		{X: 50, Y: 50, W: 10, H: 10},
+		{X: 5, Y: 5, W: 10, H: 10},
+		{X: 0, Y: 0, W: 0, H: 10},
+	}
+
+	merged := mergeRects(rects)
+	if len(merged) != 2 {
+		t.Fatal("Expected 2 rectangles, gave ", merged)
+	}
+
+	expected := sdl.Rect{X: 0, Y: 0, W: 15, H: 15}
+	if merged[1] != expected {
+		t.Error("Expected ", expected, ", gave ", merged[1])
+	}
+}
+
+func TestSameContent(t *testing.T) {
+	a := &Label{ItemBase: ItemBase{UID: 1, Events: map[string]string{}}, Text: "a"}
+	b := &Label{ItemBase: ItemBase{UID: 1, Position: Vector{X: 3}}, Text: "a"}
+
+	if !SameContent(a, b) {
+		t.Error("Expected labels with the same text to look the same")
+	}
+
+	b.Text = "b"
+	if SameContent(a, b) {
+		t.Error("Expected labels with different texts to look different")
+	}
+}
@@ -0,0 +1,195 @@
+package data
+
+import (
+	"github.com/phoenixdevelops/fliw/image"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/*
+##############################################################
+# Section: Scrolling
+##############################################################
+*/
+
+// ScrollMode tells in which directions a container can be scrolled
+type ScrollMode int
+
+// the directions a container can be scrolled in
+const (
+	ScrollNone ScrollMode = iota
+	ScrollVertical
+	ScrollHorizontal
+	ScrollBoth
+)
+
+// the width of scrollbars and the color of their thumbs
+const (
+	scrollbarWidth = 4
+	scrollbarColor = 0xFF888888
+)
+
+// the scroll offsets of the containers by their uid.
+// They are kept here, as containers are parsed again every frame
+var scrollOffsets = make(map[uint]Vector)
+
+// tells wether a container can be scrolled vertically and horizontally
+func scrollDirections(cont Container) (vertical bool, horizontal bool) {
+	mode := cont.GetScrollMode()
+	return mode == ScrollVertical || mode == ScrollBoth,
+		mode == ScrollHorizontal || mode == ScrollBoth
+}
+
+// gets the size of the area covered by the items of a container,
+// including the border and padding after them
+func contentSize(cont Container, layout []Vector) (size Vector) {
+	for i, item := range cont.GetItems() {
+		itemsize := item.GetSize()
+		size.X = max32(size.X, layout[i].X+itemsize.X)
+		size.Y = max32(size.Y, layout[i].Y+itemsize.Y)
+	}
+
+	style := cont.GetStyle()
+	size.X += style.Border + style.Padding.Right
+	size.Y += style.Border + style.Padding.Bottom
+
+	return
+}
+
+// clamps an offset so the container doesn't scroll past its content
+func clampOffset(cont Container, layout []Vector, offset Vector) Vector {
+	vertical, horizontal := scrollDirections(cont)
+	content := contentSize(cont, layout)
+	size := frameSize(cont)
+
+	if !horizontal {
+		offset.X = 0
+	}
+	if !vertical {
+		offset.Y = 0
+	}
+
+	offset.X = max32(0, min32(offset.X, content.X-size.X))
+	offset.Y = max32(0, min32(offset.Y, content.Y-size.Y))
+
+	return offset
+}
+
+// ScrollOffset gets how far a container is scrolled
+func ScrollOffset(cont Container) Vector {
+	if cont.GetScrollMode() == ScrollNone {
+		return Vector{}
+	}
+
+	return clampOffset(cont, cont.Layout(), scrollOffsets[cont.GetUID()])
+}
+
+// Scroll scrolls a container by delta pixels.
+// It tells wether the container could be scrolled
+func Scroll(cont Container, delta Vector) bool {
+	if cont.GetScrollMode() == ScrollNone {
+		return false
+	}
+
+	layout := cont.Layout()
+	old := clampOffset(cont, layout, scrollOffsets[cont.GetUID()])
+	offset := clampOffset(cont, layout, Vector{X: old.X + delta.X, Y: old.Y + delta.Y})
+
+	scrollOffsets[cont.GetUID()] = offset
+	return offset != old
+}
+
+// ScrollableAt gets the innermost container at a position that can
+// be scrolled. pos is relative to root. ok is false if there is none
+func ScrollableAt(root Container, pos Vector) (scrollable Container, ok bool) {
+	cont := root
+
+	for {
+		if cont.GetScrollMode() != ScrollNone {
+			scrollable, ok = cont, true
+		}
+
+		item := cont.GetItemAt(pos)
+		child, isContainer := item.(Container)
+		if !isContainer || child == cont {
+			return
+		}
+
+		position := ItemPosition(cont, item)
+		pos.X -= position.X
+		pos.Y -= position.Y
+		cont = child
+	}
+}
+
+// ItemPosition gets the position an item of a container is drawn at,
+// relative to the container. It includes the scroll offset of the
+// container and the margin of the item
+func ItemPosition(cont Container, item Item) Vector {
+	for i, other := range cont.GetItems() {
+		if other == item {
+			frame := frameRect(item, scrolledLayout(cont)[i])
+			return Vector{X: frame.X, Y: frame.Y}
+		}
+	}
+
+	return item.GetPosition()
+}
+
+// gets the layout of a container moved by its scroll offset
+func scrolledLayout(cont Container) []Vector {
+	layout := cont.Layout()
+	if cont.GetScrollMode() == ScrollNone {
+		return layout
+	}
+
+	offset := clampOffset(cont, layout, scrollOffsets[cont.GetUID()])
+	for i := range layout {
+		layout[i].X -= offset.X
+		layout[i].Y -= offset.Y
+	}
+
+	return layout
+}
+
+// gets the areas of a container covered by its scrollbars
+func scrollbarRects(cont Container) (tracks []sdl.Rect, thumbs []sdl.Rect) {
+	if !cont.HasScrollbar() || cont.GetScrollMode() == ScrollNone {
+		return
+	}
+
+	layout := cont.Layout()
+	content := contentSize(cont, layout)
+	offset := clampOffset(cont, layout, scrollOffsets[cont.GetUID()])
+	size := frameSize(cont)
+	vertical, horizontal := scrollDirections(cont)
+
+	// the scrollbars are inside of the border
+	border := cont.GetStyle().Border
+
+	// gets the start and length of a thumb on a track of length track
+	thumb := func(track int32, visible int32, content int32, offset int32) (int32, int32) {
+		return offset * track / content, max32(scrollbarWidth, visible*track/content)
+	}
+
+	if vertical && content.Y > size.Y {
+		y, h := thumb(size.Y, size.Y, content.Y, offset.Y)
+		tracks = append(tracks, sdl.Rect{X: size.X - border - scrollbarWidth, Y: 0, W: scrollbarWidth, H: size.Y})
+		thumbs = append(thumbs, sdl.Rect{X: size.X - border - scrollbarWidth, Y: y, W: scrollbarWidth, H: h})
+	}
+	if horizontal && content.X > size.X {
+		x, w := thumb(size.X, size.X, content.X, offset.X)
+		tracks = append(tracks, sdl.Rect{X: 0, Y: size.Y - border - scrollbarWidth, W: size.X, H: scrollbarWidth})
+		thumbs = append(thumbs, sdl.Rect{X: x, Y: size.Y - border - scrollbarWidth, W: w, H: scrollbarWidth})
+	}
+
+	return
+}
+
+// draws the scrollbars of a container onto its surface
+func drawScrollbars(cont Container, surf *sdl.Surface) {
+	_, thumbs := scrollbarRects(cont)
+
+	for i := range thumbs {
+		surf.FillRect(&thumbs[i], image.UInt32ToColor(scrollbarColor).Uint32())
+	}
+}
@@ -0,0 +1,302 @@
+package data
+
+import (
+	"math"
+
+	"github.com/phoenixdevelops/fliw/image"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/*
+##############################################################
+# Section: Shapes
+##############################################################
+*/
+
+// the count of samples per pixel in each direction used for anti-aliasing
+const samples = 4
+
+/*
+########################
+# Subsection: Gradient
+########################
+*/
+
+// Stop is the color of a gradient at an offset from 0 to 1
+type Stop struct {
+	Offset float64
+	Color  uint32
+}
+
+// Gradient is an item filled with colors blending into each other
+type Gradient struct {
+	ItemBase
+
+	// radial gradients go from the center to the edges,
+	// linear gradients go in the direction of Angle
+	Radial bool
+
+	// the direction of a linear gradient in degrees,
+	// 0 is from left to right, 90 from top to bottom
+	Angle float64
+
+	// the colors of the gradient, ordered by their offset
+	Stops []Stop
+}
+
+// Draw draws the item onto the parent surface
+func (grad *Gradient) Draw(surf *sdl.Surface) (err error) {
+	content := grad.Style.Content(grad.Size)
+	if len(grad.Stops) == 0 || content.W == 0 || content.H == 0 {
+		return
+	}
+
+	w, h := float64(content.W), float64(content.H)
+
+	// a linear gradient reaches from corner to corner in its direction
+	angle := grad.Angle * math.Pi / 180
+	dx, dy := math.Cos(angle), math.Sin(angle)
+	length := math.Abs(w*dx) + math.Abs(h*dy)
+
+	withPixels(surf, func(pixels []byte) {
+		for y := int32(0); y < content.H; y++ {
+			for x := int32(0); x < content.W; x++ {
+				px := float64(x) + 0.5 - w/2
+				py := float64(y) + 0.5 - h/2
+
+				var t float64
+				if grad.Radial {
+					t = math.Hypot(px/(w/2), py/(h/2))
+				} else {
+					t = (px*dx+py*dy)/length + 0.5
+				}
+
+				setPixel(surf, pixels, content.X+x, content.Y+y, grad.colorAt(t))
+			}
+		}
+	})
+
+	return
+}
+
+// gets the color of the gradient at offset t
+func (grad *Gradient) colorAt(t float64) sdl.Color {
+	stops := grad.Stops
+
+	if t <= stops[0].Offset {
+		return image.UInt32ToColor(stops[0].Color)
+	}
+
+	for i := 1; i < len(stops); i++ {
+		if t <= stops[i].Offset {
+			share := (t - stops[i-1].Offset) / (stops[i].Offset - stops[i-1].Offset)
+			return addColors(image.UInt32ToColor(stops[i-1].Color), 1-share, image.UInt32ToColor(stops[i].Color), share)
+		}
+	}
+
+	return image.UInt32ToColor(stops[len(stops)-1].Color)
+}
+
+/*
+########################
+# Subsection: Rectangle, Ellipse, Line & Polygon
+########################
+*/
+
+// Shape is how a geometric item is filled and outlined.
+// The stroke is drawn over the fill, centered on the outline
+type Shape struct {
+	Fill        uint32
+	Stroke      uint32
+	StrokeWidth float64
+}
+
+// Rectangle is an item filled with a rectangle
+type Rectangle struct {
+	ItemBase
+	Shape
+}
+
+// Draw draws the item onto the parent surface.
+// The outline is inset so the stroke stays inside of the item
+func (rect *Rectangle) Draw(surf *sdl.Surface) (err error) {
+	content := rect.Style.Content(rect.Size)
+	inset := rect.StrokeWidth / 2
+	right, bottom := float64(content.W)-inset, float64(content.H)-inset
+
+	rect.rasterize(surf, content, func(x float64, y float64) (fill bool, stroke bool) {
+		fill = x >= inset && x < right && y >= inset && y < bottom
+		distance := math.Min(math.Min(math.Abs(x-inset), math.Abs(x-right)), math.Min(math.Abs(y-inset), math.Abs(y-bottom)))
+		return fill, distance <= inset && x >= 0 && x < float64(content.W) && y >= 0 && y < float64(content.H)
+	})
+
+	return
+}
+
+// Ellipse is an item filled with an ellipse
+type Ellipse struct {
+	ItemBase
+	Shape
+}
+
+// Draw draws the item onto the parent surface.
+// The outline is inset so the stroke stays inside of the item
+func (ell *Ellipse) Draw(surf *sdl.Surface) (err error) {
+	content := ell.Style.Content(ell.Size)
+	inset := ell.StrokeWidth / 2
+	rx, ry := float64(content.W)/2, float64(content.H)/2
+
+	// the points inside of an ellipse with radii rx, ry
+	inside := func(x float64, y float64, rx float64, ry float64) bool {
+		if rx <= 0 || ry <= 0 {
+			return false
+		}
+		return math.Pow((x-float64(content.W)/2)/rx, 2)+math.Pow((y-float64(content.H)/2)/ry, 2) <= 1
+	}
+
+	ell.rasterize(surf, content, func(x float64, y float64) (fill bool, stroke bool) {
+		fill = inside(x, y, rx-inset, ry-inset)
+		return fill, inside(x, y, rx, ry) && !inside(x, y, rx-2*inset, ry-2*inset)
+	})
+
+	return
+}
+
+// Line is an item with a line drawn between two points.
+// Only the stroke of its shape is drawn
+type Line struct {
+	ItemBase
+	Shape
+
+	// the ends of the line, relative to the content area
+	From Vector
+	To   Vector
+}
+
+// Draw draws the item onto the parent surface
+func (line *Line) Draw(surf *sdl.Surface) (err error) {
+	content := line.Style.Content(line.Size)
+
+	line.rasterize(surf, content, func(x float64, y float64) (fill bool, stroke bool) {
+		return false, segmentDistance(x, y, line.From, line.To) <= line.StrokeWidth/2
+	})
+
+	return
+}
+
+// Polygon is an item filled with a polygon
+type Polygon struct {
+	ItemBase
+	Shape
+
+	// the corners of the polygon, relative to the content area
+	Points []Vector
+}
+
+// Draw draws the item onto the parent surface
+func (poly *Polygon) Draw(surf *sdl.Surface) (err error) {
+	content := poly.Style.Content(poly.Size)
+
+	poly.rasterize(surf, content, func(x float64, y float64) (fill bool, stroke bool) {
+		fill = poly.contains(x, y)
+
+		for i := range poly.Points {
+			next := poly.Points[(i+1)%len(poly.Points)]
+			if segmentDistance(x, y, poly.Points[i], next) <= poly.StrokeWidth/2 {
+				return fill, true
+			}
+		}
+
+		return fill, false
+	})
+
+	return
+}
+
+// tells wether a point is inside of the polygon, using the even-odd rule
+func (poly *Polygon) contains(x float64, y float64) (inside bool) {
+	for i := range poly.Points {
+		a := poly.Points[i]
+		b := poly.Points[(i+1)%len(poly.Points)]
+		ax, ay, bx, by := float64(a.X), float64(a.Y), float64(b.X), float64(b.Y)
+
+		// count the edges crossing the ray going right from the point
+		if (ay > y) != (by > y) && x < ax+(y-ay)*(bx-ax)/(by-ay) {
+			inside = !inside
+		}
+	}
+
+	return
+}
+
+// gets the distance of a point to the line segment from a to b
+func segmentDistance(x float64, y float64, a Vector, b Vector) float64 {
+	ax, ay := float64(a.X), float64(a.Y)
+	dx, dy := float64(b.X)-ax, float64(b.Y)-ay
+
+	// the closest point is the projection onto the segment
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = math.Max(0, math.Min(1, ((x-ax)*dx+(y-ay)*dy)/length))
+	}
+
+	return math.Hypot(x-(ax+t*dx), y-(ay+t*dy))
+}
+
+// draws a shape onto the content area of surf. shape tells for a point
+// relative to the content area wether it is filled and wether it is
+// on the stroke. Each pixel is sampled several times for anti-aliasing
+func (shape Shape) rasterize(surf *sdl.Surface, content sdl.Rect, inside func(x float64, y float64) (fill bool, stroke bool)) {
+	fill := image.UInt32ToColor(shape.Fill)
+	stroke := image.UInt32ToColor(shape.Stroke)
+	if shape.StrokeWidth <= 0 {
+		stroke = sdl.Color{}
+	}
+
+	withPixels(surf, func(pixels []byte) {
+		for y := int32(0); y < content.H; y++ {
+			for x := int32(0); x < content.W; x++ {
+				var filled, stroked int
+				for sy := 0; sy < samples; sy++ {
+					for sx := 0; sx < samples; sx++ {
+						f, s := inside(float64(x)+(float64(sx)+0.5)/samples, float64(y)+(float64(sy)+0.5)/samples)
+						if s && stroke.A > 0 {
+							stroked++
+						} else if f {
+							filled++
+						}
+					}
+				}
+
+				if filled == 0 && stroked == 0 {
+					continue
+				}
+
+				color := addColors(fill, float64(filled)/(samples*samples), stroke, float64(stroked)/(samples*samples))
+				px, py := content.X+x, content.Y+y
+				setPixel(surf, pixels, px, py, blendColors(getPixel(surf, pixels, px, py), color))
+			}
+		}
+	})
+}
+
+// gets the color of a pixel with color src drawn over color dst
+func blendColors(dst sdl.Color, src sdl.Color) sdl.Color {
+	srcalpha := float64(src.A) / 255
+	dstalpha := float64(dst.A) / 255 * (1 - srcalpha)
+
+	return addColors(src, 1, sdl.Color{R: dst.R, G: dst.G, B: dst.B, A: uint8(math.Round(255 * dstalpha))}, 1)
+}
+
+// calls draw with the pixels of a surface, which is locked meanwhile.
+// Nothing is drawn onto surfaces without pixels
+func withPixels(surf *sdl.Surface, draw func(pixels []byte)) {
+	if surf.MustLock() {
+		surf.Lock()
+		defer surf.Unlock()
+	}
+
+	if pixels := surf.Pixels(); pixels != nil {
+		draw(pixels)
+	}
+}
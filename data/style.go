@@ -0,0 +1,186 @@
+package data
+
+import (
+	"math"
+
+	"github.com/phoenixdevelops/fliw/image"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/*
+##############################################################
+# Section: Box model
+##############################################################
+*/
+
+// Edges holds a value for each side of a rectangle
+type Edges struct {
+	Top    int32
+	Right  int32
+	Bottom int32
+	Left   int32
+}
+
+// Style is the box model of an item and how it is drawn onto its parent.
+// The size of an item includes its margin, border and padding
+type Style struct {
+	// the space around the border, nothing is drawn there
+	Margin Edges
+
+	// the width and color of the border
+	Border      int32
+	BorderColor uint32
+
+	// the space between the border and the content
+	Padding Edges
+
+	// the radius of the corners of the border, 0 for square corners
+	Radius int32
+
+	// the item and its items are drawn with an opacity of
+	// 1 - Transparency, so items are opaque by default
+	Transparency float64
+}
+
+// gets the area an item of size size at position is drawn in,
+// which is the area inside of its margin
+func (style Style) frame(position Vector, size Vector) sdl.Rect {
+	return sdl.Rect{
+		X: position.X + style.Margin.Left,
+		Y: position.Y + style.Margin.Top,
+		W: max32(0, size.X-style.Margin.Left-style.Margin.Right),
+		H: max32(0, size.Y-style.Margin.Top-style.Margin.Bottom),
+	}
+}
+
+// Content gets the area inside of the border and padding
+// of an item of size size, relative to the area the item is drawn in
+func (style Style) Content(size Vector) sdl.Rect {
+	frame := style.frame(Vector{}, size)
+
+	return sdl.Rect{
+		X: style.Border + style.Padding.Left,
+		Y: style.Border + style.Padding.Top,
+		W: max32(0, frame.W-2*style.Border-style.Padding.Left-style.Padding.Right),
+		H: max32(0, frame.H-2*style.Border-style.Padding.Top-style.Padding.Bottom),
+	}
+}
+
+// tells wether a position relative to the area an item of size
+// size is drawn in is inside of its rounded corners
+func (style Style) contains(size Vector, pos Vector) bool {
+	frame := style.frame(Vector{}, size)
+	return coverage(pos.X, pos.Y, frame.W, frame.H, 0, style.Radius) >= 0.5
+}
+
+// gets the alpha the surface of the item is drawn with
+func (style Style) alpha() uint8 {
+	opacity := math.Max(0, math.Min(1, 1-style.Transparency))
+	return uint8(math.Round(255 * opacity))
+}
+
+// GetStyle gets the box model of the item
+func (base *ItemBase) GetStyle() Style {
+	return base.Style
+}
+
+// SetStyle sets the box model of the item
+func (base *ItemBase) SetStyle(style Style) {
+	base.Style = style
+}
+
+// gets the area an item is drawn in, with its layout position
+func frameRect(item Item, position Vector) sdl.Rect {
+	return item.GetStyle().frame(position, item.GetSize())
+}
+
+// gets the size of the area an item is drawn in
+func frameSize(item Item) Vector {
+	frame := frameRect(item, Vector{})
+	return Vector{X: frame.W, Y: frame.H}
+}
+
+// draws the border of an item onto the surface it was drawn on and rounds
+// its corners. The area outside of the corners becomes transparent.
+// Edges are anti-aliased by how much of a pixel they cover
+func decorate(style Style, surf *sdl.Surface) {
+	if style.Border <= 0 && style.Radius <= 0 {
+		return
+	}
+
+	border := image.UInt32ToColor(style.BorderColor)
+
+	// only the pixels this close to the edges can be changed
+	band := max32(style.Radius, style.Border) + 1
+
+	withPixels(surf, func(pixels []byte) {
+		for y := int32(0); y < surf.H; y++ {
+			for x := int32(0); x < surf.W; x++ {
+				// skip the middle of the rows between the corners
+				if y >= band && y < surf.H-band && x == band && surf.W-band > band {
+					x = surf.W - band
+				}
+
+				outer := coverage(x, y, surf.W, surf.H, 0, style.Radius)
+				inner := coverage(x, y, surf.W, surf.H, style.Border, style.Radius-style.Border)
+				if inner >= 1 {
+					continue
+				}
+
+				// the content covers the inner part of the border,
+				// nothing covers the part outside of it
+				content := getPixel(surf, pixels, x, y)
+				setPixel(surf, pixels, x, y, addColors(content, inner, border, outer-inner))
+			}
+		}
+	})
+}
+
+// gets how much of the pixel at x, y is covered by a rectangle with rounded
+// corners of radius radius, inset by inset on each side of an area of w × h
+func coverage(x int32, y int32, w int32, h int32, inset int32, radius int32) float64 {
+	halfw := float64(w-2*inset) / 2
+	halfh := float64(h-2*inset) / 2
+	if halfw <= 0 || halfh <= 0 {
+		return 0
+	}
+
+	r := math.Max(0, math.Min(float64(radius), math.Min(halfw, halfh)))
+
+	// the distance of the pixel's center to the edge of the rectangle,
+	// negative inside of it
+	dx := math.Abs(float64(x)+0.5-float64(w)/2) - (halfw - r)
+	dy := math.Abs(float64(y)+0.5-float64(h)/2) - (halfh - r)
+	distance := math.Hypot(math.Max(dx, 0), math.Max(dy, 0)) + math.Min(math.Max(dx, dy), 0) - r
+
+	return math.Max(0, math.Min(1, 0.5-distance))
+}
+
+// gets the color of a pixel covered by a share of a and a share of b.
+// The colors are weighted by their alpha, the rest of the pixel is transparent
+func addColors(a sdl.Color, ashare float64, b sdl.Color, bshare float64) sdl.Color {
+	aweight := float64(a.A) / 255 * ashare
+	bweight := float64(b.A) / 255 * bshare
+	alpha := aweight + bweight
+	if alpha <= 0 {
+		return sdl.Color{}
+	}
+
+	add := func(a uint8, b uint8) uint8 {
+		return uint8(math.Round((float64(a)*aweight + float64(b)*bweight) / alpha))
+	}
+
+	return sdl.Color{R: add(a.R, b.R), G: add(a.G, b.G), B: add(a.B, b.B), A: uint8(math.Round(255 * math.Min(1, alpha)))}
+}
+
+// gets the color of a pixel of a 32 bit surface
+func getPixel(surf *sdl.Surface, pixels []byte, x int32, y int32) sdl.Color {
+	i := y*surf.Pitch + x*4
+	return sdl.Color{R: pixels[i+2], G: pixels[i+1], B: pixels[i], A: pixels[i+3]}
+}
+
+// sets the color of a pixel of a 32 bit surface
+func setPixel(surf *sdl.Surface, pixels []byte, x int32, y int32, color sdl.Color) {
+	i := y*surf.Pitch + x*4
+	pixels[i], pixels[i+1], pixels[i+2], pixels[i+3] = color.B, color.G, color.R, color.A
+}
@@ -0,0 +1,466 @@
+package font
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+/*
+finds, opens and caches the fonts text is drawn with
+*/
+
+/*
+##############################################################
+# Section: Weights
+##############################################################
+*/
+
+// font weights, as used by OpenType and css
+const (
+	Thin       = 100
+	ExtraLight = 200
+	Light      = 300
+	Normal     = 400
+	Medium     = 500
+	SemiBold   = 600
+	Bold       = 700
+	ExtraBold  = 800
+	Black      = 900
+)
+
+var weightNames = map[string]int{
+	"thin":       Thin,
+	"extralight": ExtraLight,
+	"light":      Light,
+	"normal":     Normal,
+	"regular":    Normal,
+	"medium":     Medium,
+	"semibold":   SemiBold,
+	"bold":       Bold,
+	"extrabold":  ExtraBold,
+	"black":      Black,
+}
+
+// ParseWeight parses a weight given as name (e.g. semibold)
+// or as number between 1 and 1000
+func ParseWeight(weight string) (result int, err error) {
+	weight = strings.ToLower(strings.TrimSpace(weight))
+
+	if result, ok := weightNames[weight]; ok {
+		return result, nil
+	}
+
+	result, err = strconv.Atoi(weight)
+	if err != nil || result < 1 || result > 1000 {
+		return 0, errors.New("Invalid font weight: " + weight)
+	}
+
+	return result, nil
+}
+
+/*
+##############################################################
+# Section: Lookup
+##############################################################
+*/
+
+// Fallbacks are the families used for characters
+// none of the requested fonts can draw
+var Fallbacks = []string{"sans-serif", "Noto Sans Symbols", "Noto Sans Symbols2", "Symbola"}
+
+// the family used if none is given
+const defaultFamily = "sans-serif"
+
+type matchKey struct {
+	family string
+	weight int
+	italic bool
+}
+
+var (
+	mu sync.Mutex
+
+	// fonts of the system, read on the first lookup
+	config      *Config
+	systemFaces []Face
+
+	// fonts added with AddDir
+	addedDirs  = make(map[string]bool)
+	addedFaces []Face
+
+	matches = make(map[matchKey]Face)
+)
+
+// AddDir adds a directory containing font files, e.g. the fonts
+// bundled with a module. Its fonts are preferred over the fonts of the system
+func AddDir(dir string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if addedDirs[dir] {
+		return
+	}
+	addedDirs[dir] = true
+
+	addedFaces = append(addedFaces, scanDir(dir)...)
+	matches = make(map[matchKey]Face)
+}
+
+// reads the fonts of the system if that wasn't done yet
+func loadSystemFaces() {
+	if config != nil {
+		return
+	}
+
+	loaded := LoadConfig()
+	config = &loaded
+
+	for _, dir := range config.Dirs {
+		systemFaces = append(systemFaces, scanDir(dir)...)
+	}
+}
+
+// Find finds the font file of a family that matches weight and italic best.
+// The family can also be the path to a font file or
+// a generic family like sans-serif, serif or monospace
+func Find(family string, weight int, italic bool) (face Face, err error) {
+	family = strings.TrimSpace(family)
+	if family == "" {
+		family = defaultFamily
+	}
+
+	// font files can be used directly
+	if IsFontFile(family) {
+		if _, err := os.Stat(family); err != nil {
+			return face, err
+		}
+		face, err = ReadFace(family)
+		if err != nil {
+			// the style is unknown, but the file may still be usable
+			face = Face{Path: family, Weight: Normal}
+		}
+		return face, nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	key := matchKey{strings.ToLower(family), weight, italic}
+	if face, ok := matches[key]; ok {
+		return face, nil
+	}
+
+	loadSystemFaces()
+
+	// try the family itself and then the families it is an alias for
+	names := append([]string{family}, config.Aliases[key.family]...)
+	for _, name := range names {
+		face, ok := bestMatch(addedFaces, name, weight, italic)
+		if !ok {
+			face, ok = bestMatch(systemFaces, name, weight, italic)
+		}
+
+		if ok {
+			matches[key] = face
+			return face, nil
+		}
+	}
+
+	return face, errors.New("No font found for family " + family)
+}
+
+// finds the face of a family that matches weight and italic best
+func bestMatch(faces []Face, family string, weight int, italic bool) (best Face, ok bool) {
+	bestScore := -1
+
+	for _, face := range faces {
+		if !strings.EqualFold(face.Family, family) {
+			continue
+		}
+
+		score := abs(face.Weight - weight)
+		if face.Italic != italic {
+			score += 1000
+		}
+
+		if bestScore < 0 || score < bestScore {
+			best = face
+			bestScore = score
+		}
+	}
+
+	return best, bestScore >= 0
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+/*
+##############################################################
+# Section: Cache
+##############################################################
+*/
+
+type fontKey struct {
+	path  string
+	size  int
+	style int
+}
+
+var (
+	fontsMu sync.Mutex
+	fonts   = make(map[fontKey]*ttf.Font)
+)
+
+// Open opens a font file with a size and a ttf style (e.g. ttf.STYLE_ITALIC).
+// Opened fonts are shared, so they must not be closed or changed
+func Open(path string, size int, style int) (font *ttf.Font, err error) {
+	fontsMu.Lock()
+	defer fontsMu.Unlock()
+
+	key := fontKey{path, size, style}
+	if font, ok := fonts[key]; ok {
+		return font, nil
+	}
+
+	font, err = ttf.OpenFont(path, size)
+	if err != nil {
+		return
+	}
+	if style != ttf.STYLE_NORMAL {
+		font.SetStyle(style)
+	}
+
+	fonts[key] = font
+	return font, nil
+}
+
+// CloseAll closes all opened fonts
+func CloseAll() {
+	fontsMu.Lock()
+	defer fontsMu.Unlock()
+
+	for key, font := range fonts {
+		font.Close()
+		delete(fonts, key)
+	}
+}
+
+/*
+##############################################################
+# Section: Fallback chains
+##############################################################
+*/
+
+// Chain is a list of fonts. Each character is drawn
+// with the first font of the chain that has a glyph for it
+type Chain []*ttf.Font
+
+// OpenChain opens the fonts of a comma separated list of families
+// (see Find), followed by the Fallbacks.
+// Families that can't be found are skipped
+func OpenChain(families string, weight int, italic bool, size int) (chain Chain, err error) {
+	names := append(strings.Split(families, ","), Fallbacks...)
+
+	opened := make(map[string]bool)
+	for _, name := range names {
+		face, ferr := Find(name, weight, italic)
+		if ferr != nil {
+			if err == nil {
+				err = ferr
+			}
+			continue
+		}
+		if opened[face.Path] {
+			continue
+		}
+		opened[face.Path] = true
+
+		// let sdl_ttf fake styles the font file doesn't have
+		style := ttf.STYLE_NORMAL
+		if italic && !face.Italic {
+			style |= ttf.STYLE_ITALIC
+		}
+		if weight >= SemiBold && face.Weight < SemiBold {
+			style |= ttf.STYLE_BOLD
+		}
+
+		font, ferr := Open(face.Path, size, style)
+		if ferr != nil {
+			if err == nil {
+				err = ferr
+			}
+			continue
+		}
+
+		chain = append(chain, font)
+	}
+
+	if len(chain) == 0 {
+		return nil, err
+	}
+
+	return chain, nil
+}
+
+// a part of a text drawn with a single font
+type run struct {
+	text string
+	font *ttf.Font
+}
+
+// splits a text into runs of characters drawn with the same font
+func (chain Chain) runs(text string) (runs []run) {
+	var current *ttf.Font
+	start := 0
+
+	for i, char := range text {
+		font := chain[0]
+		for _, f := range chain {
+			if f.GlyphIsProvided32(char) {
+				font = f
+				break
+			}
+		}
+
+		if font != current && i > 0 {
+			runs = append(runs, run{text[start:i], current})
+			start = i
+		}
+		current = font
+	}
+
+	if start < len(text) {
+		runs = append(runs, run{text[start:], current})
+	}
+
+	return
+}
+
+// Size gets the size of a text drawn with the chain
+func (chain Chain) Size(text string) (width int, height int, err error) {
+	height = chain[0].Height()
+
+	for _, r := range chain.runs(text) {
+		w, h, err := r.font.SizeUTF8(r.text)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		width += w
+		if h > height {
+			height = h
+		}
+	}
+
+	return
+}
+
+// RenderShaded draws a text in color fg onto a surface filled with bg
+func (chain Chain) RenderShaded(text string, fg sdl.Color, bg sdl.Color) (surface *sdl.Surface, err error) {
+	runs := chain.runs(text)
+	if len(runs) == 1 {
+		return runs[0].font.RenderUTF8Shaded(text, fg, bg)
+	}
+
+	surfaces := make([]*sdl.Surface, 0, len(runs))
+	ascents := make([]int32, 0, len(runs))
+	defer func() {
+		for _, s := range surfaces {
+			s.Free()
+		}
+	}()
+
+	for _, r := range runs {
+		s, err := r.font.RenderUTF8Shaded(r.text, fg, bg)
+		if err != nil {
+			return nil, err
+		}
+		surfaces = append(surfaces, s)
+		ascents = append(ascents, int32(r.font.Ascent()))
+	}
+
+	return JoinBaseline(surfaces, ascents, bg)
+}
+
+// RenderBlended draws a text in color fg onto a transparent surface,
+// so it can be drawn onto backgrounds that are not opaque
+func (chain Chain) RenderBlended(text string, fg sdl.Color) (surface *sdl.Surface, err error) {
+	runs := chain.runs(text)
+	if len(runs) == 1 {
+		return runs[0].font.RenderUTF8Blended(text, fg)
+	}
+
+	surfaces := make([]*sdl.Surface, 0, len(runs))
+	ascents := make([]int32, 0, len(runs))
+	defer func() {
+		for _, s := range surfaces {
+			s.Free()
+		}
+	}()
+
+	for _, r := range runs {
+		s, err := r.font.RenderUTF8Blended(r.text, fg)
+		if err != nil {
+			return nil, err
+		}
+		surfaces = append(surfaces, s)
+		ascents = append(ascents, int32(r.font.Ascent()))
+	}
+
+	return JoinBaseline(surfaces, ascents, sdl.Color{})
+}
+
+// Render draws a text in color fg onto a surface filled with bg.
+// Texts on backgrounds that are not opaque are blended
+func (chain Chain) Render(text string, fg sdl.Color, bg sdl.Color) (surface *sdl.Surface, err error) {
+	if bg.A == 0xFF {
+		return chain.RenderShaded(text, fg, bg)
+	}
+
+	return chain.RenderBlended(text, fg)
+}
+
+// JoinBaseline puts rendered texts next to each other on a common baseline.
+// ascents are the distances of the baselines from the top of the surfaces.
+// The surface it gets has an alpha channel, bg may be transparent
+func JoinBaseline(surfaces []*sdl.Surface, ascents []int32, bg sdl.Color) (surface *sdl.Surface, err error) {
+	var width, ascent, descent int32
+	for i, s := range surfaces {
+		width += s.W
+		if ascents[i] > ascent {
+			ascent = ascents[i]
+		}
+		if d := s.H - ascents[i]; d > descent {
+			descent = d
+		}
+	}
+
+	surface, err = sdl.CreateRGBSurfaceWithFormat(0, width, ascent+descent, 32, sdl.PIXELFORMAT_ARGB8888)
+	if err != nil {
+		return
+	}
+	surface.SetBlendMode(sdl.BLENDMODE_BLEND)
+	surface.FillRect(nil, bg.Uint32())
+
+	// the texts don't overlap, so they are copied instead of blended
+	x := int32(0)
+	for i, s := range surfaces {
+		y := ascent - ascents[i]
+		s.SetBlendMode(sdl.BLENDMODE_NONE)
+		s.Blit(&sdl.Rect{X: 0, Y: 0, W: s.W, H: s.H}, surface, &sdl.Rect{X: x, Y: y, W: s.W, H: s.H})
+		x += s.W
+	}
+
+	return surface, nil
+}
@@ -0,0 +1,102 @@
+package font
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseWeight(t *testing.T) {
+	tests := map[string]int{
+		"bold":     Bold,
+		" Light ":  Light,
+		"regular":  Normal,
+		"550":      550,
+		"semibold": SemiBold,
+	}
+
+	for weight, expected := range tests {
+		if result, err := ParseWeight(weight); err != nil || result != expected {
+			t.Error("Expected ", expected, ", gave ", result, err)
+		}
+	}
+
+	for _, weight := range []string{"heavyish", "0", "1001", ""} {
+		if _, err := ParseWeight(weight); err == nil {
+			t.Error("Expected an error for ", weight)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fontconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Mkdir(filepath.Join(dir, "conf.d"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "fonts.conf"), []byte(`<?xml version="1.0"?>
+<fontconfig>
+	<dir>/opt/fonts</dir>
+	<dir>relative</dir>
+	<include ignore_missing="yes">conf.d</include>
+	<include ignore_missing="yes">missing.conf</include>
+</fontconfig>`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "conf.d", "10-alias.conf"), []byte(`<?xml version="1.0"?>
+<fontconfig>
+	<alias>
+		<family>Sans-Serif</family>
+		<prefer><family>Inter</family></prefer>
+		<default><family>Roboto</family></default>
+	</alias>
+</fontconfig>`), 0644)
+
+	os.Setenv("FONTCONFIG_FILE", filepath.Join(dir, "fonts.conf"))
+	defer os.Unsetenv("FONTCONFIG_FILE")
+
+	config := LoadConfig()
+
+	dirs := []string{"/opt/fonts", filepath.Join(dir, "relative")}
+	if len(config.Dirs) != len(dirs) || config.Dirs[0] != dirs[0] || config.Dirs[1] != dirs[1] {
+		t.Error("Expected ", dirs, ", gave ", config.Dirs)
+	}
+
+	// the configured aliases come before the default ones
+	aliases := config.Aliases["sans-serif"]
+	if len(aliases) < 3 || aliases[0] != "Inter" || aliases[1] != "Roboto" || aliases[2] != "DejaVu Sans" {
+		t.Error("Expected [Inter Roboto DejaVu Sans ...], gave ", aliases)
+	}
+}
+
+func TestBestMatch(t *testing.T) {
+	faces := []Face{
+		{Path: "regular.ttf", Family: "Inter", Weight: Normal},
+		{Path: "bold.ttf", Family: "Inter", Weight: Bold},
+		{Path: "italic.ttf", Family: "Inter", Weight: Normal, Italic: true},
+		{Path: "other.ttf", Family: "Roboto", Weight: SemiBold},
+	}
+
+	tests := []struct {
+		weight   int
+		italic   bool
+		expected string
+	}{
+		{Normal, false, "regular.ttf"},
+		{SemiBold, false, "bold.ttf"},
+		{Bold, true, "italic.ttf"},
+		{Light, false, "regular.ttf"},
+	}
+
+	for _, test := range tests {
+		face, ok := bestMatch(faces, "inter", test.weight, test.italic)
+		if !ok || face.Path != test.expected {
+			t.Error("Expected ", test.expected, ", gave ", face.Path)
+		}
+	}
+
+	if _, ok := bestMatch(faces, "Arial", Normal, false); ok {
+		t.Error("Expected no match for a missing family")
+	}
+}
@@ -0,0 +1,196 @@
+package font
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/phoenixdevelops/fliw/xdg"
+)
+
+/*
+finds the font directories and family aliases the way fontconfig does,
+by reading fonts.conf and the files it includes
+*/
+
+// Config holds the font directories and aliases of a fonts.conf file
+type Config struct {
+	Dirs []string
+
+	// the families to use for a family name like sans-serif,
+	// in the order they are preferred
+	Aliases map[string][]string
+}
+
+// the elements of a fonts.conf file that are needed
+type xmlFontconfig struct {
+	Dirs     []xmlDir   `xml:"dir"`
+	Includes []xmlDir   `xml:"include"`
+	Aliases  []xmlAlias `xml:"alias"`
+}
+
+type xmlDir struct {
+	Prefix string `xml:"prefix,attr"`
+	Path   string `xml:",chardata"`
+}
+
+type xmlAlias struct {
+	Family  string      `xml:"family"`
+	Prefer  xmlFamilies `xml:"prefer"`
+	Accept  xmlFamilies `xml:"accept"`
+	Default xmlFamilies `xml:"default"`
+}
+
+type xmlFamilies struct {
+	Families []string `xml:"family"`
+}
+
+// the directories fonts are searched in if there is no fonts.conf
+var defaultDirs = []string{
+	"/usr/share/fonts",
+	"/usr/local/share/fonts",
+	"~/.local/share/fonts",
+	"~/.fonts",
+}
+
+// the aliases used if fonts.conf doesn't define them
+var defaultAliases = map[string][]string{
+	"sans-serif": {"DejaVu Sans", "Noto Sans", "Liberation Sans", "Cantarell", "FreeSans"},
+	"serif":      {"DejaVu Serif", "Noto Serif", "Liberation Serif", "FreeSerif"},
+	"monospace":  {"DejaVu Sans Mono", "Noto Sans Mono", "Liberation Mono", "FreeMono"},
+}
+
+// LoadConfig reads the fontconfig configuration.
+// The file can be set with FONTCONFIG_FILE, it defaults to /etc/fonts/fonts.conf
+func LoadConfig() (config Config) {
+	path := os.Getenv("FONTCONFIG_FILE")
+	if path == "" {
+		path = "/etc/fonts/fonts.conf"
+	}
+
+	config = Config{Aliases: make(map[string][]string)}
+	config.read(path, make(map[string]bool))
+
+	if len(config.Dirs) == 0 {
+		for _, dir := range defaultDirs {
+			config.Dirs = append(config.Dirs, xdg.ExpandHome(dir))
+		}
+	}
+	for family, aliases := range defaultAliases {
+		config.Aliases[family] = append(config.Aliases[family], aliases...)
+	}
+
+	return
+}
+
+// reads a config file or all .conf files of a directory.
+// Files that can't be read are ignored, like fontconfig does
+// for includes with ignore_missing
+func (config *Config) read(path string, visited map[string]bool) {
+	if visited[path] {
+		return
+	}
+	visited[path] = true
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+
+	if info.IsDir() {
+		files, err := filepath.Glob(filepath.Join(path, "*.conf"))
+		if err != nil {
+			return
+		}
+		sort.Strings(files)
+
+		for _, file := range files {
+			config.read(file, visited)
+		}
+		return
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	var parsed xmlFontconfig
+	if xml.Unmarshal(content, &parsed) != nil {
+		return
+	}
+
+	for _, dir := range parsed.Dirs {
+		config.Dirs = append(config.Dirs, resolveConfigPath(dir, path))
+	}
+
+	for _, alias := range parsed.Aliases {
+		family := strings.ToLower(strings.TrimSpace(alias.Family))
+		for _, families := range []xmlFamilies{alias.Prefer, alias.Accept, alias.Default} {
+			for _, name := range families.Families {
+				config.Aliases[family] = append(config.Aliases[family], strings.TrimSpace(name))
+			}
+		}
+	}
+
+	for _, include := range parsed.Includes {
+		config.read(resolveConfigPath(include, path), visited)
+	}
+}
+
+// resolves a path of a dir or include element
+// relative to the config file it is in
+func resolveConfigPath(dir xmlDir, configpath string) string {
+	path := strings.TrimSpace(dir.Path)
+
+	switch {
+	case dir.Prefix == "xdg":
+		return filepath.Join(xdg.DataHome(), path)
+	case strings.HasPrefix(path, "~"):
+		return xdg.ExpandHome(path)
+	case !filepath.IsAbs(path):
+		return filepath.Join(filepath.Dir(configpath), path)
+	}
+
+	return path
+}
+
+// IsFontFile tells wether a file is a font file that can be opened
+func IsFontFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ttf", ".otf", ".ttc":
+		return true
+	}
+
+	return false
+}
+
+// finds all font files in a directory and its subdirectories
+func scanDir(dir string) (faces []Face) {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// unreadable directories are skipped
+			return nil
+		}
+
+		if info.IsDir() || !IsFontFile(path) {
+			return nil
+		}
+
+		face, err := ReadFace(path)
+		if err == nil {
+			faces = append(faces, face)
+		}
+
+		return nil
+	})
+
+	return
+}
+
+func containsFold(s string, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
@@ -0,0 +1,182 @@
+package font
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"unicode/utf16"
+)
+
+/*
+reads the names and style of a font file (TrueType, OpenType or
+TrueType collection) without loading the whole font
+*/
+
+// Face describes a font file
+type Face struct {
+	Path   string
+	Family string
+	Weight int
+	Italic bool
+}
+
+// sfnt name ids
+const (
+	nameFamily            = 1
+	nameSubfamily         = 2
+	nameTypographicFamily = 16
+)
+
+// the fsSelection bit of the OS/2 table marking italic fonts
+const fsSelectionItalic = 1
+
+// ReadFace reads the family name and style of a font file
+func ReadFace(path string) (face Face, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	face.Path = path
+	face.Weight = Normal
+
+	// collections start with their own header,
+	// the first font of the collection is used
+	var offset int64
+	tag := make([]byte, 4)
+	if _, err = file.ReadAt(tag, 0); err != nil {
+		return
+	}
+	if string(tag) == "ttcf" {
+		first := make([]byte, 4)
+		if _, err = file.ReadAt(first, 12); err != nil {
+			return
+		}
+		offset = int64(binary.BigEndian.Uint32(first))
+	}
+
+	tables, err := readTableDirectory(file, offset)
+	if err != nil {
+		return
+	}
+
+	names, ok := tables["name"]
+	if !ok {
+		return face, errors.New("Font " + path + " has no name table")
+	}
+
+	family, subfamily, err := readNames(file, names)
+	if err != nil {
+		return
+	}
+	face.Family = family
+	face.Italic = containsFold(subfamily, "italic") || containsFold(subfamily, "oblique")
+
+	if os2, ok := tables["OS/2"]; ok {
+		data := make([]byte, 64)
+		if _, err := file.ReadAt(data, int64(os2.offset)); err == nil && os2.length >= 64 {
+			face.Weight = int(binary.BigEndian.Uint16(data[4:6]))
+			face.Italic = face.Italic || binary.BigEndian.Uint16(data[62:64])&fsSelectionItalic != 0
+		}
+	}
+
+	return face, nil
+}
+
+type tableRecord struct {
+	offset uint32
+	length uint32
+}
+
+// reads the table directory of the font starting at offset
+func readTableDirectory(file *os.File, offset int64) (tables map[string]tableRecord, err error) {
+	header := make([]byte, 12)
+	if _, err = file.ReadAt(header, offset); err != nil {
+		return
+	}
+
+	count := int(binary.BigEndian.Uint16(header[4:6]))
+	records := make([]byte, 16*count)
+	if _, err = file.ReadAt(records, offset+12); err != nil {
+		return
+	}
+
+	tables = make(map[string]tableRecord, count)
+	for i := 0; i < count; i++ {
+		record := records[16*i : 16*i+16]
+		tables[string(record[0:4])] = tableRecord{
+			offset: binary.BigEndian.Uint32(record[8:12]),
+			length: binary.BigEndian.Uint32(record[12:16]),
+		}
+	}
+
+	return
+}
+
+// reads the family and subfamily names from the name table.
+// The typographic family is preferred, as the family name of
+// e.g. a semibold face often is "Inter SemiBold"
+func readNames(file *os.File, table tableRecord) (family string, subfamily string, err error) {
+	data := make([]byte, table.length)
+	if _, err = file.ReadAt(data, int64(table.offset)); err != nil {
+		return
+	}
+	if len(data) < 6 {
+		return "", "", errors.New("Invalid name table")
+	}
+
+	count := int(binary.BigEndian.Uint16(data[2:4]))
+	storage := int(binary.BigEndian.Uint16(data[4:6]))
+
+	names := make(map[int]string)
+	for i := 0; i < count && 6+12*i+12 <= len(data); i++ {
+		record := data[6+12*i : 6+12*i+12]
+		platform := binary.BigEndian.Uint16(record[0:2])
+		language := binary.BigEndian.Uint16(record[4:6])
+		id := int(binary.BigEndian.Uint16(record[6:8]))
+		length := int(binary.BigEndian.Uint16(record[8:10]))
+		start := storage + int(binary.BigEndian.Uint16(record[10:12]))
+
+		if start+length > len(data) {
+			continue
+		}
+		raw := data[start : start+length]
+
+		var name string
+		switch {
+		// windows, english (united states)
+		case platform == 3 && language == 0x409:
+			name = decodeUTF16(raw)
+		// macintosh, english
+		case platform == 1 && language == 0:
+			name = string(raw)
+		default:
+			continue
+		}
+
+		// prefer the windows names
+		if _, ok := names[id]; !ok || platform == 3 {
+			names[id] = name
+		}
+	}
+
+	family = names[nameTypographicFamily]
+	if family == "" {
+		family = names[nameFamily]
+	}
+	if family == "" {
+		return "", "", errors.New("Font has no family name")
+	}
+
+	return family, names[nameSubfamily], nil
+}
+
+func decodeUTF16(raw []byte) string {
+	chars := make([]uint16, len(raw)/2)
+	for i := range chars {
+		chars[i] = binary.BigEndian.Uint16(raw[2*i:])
+	}
+
+	return string(utf16.Decode(chars))
+}
@@ -0,0 +1,123 @@
+package font
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+breaks texts into lines that fit a width
+*/
+
+// Ellipsis is appended to texts that had to be shortened
+const Ellipsis = "…"
+
+// Line is a line of a wrapped text
+type Line struct {
+	Text string
+
+	// set if the line ends a paragraph (or the text)
+	End bool
+}
+
+// Layout breaks a text into lines no wider than width. Newlines start
+// a new paragraph. If maxlines is greater than 0, lines after maxlines
+// are dropped and the last line ends with an ellipsis
+func (chain Chain) Layout(text string, width int, maxlines int) []Line {
+	return layout(text, width, maxlines, chain.Width)
+}
+
+// Width gets the width of a text drawn with the chain
+func (chain Chain) Width(text string) int {
+	width, _, err := chain.Size(text)
+	if err != nil {
+		return 0
+	}
+
+	return width
+}
+
+// Ellipsize shortens a text until it fits width with an ellipsis appended
+func (chain Chain) Ellipsize(text string, width int) string {
+	return ellipsize(text, width, chain.Width)
+}
+
+// lays out a text, measuring the width of texts with measure
+func layout(text string, width int, maxlines int, measure func(string) int) (lines []Line) {
+	for _, paragraph := range strings.Split(text, "\n") {
+		lines = append(lines, wrap(paragraph, width, measure)...)
+
+		if maxlines > 0 && len(lines) > maxlines {
+			lines = lines[:maxlines]
+			lines[maxlines-1] = Line{Text: ellipsize(lines[maxlines-1].Text, width, measure), End: true}
+			return
+		}
+	}
+
+	return
+}
+
+// wraps a paragraph at spaces. Words wider than a line are broken up.
+// Without a width nothing fits a line, so the paragraph is kept as it is
+func wrap(paragraph string, width int, measure func(string) int) (lines []Line) {
+	if width <= 0 {
+		return []Line{{Text: strings.Join(strings.Fields(paragraph), " "), End: true}}
+	}
+
+	line := ""
+
+	for _, word := range strings.Fields(paragraph) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+
+		if measure(candidate) <= width {
+			line = candidate
+			continue
+		}
+
+		if line != "" {
+			lines = append(lines, Line{Text: line})
+		}
+
+		for measure(word) > width {
+			n := fitting(word, width, measure)
+			lines = append(lines, Line{Text: word[:n]})
+			word = word[n:]
+		}
+		line = word
+	}
+
+	return append(lines, Line{Text: line, End: true})
+}
+
+// gets the length in bytes of the longest beginning of a text that
+// is no wider than width. At least one character is always taken
+func fitting(text string, width int, measure func(string) int) int {
+	_, n := utf8.DecodeRuneInString(text)
+
+	for i := range text {
+		if i <= n {
+			continue
+		}
+		if measure(text[:i]) > width {
+			break
+		}
+		n = i
+	}
+
+	return n
+}
+
+// shortens a text until it fits width with an ellipsis appended
+func ellipsize(text string, width int, measure func(string) int) string {
+	text = strings.TrimRight(text, " ")
+
+	for text != "" && measure(text+Ellipsis) > width {
+		_, size := utf8.DecodeLastRuneInString(text)
+		text = strings.TrimRight(text[:len(text)-size], " ")
+	}
+
+	return text + Ellipsis
+}
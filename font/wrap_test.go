@@ -0,0 +1,56 @@
+package font
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+// every character is 10 pixels wide
+func measure(text string) int {
+	return 10 * utf8.RuneCountInString(text)
+}
+
+func TestLayout(t *testing.T) {
+	tests := []struct {
+		text     string
+		width    int
+		maxlines int
+		expected []Line
+	}{
+		{"hello world", 200, 0, []Line{{"hello world", true}}},
+		{"hello world", 80, 0, []Line{{"hello", false}, {"world", true}}},
+		{"a b\n\nc", 200, 0, []Line{{"a b", true}, {"", true}, {"c", true}}},
+		{"abcdefgh", 30, 0, []Line{{"abc", false}, {"def", false}, {"gh", true}}},
+		{"one two three four", 80, 2, []Line{{"one two", false}, {"three…", true}}},
+		{"one\ntwo\nthree", 80, 2, []Line{{"one", true}, {"two…", true}}},
+		{"", 80, 0, []Line{{"", true}}},
+		{"hello  world", 0, 0, []Line{{"hello world", true}}},
+		{"hello world", -10, 0, []Line{{"hello world", true}}},
+	}
+
+	for _, test := range tests {
+		lines := layout(test.text, test.width, test.maxlines, measure)
+
+		equal := len(lines) == len(test.expected)
+		for i := 0; equal && i < len(lines); i++ {
+			equal = lines[i] == test.expected[i]
+		}
+		if !equal {
+			t.Error("Expected ", test.expected, ", gave ", lines)
+		}
+	}
+}
+
+func TestEllipsize(t *testing.T) {
+	tests := map[string]string{
+		"short":         "short…",
+		"a longer text": "a long…",
+		"spaces   here": "spaces…",
+	}
+
+	for text, expected := range tests {
+		if result := ellipsize(text, 70, measure); result != expected {
+			t.Error("Expected ", expected, ", gave ", result)
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/*
+Animated images
+*/
+
+// frames shown shorter than this are shown for defaultDelay,
+// like browsers do for gifs without a delay
+const minDelay = 20 * time.Millisecond
+const defaultDelay = 100 * time.Millisecond
+
+// Animation is a sequence of frames shown one after another
+type Animation struct {
+	Frames []*sdl.Surface
+
+	// how long each frame is shown
+	Delays []time.Duration
+}
+
+// Duration gets how long it takes to show all frames once
+func (anim *Animation) Duration() (duration time.Duration) {
+	for _, delay := range anim.Delays {
+		duration += delay
+	}
+
+	return
+}
+
+// FrameAt gets the index of the frame shown at a time since the animation
+// started. If it doesn't loop, it stops at the last frame
+func (anim *Animation) FrameAt(elapsed time.Duration, loop bool) int {
+	duration := anim.Duration()
+	if duration <= 0 || len(anim.Frames) == 0 {
+		return 0
+	}
+
+	if loop {
+		elapsed %= duration
+	}
+
+	for i, delay := range anim.Delays {
+		if elapsed < delay {
+			return i
+		}
+		elapsed -= delay
+	}
+
+	return len(anim.Frames) - 1
+}
+
+// Free frees the surfaces of all frames
+func (anim *Animation) Free() {
+	for _, frame := range anim.Frames {
+		frame.Free()
+	}
+}
+
+// AnimationFromFile loads an animated gif or png (apng) file.
+// Other images are loaded as animation with a single frame
+func AnimationFromFile(path string) (anim *Animation, err error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	var frames []image.Image
+	var delays []time.Duration
+
+	switch {
+	case strings.EqualFold(filepath.Ext(path), ".gif"):
+		frames, delays, err = decodeGIF(file)
+	case isAPNG(file):
+		frames, delays, err = decodeAPNG(file)
+	default:
+		var img image.Image
+		img, _, err = image.Decode(bytes.NewReader(file))
+		frames, delays = []image.Image{img}, []time.Duration{0}
+	}
+	if err != nil {
+		return
+	}
+	if len(frames) == 0 {
+		return nil, errors.New("No frames in " + path)
+	}
+
+	anim = &Animation{Delays: delays}
+	for _, frame := range frames {
+		surface, err := ImgToSurface(frame)
+		if err != nil {
+			anim.Free()
+			return nil, err
+		}
+		anim.Frames = append(anim.Frames, surface)
+	}
+
+	return anim, nil
+}
+
+// SplitFrames splits a sprite sheet into count frames of the same size.
+// The frames are in a row, or in a column if the sheet is higher than wide
+func SplitFrames(sheet *sdl.Surface, count int) (frames []*sdl.Surface, err error) {
+	if count < 1 {
+		return nil, errors.New("A sprite sheet needs at least one frame")
+	}
+
+	w, h := sheet.W/int32(count), sheet.H
+	if sheet.H > sheet.W {
+		w, h = sheet.W, sheet.H/int32(count)
+	}
+	if w <= 0 || h <= 0 {
+		return nil, errors.New("The sprite sheet is too small for its frames")
+	}
+
+	// copy the alpha channel instead of blending it
+	mode, _ := sheet.GetBlendMode()
+	sheet.SetBlendMode(sdl.BLENDMODE_NONE)
+	defer sheet.SetBlendMode(mode)
+
+	for i := int32(0); i < int32(count); i++ {
+		frame, err := NewSurface(w, h)
+		if err != nil {
+			return nil, err
+		}
+
+		src := sdl.Rect{X: i * w, Y: 0, W: w, H: h}
+		if sheet.H > sheet.W {
+			src = sdl.Rect{X: 0, Y: i * h, W: w, H: h}
+		}
+		sheet.Blit(&src, frame, &sdl.Rect{X: 0, Y: 0, W: w, H: h})
+
+		frames = append(frames, frame)
+	}
+
+	return frames, nil
+}
+
+// gets how long a frame is shown, with delays too short to be meant replaced
+func frameDelay(delay time.Duration) time.Duration {
+	if delay < minDelay {
+		return defaultDelay
+	}
+	return delay
+}
+
+/*
+# GIF
+*/
+
+// decodes all frames of a gif as they are shown, each drawn over the previous ones
+func decodeGIF(file []byte) (frames []image.Image, delays []time.Duration, err error) {
+	decoded, err := gif.DecodeAll(bytes.NewReader(file))
+	if err != nil {
+		return
+	}
+
+	canvas := image.NewNRGBA(image.Rect(0, 0, decoded.Config.Width, decoded.Config.Height))
+
+	for i, frame := range decoded.Image {
+		var previous *image.NRGBA
+		if decoded.Disposal[i] == gif.DisposalPrevious {
+			previous = copyNRGBA(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+
+		frames = append(frames, copyNRGBA(canvas))
+		delays = append(delays, frameDelay(time.Duration(decoded.Delay[i])*10*time.Millisecond))
+
+		// clear the frame before the next one is drawn
+		switch decoded.Disposal[i] {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	return frames, delays, nil
+}
+
+/*
+# APNG
+*/
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngChunk is a chunk of a png file
+type pngChunk struct {
+	kind string
+	data []byte
+}
+
+// apngFrame is a frame of an apng before it is decoded
+type apngFrame struct {
+	// the frame control chunk
+	control []byte
+
+	// the compressed pixels of the frame
+	data []byte
+}
+
+// the ways an apng frame is cleared and drawn
+const (
+	apngDisposeBackground = 1
+	apngDisposePrevious   = 2
+	apngBlendOver         = 1
+)
+
+// splits a png file into its chunks
+func readChunks(file []byte) (chunks []pngChunk, err error) {
+	if !bytes.HasPrefix(file, pngSignature) {
+		return nil, errors.New("Not a png file")
+	}
+
+	for rest := file[len(pngSignature):]; len(rest) >= 12; {
+		length := binary.BigEndian.Uint32(rest)
+		if uint64(length)+12 > uint64(len(rest)) {
+			return nil, errors.New("Invalid png chunk")
+		}
+
+		chunks = append(chunks, pngChunk{string(rest[4:8]), rest[8 : 8+length]})
+		rest = rest[12+length:]
+	}
+
+	return chunks, nil
+}
+
+// tells wether a file is an animated png
+func isAPNG(file []byte) bool {
+	chunks, err := readChunks(file)
+	if err != nil {
+		return false
+	}
+
+	for _, chunk := range chunks {
+		switch chunk.kind {
+		case "acTL":
+			return true
+		case "IDAT":
+			// the animation control has to be before the image data
+			return false
+		}
+	}
+
+	return false
+}
+
+// decodes all frames of an apng as they are shown, each drawn over the previous ones.
+// Every frame is turned into a png file of its own, which is decoded by image/png
+func decodeAPNG(file []byte) (frames []image.Image, delays []time.Duration, err error) {
+	chunks, err := readChunks(file)
+	if err != nil {
+		return
+	}
+
+	var header []byte
+	var shared []pngChunk
+	var animation []*apngFrame
+	var current *apngFrame
+
+	for _, chunk := range chunks {
+		switch chunk.kind {
+		case "IHDR":
+			header = chunk.data
+		case "PLTE", "tRNS", "gAMA", "cHRM", "sRGB", "iCCP":
+			shared = append(shared, chunk)
+		case "fcTL":
+			if len(chunk.data) < 26 {
+				return nil, nil, errors.New("Invalid apng frame control")
+			}
+			current = &apngFrame{control: chunk.data}
+			animation = append(animation, current)
+		case "IDAT":
+			// the default image is only part of the
+			// animation if a frame control comes before it
+			if current != nil {
+				current.data = append(current.data, chunk.data...)
+			}
+		case "fdAT":
+			if current != nil && len(chunk.data) >= 4 {
+				current.data = append(current.data, chunk.data[4:]...)
+			}
+		}
+	}
+
+	if len(header) < 13 || len(animation) == 0 {
+		return nil, nil, errors.New("Invalid apng file")
+	}
+
+	width := binary.BigEndian.Uint32(header[0:])
+	height := binary.BigEndian.Uint32(header[4:])
+	canvas := image.NewNRGBA(image.Rect(0, 0, int(width), int(height)))
+
+	for _, frame := range animation {
+		control := frame.control
+		w, h := binary.BigEndian.Uint32(control[4:]), binary.BigEndian.Uint32(control[8:])
+		x, y := binary.BigEndian.Uint32(control[12:]), binary.BigEndian.Uint32(control[16:])
+		num, den := binary.BigEndian.Uint16(control[20:]), binary.BigEndian.Uint16(control[22:])
+		dispose, blend := control[24], control[25]
+
+		// a png file with just this frame
+		frameheader := append([]byte{}, header...)
+		binary.BigEndian.PutUint32(frameheader[0:], w)
+		binary.BigEndian.PutUint32(frameheader[4:], h)
+
+		var buf bytes.Buffer
+		buf.Write(pngSignature)
+		writeChunk(&buf, "IHDR", frameheader)
+		for _, chunk := range shared {
+			writeChunk(&buf, chunk.kind, chunk.data)
+		}
+		writeChunk(&buf, "IDAT", frame.data)
+		writeChunk(&buf, "IEND", nil)
+
+		img, err := png.Decode(&buf)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var previous *image.NRGBA
+		if dispose == apngDisposePrevious {
+			previous = copyNRGBA(canvas)
+		}
+
+		area := image.Rect(int(x), int(y), int(x+w), int(y+h))
+		op := draw.Src
+		if blend == apngBlendOver {
+			op = draw.Over
+		}
+		draw.Draw(canvas, area, img, img.Bounds().Min, op)
+
+		if den == 0 {
+			den = 100
+		}
+		frames = append(frames, copyNRGBA(canvas))
+		delays = append(delays, frameDelay(time.Duration(num)*time.Second/time.Duration(den)))
+
+		// clear the frame before the next one is drawn
+		switch dispose {
+		case apngDisposeBackground:
+			draw.Draw(canvas, area, image.Transparent, image.Point{}, draw.Src)
+		case apngDisposePrevious:
+			canvas = previous
+		}
+	}
+
+	return frames, delays, nil
+}
+
+// writes a chunk of a png file
+func writeChunk(buf *bytes.Buffer, kind string, data []byte) {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+	buf.Write(length[:])
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(kind))
+	crc.Write(data)
+
+	buf.WriteString(kind)
+	buf.Write(data)
+
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+	buf.Write(sum[:])
+}
+
+// copies an image
+func copyNRGBA(img *image.NRGBA) *image.NRGBA {
+	copied := image.NewNRGBA(img.Rect)
+	copy(copied.Pix, img.Pix)
+	return copied
+}
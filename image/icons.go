@@ -0,0 +1,392 @@
+package image
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/phoenixdevelops/fliw/xdg"
+)
+
+/*
+finds icons the way the freedesktop icon theme specification describes,
+see https://specifications.freedesktop.org/icon-theme-spec/latest/
+*/
+
+// the theme every theme falls back to
+const fallbackTheme = "hicolor"
+
+// the file types icons can have, in the order they are preferred
+var iconExtensions = []string{".png", ".svg", ".xpm"}
+
+// IconTheme is an icon theme described by an index.theme file
+type IconTheme struct {
+	Name string
+
+	// the themes icons are searched in if this theme doesn't have them
+	Inherits []string
+
+	// the directories of the theme holding the icons
+	Dirs []IconDir
+
+	// the base directories that have a directory of this theme
+	bases []string
+}
+
+// IconDir is a directory of an icon theme with icons of a size
+type IconDir struct {
+	Path  string
+	Size  int
+	Scale int
+
+	// Fixed, Scalable or Threshold
+	Type string
+
+	// the sizes scalable icons can be used for
+	MinSize int
+	MaxSize int
+
+	// how far the size of threshold icons can be off
+	Threshold int
+}
+
+// iconKey is a looked up icon
+type iconKey struct {
+	name  string
+	theme string
+	size  int
+	scale int
+}
+
+var (
+	iconsMu sync.Mutex
+
+	// the directories icons are searched in before the ones of the system
+	addedIconDirs []string
+
+	// the themes already read, nil if a theme doesn't exist
+	themes = make(map[string]*IconTheme)
+
+	// the paths of the icons already looked up,
+	// empty if an icon wasn't found
+	icons = make(map[iconKey]string)
+)
+
+// AddIconDir adds a directory containing icon themes or icons,
+// e.g. the icons bundled with a module. It is searched before the
+// directories of the system
+func AddIconDir(dir string) {
+	iconsMu.Lock()
+	defer iconsMu.Unlock()
+
+	for _, added := range addedIconDirs {
+		if added == dir {
+			return
+		}
+	}
+
+	addedIconDirs = append(addedIconDirs, dir)
+	themes = make(map[string]*IconTheme)
+	icons = make(map[iconKey]string)
+}
+
+// ForgetIcons drops the themes and icons already looked up,
+// so icons and themes installed since are found
+func ForgetIcons() {
+	iconsMu.Lock()
+	defer iconsMu.Unlock()
+
+	themes = make(map[string]*IconTheme)
+	icons = make(map[iconKey]string)
+}
+
+// IconDirs gets the base directories icons are searched in
+func IconDirs() (dirs []string) {
+	dirs = append(dirs, addedIconDirs...)
+
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".icons"))
+	}
+
+	dirs = append(dirs, filepath.Join(xdg.DataHome(), "icons"))
+
+	datadirs := os.Getenv("XDG_DATA_DIRS")
+	if datadirs == "" {
+		datadirs = "/usr/local/share:/usr/share"
+	}
+	for _, dir := range filepath.SplitList(datadirs) {
+		dirs = append(dirs, filepath.Join(dir, "icons"))
+	}
+
+	return append(dirs, "/usr/share/pixmaps")
+}
+
+// DefaultIconTheme gets the icon theme chosen by the user in the gtk settings,
+// or hicolor if there is none
+func DefaultIconTheme() string {
+	for _, version := range []string{"gtk-4.0", "gtk-3.0"} {
+		settings := readIni(filepath.Join(xdg.ConfigHome(), version, "settings.ini"))
+		if theme := settings["Settings"]["gtk-icon-theme-name"]; theme != "" {
+			return theme
+		}
+	}
+
+	return fallbackTheme
+}
+
+// LookupIcon finds the file of the icon with a name in a theme,
+// the themes it inherits from or hicolor. It is the file with the size
+// closest to size at a scale (2 for hidpi screens).
+// Icons that are not in a theme are searched in the base directories
+func LookupIcon(name string, theme string, size int, scale int) (path string, err error) {
+	iconsMu.Lock()
+	defer iconsMu.Unlock()
+
+	key := iconKey{name, theme, size, scale}
+	path, ok := icons[key]
+	if !ok {
+		path = findIcon(name, theme, size, scale, make(map[string]bool))
+		if path == "" {
+			path = findIcon(name, fallbackTheme, size, scale, make(map[string]bool))
+		}
+		if path == "" {
+			path = findFallbackIcon(name)
+		}
+
+		// icons that are not found are remembered too, so they are
+		// not searched for again every time the window is parsed
+		icons[key] = path
+	}
+
+	if path == "" {
+		return "", errors.New("Icon not found: " + name)
+	}
+	return path, nil
+}
+
+// finds an icon in a theme and the themes it inherits from
+func findIcon(name string, themename string, size int, scale int, visited map[string]bool) string {
+	if visited[themename] {
+		return ""
+	}
+	visited[themename] = true
+
+	theme := loadTheme(themename)
+	if theme == nil {
+		return ""
+	}
+
+	if path := theme.lookup(name, size, scale); path != "" {
+		return path
+	}
+
+	for _, parent := range theme.Inherits {
+		if path := findIcon(name, parent, size, scale, visited); path != "" {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// finds an icon in the directories of the theme. A directory matching
+// the size is preferred, otherwise the closest size is taken
+func (theme *IconTheme) lookup(name string, size int, scale int) string {
+	closest, distance := "", -1
+
+	for _, dir := range theme.Dirs {
+		matches := dir.matches(size, scale)
+		if !matches && distance >= 0 && dir.distance(size, scale) >= distance {
+			continue
+		}
+
+		for _, base := range theme.bases {
+			path := findIconFile(filepath.Join(base, theme.Name, dir.Path), name)
+			if path == "" {
+				continue
+			}
+
+			if matches {
+				return path
+			}
+
+			closest, distance = path, dir.distance(size, scale)
+			break
+		}
+	}
+
+	return closest
+}
+
+// finds an icon directly in one of the base directories
+func findFallbackIcon(name string) string {
+	for _, base := range IconDirs() {
+		if path := findIconFile(base, name); path != "" {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// finds the file of an icon in a directory
+func findIconFile(dir string, name string) string {
+	for _, ext := range iconExtensions {
+		path := filepath.Join(dir, name+ext)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// tells wether the icons of the directory have a size
+func (dir IconDir) matches(size int, scale int) bool {
+	if dir.Scale != scale {
+		return false
+	}
+
+	switch dir.Type {
+	case "Fixed":
+		return dir.Size == size
+	case "Scalable":
+		return dir.MinSize <= size && size <= dir.MaxSize
+	}
+
+	return dir.Size-dir.Threshold <= size && size <= dir.Size+dir.Threshold
+}
+
+// gets how far the size of the icons of the directory is off a size
+func (dir IconDir) distance(size int, scale int) int {
+	size *= scale
+
+	var min, max int
+	switch dir.Type {
+	case "Fixed":
+		min, max = dir.Size, dir.Size
+	case "Scalable":
+		min, max = dir.MinSize, dir.MaxSize
+	default:
+		min, max = dir.Size-dir.Threshold, dir.Size+dir.Threshold
+	}
+
+	if size < min*dir.Scale {
+		return min*dir.Scale - size
+	}
+	if size > max*dir.Scale {
+		return size - max*dir.Scale
+	}
+
+	return 0
+}
+
+// reads the index.theme file of a theme if that wasn't done yet.
+// It gets nil if there is no such theme
+func loadTheme(name string) *IconTheme {
+	if theme, ok := themes[name]; ok {
+		return theme
+	}
+
+	var theme *IconTheme
+	for _, base := range IconDirs() {
+		dir := filepath.Join(base, name)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+
+		// the first index.theme found describes the theme
+		if theme == nil {
+			if index := readIni(filepath.Join(dir, "index.theme")); index != nil {
+				theme = parseIndexTheme(name, index)
+			}
+		}
+		if theme != nil {
+			theme.bases = append(theme.bases, base)
+		}
+	}
+
+	themes[name] = theme
+	return theme
+}
+
+// gets an icon theme from the sections of its index.theme file
+func parseIndexTheme(name string, index map[string]map[string]string) (theme *IconTheme) {
+	theme = &IconTheme{Name: name, Inherits: splitList(index["Icon Theme"]["Inherits"])}
+
+	dirs := append(splitList(index["Icon Theme"]["Directories"]), splitList(index["Icon Theme"]["ScaledDirectories"])...)
+	for _, path := range dirs {
+		section, ok := index[path]
+		if !ok {
+			continue
+		}
+
+		number := func(key string, def int) int {
+			if value, err := strconv.Atoi(section[key]); err == nil {
+				return value
+			}
+			return def
+		}
+
+		dir := IconDir{Path: path, Size: number("Size", 0), Scale: number("Scale", 1), Type: section["Type"]}
+		dir.MinSize = number("MinSize", dir.Size)
+		dir.MaxSize = number("MaxSize", dir.Size)
+		dir.Threshold = number("Threshold", 2)
+		if dir.Type == "" {
+			dir.Type = "Threshold"
+		}
+
+		theme.Dirs = append(theme.Dirs, dir)
+	}
+
+	return
+}
+
+// reads the sections of an ini file like index.theme.
+// It gets nil if the file can't be read
+func readIni(path string) (sections map[string]map[string]string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	sections = make(map[string]map[string]string)
+	section := ""
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = line[1 : len(line)-1]
+		default:
+			if i := strings.Index(line, "="); i >= 0 {
+				if sections[section] == nil {
+					sections[section] = make(map[string]string)
+				}
+				sections[section][strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+			}
+		}
+	}
+
+	return sections
+}
+
+// splits a comma separated list of an ini file
+func splitList(list string) (items []string) {
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"unsafe"
 
+	"github.com/veandco/go-sdl2/img"
 	"github.com/veandco/go-sdl2/sdl"
 
 	// is needed in order to support these file types
@@ -17,12 +18,24 @@ import (
 System icon & image tools
 */
 
+// NewSurface creates a transparent surface with an alpha channel,
+// which is blended onto the surfaces it is drawn on
+func NewSurface(w int32, h int32) (surface *sdl.Surface, err error) {
+	surface, err = sdl.CreateRGBSurfaceWithFormat(0, w, h, 32, sdl.PIXELFORMAT_ARGB8888)
+	if err != nil {
+		return
+	}
+
+	err = surface.SetBlendMode(sdl.BLENDMODE_BLEND)
+	return
+}
+
 // ImgToSurface turns an image (image.Image) into a sdl.Surface
 func ImgToSurface(img image.Image) (surface *sdl.Surface, err error) {
 	// Credit to https://github.com/veandco/go-sdl2/issues/116#issuecomment-96056082
 	rgba := image.NewRGBA(img.Bounds())
 	w, h := img.Bounds().Max.X, img.Bounds().Max.Y
-	s, err := sdl.CreateRGBSurface(0, int32(w), int32(h), 32, 0, 0, 0, 0)
+	s, err := NewSurface(int32(w), int32(h))
 	if err != nil {
 		return s, err
 	}
@@ -30,8 +43,10 @@ func ImgToSurface(img image.Image) (surface *sdl.Surface, err error) {
 
 	for y := 0; y < h; y++ {
 		for x := 0; x < w; x++ {
-			r, g, b, a := img.At(x, y).RGBA()
-			rgba.Set(x, y, color.RGBA{uint8(b), uint8(g), uint8(r), uint8(a)})
+			// the surface is not premultiplied,
+			// its bytes are in the order of ARGB8888
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			rgba.Set(x, y, color.RGBA{c.B, c.G, c.R, c.A})
 		}
 	}
 
@@ -48,13 +63,12 @@ func ResizeSurface(surf *sdl.Surface, newx int32, newy int32) (resizedsurf *sdl.
 		scale = float64(newy) / float64(surf.H)
 	}
 
-	resizedsurf, err = sdl.CreateRGBSurface(0, int32(float64(surf.W)*scale), int32(float64(surf.H)*scale), 32, 0, 0, 0, 0)
+	resizedsurf, err = NewSurface(int32(float64(surf.W)*scale), int32(float64(surf.H)*scale))
 	if err != nil {
 		return resizedsurf, err
 	}
 
-	surf.BlitScaled(&sdl.Rect{X: 0, Y: 0, W: surf.W, H: surf.H}, resizedsurf, &sdl.Rect{X: 0, Y: 0, W: resizedsurf.W, H: resizedsurf.H})
-
+	ScaleRect(surf, sdl.Rect{X: 0, Y: 0, W: surf.W, H: surf.H}, resizedsurf, sdl.Rect{X: 0, Y: 0, W: resizedsurf.W, H: resizedsurf.H})
 	return
 }
 
@@ -71,8 +85,40 @@ func FromFile(path string) (img image.Image, err error) {
 	return img, err
 }
 
+// SurfaceFromFile loads an image with SDL_image,
+// which can read formats go can't, like xpm
+func SurfaceFromFile(path string) (surface *sdl.Surface, err error) {
+	loaded, err := img.Load(path)
+	if err != nil {
+		return
+	}
+
+	return convertSurface(loaded)
+}
+
+// converts a surface loaded by SDL_image to the format of NewSurface
+// and frees it
+func convertSurface(loaded *sdl.Surface) (surface *sdl.Surface, err error) {
+	defer loaded.Free()
+
+	surface, err = loaded.ConvertFormat(sdl.PIXELFORMAT_ARGB8888, 0)
+	if err != nil {
+		return
+	}
+
+	err = surface.SetBlendMode(sdl.BLENDMODE_BLEND)
+	return
+}
+
 // UInt32ToColor turns an uint32 to a sdl.Color
 func UInt32ToColor(ui uint32) (color sdl.Color) {
 	bytes := (*[4]byte)(unsafe.Pointer(&ui))[:]
 	return sdl.Color{R: bytes[0], G: bytes[1], B: bytes[2], A: bytes[3]}
 }
+
+// ColorToUInt32 turns a sdl.Color to an uint32, the reverse of UInt32ToColor
+func ColorToUInt32(color sdl.Color) (ui uint32) {
+	bytes := (*[4]byte)(unsafe.Pointer(&ui))[:]
+	bytes[0], bytes[1], bytes[2], bytes[3] = color.R, color.G, color.B, color.A
+	return
+}
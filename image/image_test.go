@@ -1,7 +1,19 @@
 package image
 
 import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
 )
 
 func TestUInt32ToColor(t *testing.T) {
@@ -11,3 +23,232 @@ func TestUInt32ToColor(t *testing.T) {
 		t.Errorf("Expected 0x%x, gave 0x%x", expected, result)
 	}
 }
+
+func TestColorToUInt32(t *testing.T) {
+	in := uint32(0x12345678)
+	if result := ColorToUInt32(UInt32ToColor(in)); result != in {
+		t.Errorf("Expected 0x%x, gave 0x%x", in, result)
+	}
+}
+
+func TestResample(t *testing.T) {
+	// a transparent and a red pixel next to each other
+	src := []byte{0, 0, 0, 0, 0, 0, 255, 255}
+
+	// scaled up, the red doesn't blend with the color of the transparent pixel
+	dst := make([]byte, 4*4)
+	resample(src, 8, sdl.Rect{X: 0, Y: 0, W: 2, H: 1}, dst, 16, sdl.Rect{X: 0, Y: 0, W: 4, H: 1}, sdl.Rect{X: 0, Y: 0, W: 4, H: 1})
+	expected := []byte{0, 0, 0, 0, 0, 0, 255, 64, 0, 0, 255, 191, 0, 0, 255, 255}
+	if !bytes.Equal(dst, expected) {
+		t.Error("Expected ", expected, ", gave ", dst)
+	}
+
+	// scaled down, the pixels are averaged
+	dst = make([]byte, 4)
+	resample(src, 8, sdl.Rect{X: 0, Y: 0, W: 2, H: 1}, dst, 4, sdl.Rect{X: 0, Y: 0, W: 1, H: 1}, sdl.Rect{X: 0, Y: 0, W: 1, H: 1})
+	if expected := []byte{0, 0, 255, 128}; !bytes.Equal(dst, expected) {
+		t.Error("Expected ", expected, ", gave ", dst)
+	}
+
+	// pixels outside of the clip rectangle are kept
+	dst = []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	resample(src, 8, sdl.Rect{X: 1, Y: 0, W: 1, H: 1}, dst, 8, sdl.Rect{X: 0, Y: 0, W: 2, H: 1}, sdl.Rect{X: 1, Y: 0, W: 1, H: 1})
+	if expected := []byte{1, 2, 3, 4, 0, 0, 255, 255}; !bytes.Equal(dst, expected) {
+		t.Error("Expected ", expected, ", gave ", dst)
+	}
+}
+
+func TestSVGFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fliw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := map[string][2]float64{
+		`<svg xmlns="http://www.w3.org/2000/svg" width="24px" height="16"/>`: {24, 16},
+		`<?xml version="1.0"?><svg viewBox="0,0 48 32" width="100%"/>`:       {48, 32},
+		`<svg/>`: {defaultSVGWidth, defaultSVGHeight},
+	}
+
+	for file, expected := range tests {
+		path := filepath.Join(dir, "icon.svg")
+		if err := ioutil.WriteFile(path, []byte(file), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		svg, err := SVGFromFile(path)
+		if err != nil || svg.W != expected[0] || svg.H != expected[1] {
+			t.Error("Expected ", expected, " for ", file, ", gave ", svg, err)
+		}
+	}
+
+	if !IsSVG("/usr/share/icons/a.SVG") || IsSVG("a.svg.png") {
+		t.Error("Expected only paths ending with .svg to be svg images")
+	}
+}
+
+func TestLookupIcon(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fliw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"child/index.theme": "[Icon Theme]\nInherits=parent\nDirectories=16,32\n\n" +
+			"[16]\nSize=16\nType=Fixed\n\n[32]\nSize=32\nType=Fixed\n",
+		"child/16/fliw-test-a.png":        "",
+		"child/32/fliw-test-a.png":        "",
+		"parent/index.theme":              "[Icon Theme]\nDirectories=scalable\n\n[scalable]\nSize=48\nMinSize=8\nMaxSize=512\nType=Scalable\n",
+		"parent/scalable/fliw-test-b.svg": "",
+		"fliw-test-c.xpm":                 "",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	AddIconDir(dir)
+
+	tests := map[string]string{
+		"fliw-test-a": "child/32/fliw-test-a.png",
+		"fliw-test-b": "parent/scalable/fliw-test-b.svg",
+		"fliw-test-c": "fliw-test-c.xpm",
+	}
+	for name, expected := range tests {
+		if path, err := LookupIcon(name, "child", 28, 1); err != nil || path != filepath.Join(dir, expected) {
+			t.Error("Expected ", expected, ", gave ", path, err)
+		}
+	}
+
+	if _, err := LookupIcon("fliw-test-missing", "child", 16, 1); err == nil {
+		t.Error("Expected an error for a missing icon")
+	}
+
+	// missing icons are not searched for again until the icons are forgotten
+	if err := ioutil.WriteFile(filepath.Join(dir, "fliw-test-missing.png"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LookupIcon("fliw-test-missing", "child", 16, 1); err == nil {
+		t.Error("Expected the missing icon to be remembered")
+	}
+	ForgetIcons()
+	if _, err := LookupIcon("fliw-test-missing", "child", 16, 1); err != nil {
+		t.Error("Expected the icon to be found after forgetting the icons, gave ", err)
+	}
+}
+
+func TestDecodeGIF(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.NRGBA{R: 255, A: 255}, color.NRGBA{B: 255, A: 255}}
+
+	// a red frame and a blue pixel drawn over it
+	first := image.NewPaletted(image.Rect(0, 0, 2, 1), palette)
+	first.SetColorIndex(0, 0, 1)
+	first.SetColorIndex(1, 0, 1)
+	second := image.NewPaletted(image.Rect(1, 0, 2, 1), palette)
+	second.SetColorIndex(1, 0, 2)
+
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, &gif.GIF{
+		Image:    []*image.Paletted{first, second},
+		Delay:    []int{50, 0},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalNone},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frames, delays, err := decodeGIF(buf.Bytes())
+	if err != nil || len(frames) != 2 {
+		t.Fatal("Expected 2 frames, gave ", len(frames), err)
+	}
+
+	if c := color.NRGBAModel.Convert(frames[1].At(0, 0)); c != (color.NRGBA{R: 255, A: 255}) {
+		t.Error("Expected the first frame to stay below the second, gave ", c)
+	}
+	if c := color.NRGBAModel.Convert(frames[1].At(1, 0)); c != (color.NRGBA{B: 255, A: 255}) {
+		t.Error("Expected the second frame to be drawn over the first, gave ", c)
+	}
+
+	// frames without delay are shown for the default delay
+	if delays[0] != 500*time.Millisecond || delays[1] != defaultDelay {
+		t.Error("Expected delays of 500ms and ", defaultDelay, ", gave ", delays)
+	}
+}
+
+func TestDecodeAPNG(t *testing.T) {
+	// a png, with its image data used as both frames
+	var buf bytes.Buffer
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, color.NRGBA{G: 255, A: 255})
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := readChunks(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	control := func(sequence uint32, delay uint16) []byte {
+		data := make([]byte, 26)
+		binary.BigEndian.PutUint32(data[0:], sequence)
+		binary.BigEndian.PutUint32(data[4:], 1)
+		binary.BigEndian.PutUint32(data[8:], 1)
+		binary.BigEndian.PutUint16(data[20:], delay)
+		binary.BigEndian.PutUint16(data[22:], 1000)
+		return data
+	}
+
+	var apng bytes.Buffer
+	apng.Write(pngSignature)
+	for _, chunk := range chunks {
+		switch chunk.kind {
+		case "IDAT":
+			writeChunk(&apng, "acTL", make([]byte, 8))
+			writeChunk(&apng, "fcTL", control(0, 40))
+			writeChunk(&apng, "IDAT", chunk.data)
+			writeChunk(&apng, "fcTL", control(1, 60))
+			writeChunk(&apng, "fdAT", append([]byte{0, 0, 0, 2}, chunk.data...))
+		default:
+			writeChunk(&apng, chunk.kind, chunk.data)
+		}
+	}
+
+	if !isAPNG(apng.Bytes()) || isAPNG(buf.Bytes()) {
+		t.Error("Expected only the animated png to be an apng")
+	}
+
+	frames, delays, err := decodeAPNG(apng.Bytes())
+	if err != nil || len(frames) != 2 {
+		t.Fatal("Expected 2 frames, gave ", len(frames), err)
+	}
+	if c := color.NRGBAModel.Convert(frames[1].At(0, 0)); c != (color.NRGBA{G: 255, A: 255}) {
+		t.Error("Expected green, gave ", c)
+	}
+	if delays[0] != 40*time.Millisecond || delays[1] != 60*time.Millisecond {
+		t.Error("Expected delays of 40ms and 60ms, gave ", delays)
+	}
+}
+
+func TestFrameAt(t *testing.T) {
+	anim := &Animation{Frames: make([]*sdl.Surface, 3), Delays: []time.Duration{100, 200, 100}}
+
+	tests := []struct {
+		elapsed  time.Duration
+		loop     bool
+		expected int
+	}{
+		{50, true, 0},
+		{250, true, 1},
+		{450, true, 0},
+		{450, false, 2},
+	}
+	for _, test := range tests {
+		if frame := anim.FrameAt(test.elapsed, test.loop); frame != test.expected {
+			t.Error("Expected frame ", test.expected, " at ", test.elapsed, ", gave ", frame)
+		}
+	}
+}
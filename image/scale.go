@@ -0,0 +1,150 @@
+package image
+
+import (
+	"math"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/*
+Scaling of surfaces
+*/
+
+// ScaleSurface creates a copy of a surface scaled to w × h
+func ScaleSurface(surf *sdl.Surface, w int32, h int32) (scaled *sdl.Surface, err error) {
+	scaled, err = NewSurface(w, h)
+	if err != nil {
+		return
+	}
+
+	ScaleRect(surf, sdl.Rect{X: 0, Y: 0, W: surf.W, H: surf.H}, scaled, sdl.Rect{X: 0, Y: 0, W: w, H: h})
+	return scaled, nil
+}
+
+// ScaleRect copies the area srcrect of src into the area dstrect of dst,
+// scaling it to fit. Pixels are interpolated when scaling up and averaged when
+// scaling down, which looks smoother than sdl.Surface.BlitScaled.
+// Only pixels inside of the clip rectangle of dst are changed.
+// Both surfaces have to be in the format of NewSurface
+func ScaleRect(src *sdl.Surface, srcrect sdl.Rect, dst *sdl.Surface, dstrect sdl.Rect) {
+	if src.MustLock() {
+		src.Lock()
+		defer src.Unlock()
+	}
+	if dst.MustLock() {
+		dst.Lock()
+		defer dst.Unlock()
+	}
+
+	srcpixels, dstpixels := src.Pixels(), dst.Pixels()
+	if srcpixels == nil || dstpixels == nil {
+		return
+	}
+
+	// only the part of the source inside of the surface can be read
+	srcrect = intersect(srcrect, sdl.Rect{X: 0, Y: 0, W: src.W, H: src.H})
+	clip := intersect(dst.ClipRect(), sdl.Rect{X: 0, Y: 0, W: dst.W, H: dst.H})
+
+	resample(srcpixels, src.Pitch, srcrect, dstpixels, dst.Pitch, dstrect, clip)
+}
+
+// tap is a pixel of the source a pixel of the destination is made of
+type tap struct {
+	index  int32
+	weight float64
+}
+
+// gets for each of length pixels of the destination which of srclength
+// pixels of the source it is made of and by how much.
+func taps(srclength int32, length int32) (result [][]tap) {
+	result = make([][]tap, length)
+	scale := float64(srclength) / float64(length)
+
+	for i := range result {
+		if scale > 1 {
+			// scaling down, average all pixels covered by the pixel
+			start, end := float64(i)*scale, float64(i+1)*scale
+			for j := int32(start); float64(j) < end && j < srclength; j++ {
+				covered := math.Min(end, float64(j+1)) - math.Max(start, float64(j))
+				result[i] = append(result[i], tap{j, covered / scale})
+			}
+			continue
+		}
+
+		// scaling up, interpolate between the two closest pixels
+		pos := math.Max(0, (float64(i)+0.5)*scale-0.5)
+		first := int32(pos)
+		share := pos - float64(first)
+		if first+1 >= srclength {
+			first, share = srclength-1, 0
+		}
+
+		result[i] = append(result[i], tap{first, 1 - share})
+		if share > 0 {
+			result[i] = append(result[i], tap{first + 1, share})
+		}
+	}
+
+	return
+}
+
+// scales the pixels of srcrect into dstrect, changing only the pixels inside of clip.
+// The pixels are 4 bytes in the order of ARGB8888 and not premultiplied,
+// so they are weighted by their alpha
+func resample(src []byte, srcpitch int32, srcrect sdl.Rect, dst []byte, dstpitch int32, dstrect sdl.Rect, clip sdl.Rect) {
+	if srcrect.W <= 0 || srcrect.H <= 0 || dstrect.W <= 0 || dstrect.H <= 0 {
+		return
+	}
+
+	xtaps := taps(srcrect.W, dstrect.W)
+	ytaps := taps(srcrect.H, dstrect.H)
+
+	for y := max32(dstrect.Y, clip.Y); y < min32(dstrect.Y+dstrect.H, clip.Y+clip.H); y++ {
+		for x := max32(dstrect.X, clip.X); x < min32(dstrect.X+dstrect.W, clip.X+clip.W); x++ {
+			var b, g, r, a float64
+
+			for _, ytap := range ytaps[y-dstrect.Y] {
+				for _, xtap := range xtaps[x-dstrect.X] {
+					i := (srcrect.Y+ytap.index)*srcpitch + (srcrect.X+xtap.index)*4
+					alpha := float64(src[i+3]) * xtap.weight * ytap.weight
+
+					b += float64(src[i]) * alpha
+					g += float64(src[i+1]) * alpha
+					r += float64(src[i+2]) * alpha
+					a += alpha
+				}
+			}
+
+			i := y*dstpitch + x*4
+			if a <= 0 {
+				dst[i], dst[i+1], dst[i+2], dst[i+3] = 0, 0, 0, 0
+				continue
+			}
+
+			dst[i] = uint8(math.Round(b / a))
+			dst[i+1] = uint8(math.Round(g / a))
+			dst[i+2] = uint8(math.Round(r / a))
+			dst[i+3] = uint8(math.Round(math.Min(255, a)))
+		}
+	}
+}
+
+// gets the area two rectangles have in common
+func intersect(a sdl.Rect, b sdl.Rect) sdl.Rect {
+	x, y := max32(a.X, b.X), max32(a.Y, b.Y)
+	return sdl.Rect{X: x, Y: y, W: max32(0, min32(a.X+a.W, b.X+b.W)-x), H: max32(0, min32(a.Y+a.H, b.Y+b.H)-y)}
+}
+
+func min32(a int32, b int32) int32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a int32, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,168 @@
+package image
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/veandco/go-sdl2/img"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/*
+SVG images
+*/
+
+// the size of svg images that don't give one, like in browsers
+const defaultSVGWidth, defaultSVGHeight = 300, 150
+
+// SVG is a vector image, which can be rasterized at any size
+type SVG struct {
+	// the size given in the file
+	W float64
+	H float64
+
+	file []byte
+
+	// the position of the root element in the file and its attributes
+	start int64
+	end   int64
+	attrs []xml.Attr
+}
+
+// IsSVG tells wether a path is the path of an svg image
+func IsSVG(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".svg")
+}
+
+// SVGFromFile loads an svg image from an existing file
+func SVGFromFile(path string) (svg *SVG, err error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	svg = &SVG{file: file}
+
+	// find the root element
+	decoder := xml.NewDecoder(bytes.NewReader(file))
+	for {
+		svg.start = decoder.InputOffset()
+		token, err := decoder.RawToken()
+		if err != nil {
+			return nil, errors.New("No svg element in " + path)
+		}
+
+		if element, ok := token.(xml.StartElement); ok {
+			if element.Name.Local != "svg" {
+				return nil, errors.New("No svg element in " + path)
+			}
+
+			svg.end = decoder.InputOffset()
+			svg.attrs = element.Attr
+			break
+		}
+	}
+
+	// the size is given by width and height,
+	// or by the viewBox if they are missing
+	viewbox := strings.Fields(strings.Replace(svg.attr("viewBox"), ",", " ", -1))
+	if len(viewbox) == 4 {
+		svg.W, _ = strconv.ParseFloat(viewbox[2], 64)
+		svg.H, _ = strconv.ParseFloat(viewbox[3], 64)
+	}
+	if w, ok := parseLength(svg.attr("width")); ok {
+		svg.W = w
+	}
+	if h, ok := parseLength(svg.attr("height")); ok {
+		svg.H = h
+	}
+
+	if svg.W <= 0 || svg.H <= 0 {
+		svg.W, svg.H = defaultSVGWidth, defaultSVGHeight
+	}
+
+	return svg, nil
+}
+
+// Rasterize draws the image onto a new surface of size w × h,
+// stretching it if the size has another aspect ratio
+func (svg *SVG) Rasterize(w int32, h int32) (surface *sdl.Surface, err error) {
+	if w <= 0 || h <= 0 {
+		return nil, errors.New("Can't rasterize an svg image to an empty size")
+	}
+
+	// replace the size of the root element, so it is drawn at w × h.
+	// The viewBox keeps the content at its place
+	var root strings.Builder
+	root.WriteString("<svg")
+	for _, attr := range svg.attrs {
+		switch attr.Name.Local {
+		case "width", "height", "viewBox", "preserveAspectRatio":
+			if attr.Name.Space == "" {
+				continue
+			}
+		}
+
+		name := attr.Name.Local
+		if attr.Name.Space != "" {
+			name = attr.Name.Space + ":" + name
+		}
+		root.WriteString(" " + name + "=\"")
+		xml.EscapeText(&root, []byte(attr.Value))
+		root.WriteString("\"")
+	}
+
+	viewbox := svg.attr("viewBox")
+	if viewbox == "" {
+		viewbox = "0 0 " + formatFloat(svg.W) + " " + formatFloat(svg.H)
+	}
+	root.WriteString(" width=\"" + strconv.Itoa(int(w)) + "\" height=\"" + strconv.Itoa(int(h)) +
+		"\" viewBox=\"" + viewbox + "\" preserveAspectRatio=\"none\">")
+
+	file := make([]byte, 0, len(svg.file)+root.Len())
+	file = append(file, svg.file[:svg.start]...)
+	file = append(file, root.String()...)
+	file = append(file, svg.file[svg.end:]...)
+
+	rw, err := sdl.RWFromMem(file)
+	if err != nil {
+		return
+	}
+
+	loaded, err := img.LoadTypedRW(rw, true, "SVG")
+	if err != nil {
+		return
+	}
+
+	return convertSurface(loaded)
+}
+
+// gets the value of an attribute of the root element
+func (svg *SVG) attr(name string) string {
+	for _, attr := range svg.attrs {
+		if attr.Name.Space == "" && attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+
+	return ""
+}
+
+// parses an absolute svg length in pixels, like "24" or "24px".
+// Relative lengths like "100%" are not ok
+func parseLength(length string) (result float64, ok bool) {
+	length = strings.TrimSuffix(strings.TrimSpace(length), "px")
+
+	result, err := strconv.ParseFloat(length, 64)
+	return result, err == nil && result > 0 && !math.IsInf(result, 0)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
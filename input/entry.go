@@ -0,0 +1,339 @@
+package input
+
+import (
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/*
+text typed into entries
+*/
+
+// Entry is the text of an entry item being edited
+type Entry struct {
+	Text []rune
+
+	// the index of the rune the cursor is in front of
+	Cursor int
+
+	// the other end of the selection, the same as Cursor if nothing is selected
+	Anchor int
+
+	// the text of password entries can't be copied
+	Password bool
+
+	// called with the text after the user changed it and when enter is pressed
+	OnChange func(text string)
+	OnSubmit func(text string)
+}
+
+// the entries by the uid of their item
+var entries = make(map[uint]*Entry)
+
+// the uid of the entry typed into, if hasFocus is set
+var focused uint
+var hasFocus bool
+
+// GetEntry gets the entry of the item with uid,
+// created is set if the entry didn't exist before
+func GetEntry(uid uint) (entry *Entry, created bool) {
+	if entry, ok := entries[uid]; ok {
+		return entry, false
+	}
+
+	entry = &Entry{}
+	entries[uid] = entry
+	return entry, true
+}
+
+// ResetEntries removes all entries, e.g. after the uids of the items changed
+func ResetEntries() {
+	entries = make(map[uint]*Entry)
+	Unfocus()
+}
+
+// KeepEntries removes the entries of all items but the ones with the
+// uids in keep, e.g. after the other items were removed.
+// If the entry typed into is removed, no entry gets the text typed
+func KeepEntries(keep map[uint]bool) {
+	for uid := range entries {
+		if !keep[uid] {
+			delete(entries, uid)
+		}
+	}
+
+	if hasFocus && !keep[focused] {
+		Unfocus()
+	}
+}
+
+// Focus lets the text typed go to the entry of the item with uid
+func Focus(uid uint) {
+	if !hasFocus {
+		sdl.StartTextInput()
+	}
+
+	focused, hasFocus = uid, true
+}
+
+// Unfocus lets no entry get the text typed
+func Unfocus() {
+	if hasFocus {
+		sdl.StopTextInput()
+	}
+
+	hasFocus = false
+}
+
+// IsFocused tells wether the entry of the item with uid gets the text typed
+func IsFocused(uid uint) bool {
+	return hasFocus && focused == uid
+}
+
+// FocusedEntry gets the entry the text typed goes to, nil if there is none
+func FocusedEntry() *Entry {
+	if !hasFocus {
+		return nil
+	}
+
+	entry, _ := GetEntry(focused)
+	return entry
+}
+
+// String gets the text of the entry
+func (entry *Entry) String() string {
+	return string(entry.Text)
+}
+
+// SetText replaces the text, the cursor is put at its end
+func (entry *Entry) SetText(text string) {
+	entry.Text = []rune(singleLine(text))
+	entry.Cursor = len(entry.Text)
+	entry.Anchor = entry.Cursor
+}
+
+// Selection gets the start and the end of the selected text
+func (entry *Entry) Selection() (start int, end int) {
+	if entry.Anchor < entry.Cursor {
+		return entry.Anchor, entry.Cursor
+	}
+	return entry.Cursor, entry.Anchor
+}
+
+// SelectedText gets the text that is selected
+func (entry *Entry) SelectedText() string {
+	start, end := entry.Selection()
+	return string(entry.Text[start:end])
+}
+
+// MoveTo puts the cursor in front of the rune at index.
+// If selecting is set, the text the cursor moved over is selected
+func (entry *Entry) MoveTo(index int, selecting bool) {
+	if index < 0 {
+		index = 0
+	}
+	if index > len(entry.Text) {
+		index = len(entry.Text)
+	}
+
+	entry.Cursor = index
+	if !selecting {
+		entry.Anchor = index
+	}
+}
+
+// SelectAll selects the whole text
+func (entry *Entry) SelectAll() {
+	entry.Anchor, entry.Cursor = 0, len(entry.Text)
+}
+
+// Insert replaces the selected text with text, like typing it
+func (entry *Entry) Insert(text string) {
+	inserted := []rune(singleLine(text))
+	start, end := entry.Selection()
+
+	result := make([]rune, 0, len(entry.Text)-(end-start)+len(inserted))
+	result = append(result, entry.Text[:start]...)
+	result = append(result, inserted...)
+	entry.Text = append(result, entry.Text[end:]...)
+
+	entry.MoveTo(start+len(inserted), false)
+}
+
+// Backspace removes the selected text or the rune (or word) in front of the cursor
+func (entry *Entry) Backspace(word bool) {
+	if entry.Anchor == entry.Cursor {
+		if word {
+			entry.MoveTo(previousWord(entry.Text, entry.Cursor), true)
+		} else {
+			entry.MoveTo(entry.Cursor-1, true)
+		}
+	}
+
+	entry.Insert("")
+}
+
+// Delete removes the selected text or the rune (or word) behind the cursor
+func (entry *Entry) Delete(word bool) {
+	if entry.Anchor == entry.Cursor {
+		if word {
+			entry.MoveTo(nextWord(entry.Text, entry.Cursor), true)
+		} else {
+			entry.MoveTo(entry.Cursor+1, true)
+		}
+	}
+
+	entry.Insert("")
+}
+
+// Copy puts the selected text into the clipboard.
+// Nothing is copied out of password entries
+func (entry *Entry) Copy() {
+	if entry.Password || entry.Anchor == entry.Cursor {
+		return
+	}
+
+	if err := sdl.SetClipboardText(entry.SelectedText()); err != nil {
+		log.Println("Could not copy text:", err)
+	}
+}
+
+// Paste replaces the selected text with the text of the clipboard
+func (entry *Entry) Paste() {
+	text, err := sdl.GetClipboardText()
+	if err != nil {
+		log.Println("Could not paste text:", err)
+		return
+	}
+
+	entry.Insert(text)
+}
+
+// Type inserts text typed by the user and reports the change
+func (entry *Entry) Type(text string) {
+	if singleLine(text) == "" {
+		return
+	}
+
+	entry.Insert(text)
+	entry.changed()
+}
+
+// HandleKey edits the entry like text fields do when a key is pressed:
+// moving the cursor with the arrow keys, home and end (by words while ctrl
+// is held, selecting while shift is held), deleting with backspace and delete,
+// ctrl+a, ctrl+c, ctrl+x and ctrl+v to select, copy, cut and paste and enter to submit.
+// Text is typed by Type, as the characters of a key depend on the keyboard layout
+func (entry *Entry) HandleKey(key sdl.Keycode, mod sdl.Keymod) {
+	ctrl := mod&sdl.KMOD_CTRL != 0
+	shift := mod&sdl.KMOD_SHIFT != 0
+	before := entry.String()
+
+	switch key {
+	case sdl.K_LEFT:
+		if entry.Anchor != entry.Cursor && !shift {
+			start, _ := entry.Selection()
+			entry.MoveTo(start, false)
+		} else if ctrl {
+			entry.MoveTo(previousWord(entry.Text, entry.Cursor), shift)
+		} else {
+			entry.MoveTo(entry.Cursor-1, shift)
+		}
+	case sdl.K_RIGHT:
+		if entry.Anchor != entry.Cursor && !shift {
+			_, end := entry.Selection()
+			entry.MoveTo(end, false)
+		} else if ctrl {
+			entry.MoveTo(nextWord(entry.Text, entry.Cursor), shift)
+		} else {
+			entry.MoveTo(entry.Cursor+1, shift)
+		}
+	case sdl.K_HOME, sdl.K_UP:
+		entry.MoveTo(0, shift)
+	case sdl.K_END, sdl.K_DOWN:
+		entry.MoveTo(len(entry.Text), shift)
+	case sdl.K_BACKSPACE:
+		entry.Backspace(ctrl)
+	case sdl.K_DELETE:
+		entry.Delete(ctrl)
+	case sdl.K_RETURN, sdl.K_KP_ENTER:
+		if entry.OnSubmit != nil {
+			entry.OnSubmit(entry.String())
+		}
+	case sdl.K_ESCAPE:
+		Unfocus()
+	case sdl.K_a:
+		if ctrl {
+			entry.SelectAll()
+		}
+	case sdl.K_c:
+		if ctrl {
+			entry.Copy()
+		}
+	case sdl.K_x:
+		if ctrl && !entry.Password {
+			entry.Copy()
+			entry.Insert("")
+		}
+	case sdl.K_v:
+		if ctrl {
+			entry.Paste()
+		}
+	}
+
+	if entry.String() != before {
+		entry.changed()
+	}
+}
+
+// reports that the user changed the text
+func (entry *Entry) changed() {
+	if entry.OnChange != nil {
+		entry.OnChange(entry.String())
+	}
+}
+
+// gets the index of the start of the word in front of index
+func previousWord(text []rune, index int) int {
+	for index > 0 && !isWordRune(text[index-1]) {
+		index--
+	}
+	for index > 0 && isWordRune(text[index-1]) {
+		index--
+	}
+
+	return index
+}
+
+// gets the index of the end of the word behind index
+func nextWord(text []rune, index int) int {
+	for index < len(text) && !isWordRune(text[index]) {
+		index++
+	}
+	for index < len(text) && isWordRune(text[index]) {
+		index++
+	}
+
+	return index
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// removes line breaks and other control characters,
+// as entries only have a single line
+func singleLine(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return ' '
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
@@ -0,0 +1,94 @@
+package input
+
+import (
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+func TestEntryEditing(t *testing.T) {
+	entry := &Entry{}
+	entry.SetText("hello world")
+
+	entry.HandleKey(sdl.K_LEFT, sdl.KMOD_LCTRL)
+	entry.Type("big\n")
+	if entry.String() != "hello big world" || entry.Cursor != 10 {
+		t.Error("Expected 'hello big world' at 10, gave ", entry.String(), " at ", entry.Cursor)
+	}
+
+	// select the word in front of the cursor and replace it
+	entry.HandleKey(sdl.K_LEFT, sdl.KMOD_LCTRL|sdl.KMOD_LSHIFT)
+	if selected := entry.SelectedText(); selected != "big " {
+		t.Error("Expected 'big ' to be selected, gave ", selected)
+	}
+	entry.Type("wide ")
+	if entry.String() != "hello wide world" {
+		t.Error("Expected 'hello wide world', gave ", entry.String())
+	}
+
+	entry.HandleKey(sdl.K_BACKSPACE, sdl.KMOD_LCTRL)
+	entry.HandleKey(sdl.K_DELETE, sdl.KMOD_NONE)
+	if entry.String() != "hello orld" || entry.Cursor != 6 {
+		t.Error("Expected 'hello orld' at 6, gave ", entry.String(), " at ", entry.Cursor)
+	}
+
+	entry.HandleKey(sdl.K_END, sdl.KMOD_NONE)
+	entry.HandleKey(sdl.K_BACKSPACE, sdl.KMOD_NONE)
+	if entry.String() != "hello orl" {
+		t.Error("Expected 'hello orl', gave ", entry.String())
+	}
+}
+
+func TestEntryUnicode(t *testing.T) {
+	entry := &Entry{}
+	entry.Type("grüße 日本")
+
+	entry.HandleKey(sdl.K_BACKSPACE, sdl.KMOD_NONE)
+	entry.HandleKey(sdl.K_HOME, sdl.KMOD_NONE)
+	entry.HandleKey(sdl.K_RIGHT, sdl.KMOD_LCTRL)
+	if entry.String() != "grüße 日" || entry.Cursor != 5 {
+		t.Error("Expected 'grüße 日' at 5, gave ", entry.String(), " at ", entry.Cursor)
+	}
+}
+
+func TestEntryCallbacks(t *testing.T) {
+	var changed, submitted []string
+	entry := &Entry{
+		OnChange: func(text string) { changed = append(changed, text) },
+		OnSubmit: func(text string) { submitted = append(submitted, text) },
+	}
+
+	entry.Type("ab")
+	entry.HandleKey(sdl.K_LEFT, sdl.KMOD_NONE)
+	entry.HandleKey(sdl.K_BACKSPACE, sdl.KMOD_NONE)
+	entry.HandleKey(sdl.K_RETURN, sdl.KMOD_NONE)
+
+	// moving the cursor doesn't change the text
+	if len(changed) != 2 || changed[0] != "ab" || changed[1] != "b" {
+		t.Error("Expected changes to 'ab' and 'b', gave ", changed)
+	}
+	if len(submitted) != 1 || submitted[0] != "b" {
+		t.Error("Expected 'b' to be submitted, gave ", submitted)
+	}
+}
+
+func TestKeepEntries(t *testing.T) {
+	ResetEntries()
+	defer ResetEntries()
+
+	GetEntry(1)
+	GetEntry(2)
+	Focus(2)
+
+	// the focus goes away with the entry
+	KeepEntries(map[uint]bool{1: true})
+	if _, created := GetEntry(1); created {
+		t.Error("Expected entry 1 to be kept")
+	}
+	if FocusedEntry() != nil || IsFocused(2) {
+		t.Error("Expected no entry to be focused")
+	}
+	if _, created := GetEntry(2); !created {
+		t.Error("Expected entry 2 to be removed")
+	}
+}
@@ -3,10 +3,14 @@ package launcher
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/phoenixdevelops/fliw/backend"
 	"github.com/phoenixdevelops/fliw/data"
+	"github.com/phoenixdevelops/fliw/image"
+	"github.com/phoenixdevelops/fliw/input"
 	"github.com/phoenixdevelops/fliw/parser"
+	"github.com/phoenixdevelops/fliw/transition"
 	"github.com/veandco/go-sdl2/sdl"
 )
 
@@ -16,72 +20,62 @@ import (
 ##############################################################
 */
 
-var plugin *backend.Plugin
+// Options holds settings on how to show a window
+type Options struct {
+	// Display is the index of the display the window is shown on
+	Display int
+
+	// Watch enables reloading the window when one
+	// of its files changes
+	Watch bool
+
+	// Backend is the path of the backend of the window.
+	// Defaults to app.so in the module directory.
+	// See backend.OpenBackend for the kinds of backends.
+	Backend string
+}
 
 // ShowWindow Shows a window given the path to the config files
-func ShowWindow(path string) (err error) {
+func ShowWindow(path string, options Options) (err error) {
 	// process the files
 	// these steps are fatal:
 	// if an error occurs, the program can't continue
 
-	backend.AddPlugin(path + "/app.so")
+	backendpath := options.Backend
+	if backendpath == "" {
+		backendpath = path + "/app.so"
+	}
 
-	window, windowtype, err := parser.UnmarshalXMLFile(path)
+	err = backend.AddPlugin(backendpath)
 	if err != nil {
 		return
 	}
+	parser.SetBackend(backendpath)
 
-	// Get a function for initializing, updating and eventhandling
-
-	var initialize func(*data.BaseContainer, *bool)
-	var update func()
-	var handleevent func(sdl.Event)
-	var ok bool
-
-	plug := backend.GetPlugin(path + "/app.so").GetPlugin()
-
-	symInitializer, err := plug.Lookup("Init")
+	window, windowtype, err := parser.UnmarshalXMLFile(path, options.Display)
 	if err != nil {
-		log.Println(err)
-	} else {
-		initialize, ok = symInitializer.(func(*data.BaseContainer, *bool))
-		if !ok {
-			log.Fatal("Could not cast Init() function")
-		}
+		return
 	}
 
-	symUpdater, err := plug.Lookup("Update")
+	running := true
+	parsed, err := window.Parse()
 	if err != nil {
-		log.Println(err)
-	} else {
-		update, ok = symUpdater.(func())
-		if !ok {
-			log.Fatal("Could not cast Update() function")
-		}
+		return
 	}
+	cont := parsed.(*data.BaseContainer)
 
-	symHandler, err := plug.Lookup("HandleEvent")
+	plug, err := backend.GetBackend(backendpath)
 	if err != nil {
-		log.Println(err)
-	} else {
-		handleevent, ok = symHandler.(func(sdl.Event))
-		if !ok {
-			log.Fatal("Could not cast HandleEvent() function")
-		}
+		return
 	}
-
-	running := true
-
-	handler := normalWindowHandler{window.Parse().(*data.BaseContainer), &running, initialize, update, handleevent}
+	handler := normalWindowHandler{cont, &running, plug}
 
 	// initalize here because we didn't have the main container
 	// before
 	backend.Init(handler.GetContainer())
 
 	// create the window using the handler instance we just declared
-	createWindow(handler, &window, windowtype)
-
-	return
+	return createWindow(handler, &window, windowtype, options)
 }
 
 /*
@@ -90,6 +84,9 @@ func ShowWindow(path string) (err error) {
 ##############################################################
 */
 
+// the time a frame takes at least
+const frameTime = time.Second / 60
+
 type windowHandler interface {
 	init(*data.BaseContainer, *bool)
 	update()
@@ -97,7 +94,7 @@ type windowHandler interface {
 	GetContainer() *data.BaseContainer
 }
 
-func createWindow(handler windowHandler, xmlwindow *parser.XMLWindow, windowtype uint32) (err error) {
+func createWindow(handler windowHandler, xmlwindow *parser.XMLWindow, windowtype uint32, options Options) (err error) {
 	// This variable will will determine wether the window is running or not
 	running := true
 
@@ -109,6 +106,18 @@ func createWindow(handler windowHandler, xmlwindow *parser.XMLWindow, windowtype
 	position := cont.GetPosition()
 	size := cont.GetSize()
 
+	// the position is relative to the display
+	bounds, err := sdl.GetDisplayBounds(options.Display)
+	if err != nil {
+		return err
+	}
+	position.X += bounds.X
+	position.Y += bounds.Y
+
+	// a compositor has to blend the window with what is behind it
+	// for the transparent parts of its background to be seen through
+	sdl.SetHint(sdl.HINT_VIDEO_X11_NET_WM_BYPASS_COMPOSITOR, "0")
+
 	// create an sdl window for the window struct instance
 	window, err := sdl.CreateWindow("Sidebar", position.X, position.Y,
 		size.X, size.Y, windowtype)
@@ -123,39 +132,280 @@ func createWindow(handler windowHandler, xmlwindow *parser.XMLWindow, windowtype
 	defer window.Destroy()
 	defer sdl.Quit()
 
+	// watch the files of the window
+	var watcher *fileWatcher
+	if options.Watch {
+		watcher, err = newFileWatcher()
+		if err != nil {
+			return err
+		}
+		defer watcher.close()
+
+		err = watcher.watch(parser.GetLoadedFiles())
+		if err != nil {
+			return err
+		}
+	}
+
 	// Initialize the handler
 	handler.init(cont, &running)
 
+	// the last error reported while parsing
+	var lasterror string
+
+	// if it was reported that the window can't be transparent
+	var opaquereported bool
+
+	// only the items that changed are drawn every frame
+	renderer := data.NewRenderer()
+
 	// The main loop
 	for running {
+		frameStart := time.Now()
+
+		// everything moving over time is drawn at the time the frame started
+		transition.Tick(frameStart)
 
 		// Quit the program in case of exit event
 		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-			switch event.(type) {
+			switch t := event.(type) {
 			case *sdl.QuitEvent:
 				fmt.Println("Exit signal received. Quitting...")
 				running = false
 				break
+			case *sdl.WindowEvent:
+				// the content of the window surface may be lost
+				if t.Event == sdl.WINDOWEVENT_EXPOSED || t.Event == sdl.WINDOWEVENT_SIZE_CHANGED {
+					surface, err = window.GetSurface()
+					if err != nil {
+						return err
+					}
+					renderer.Invalidate()
+				}
+				handler.handleEvent(event)
+			case *sdl.MouseWheelEvent:
+				scrollWheel(cont, t)
+				handler.handleEvent(event)
+			case *sdl.MouseButtonEvent:
+				focusEntry(cont, t)
+				backend.InvokeSDLEvent(event)
+				handler.handleEvent(event)
+			case *sdl.TextInputEvent:
+				if entry := input.FocusedEntry(); entry != nil {
+					entry.Type(t.GetText())
+				}
+				handler.handleEvent(event)
+			case *sdl.KeyboardEvent:
+				// keys pressed go to the entry typed into instead of scrolling.
+				// The backend still gets them, so it knows which keys are held
+				if t.GetType() == sdl.KEYDOWN {
+					if entry := input.FocusedEntry(); entry != nil {
+						entry.HandleKey(t.Keysym.Sym, sdl.Keymod(t.Keysym.Mod))
+					} else {
+						scrollPage(cont, t.Keysym.Sym)
+					}
+				}
+				backend.InvokeSDLEvent(event)
+				handler.handleEvent(event)
 			default:
 				backend.InvokeSDLEvent(event)
 				handler.handleEvent(event)
 			}
 		}
 
-		// parse the container
-		// so variables and functions
-		// can update
-		cont = xmlwindow.Parse().(*data.BaseContainer)
+		// reload the window if any of its files changed
+		if watcher != nil {
+			reload(watcher, xmlwindow)
+		}
+
+		cont = parseFrame(xmlwindow, cont, &lasterror)
 
 		handler.update()
-		cont.Draw(surface)
-		window.UpdateSurface()
+
+		if !opaquereported && !canBeTransparent(surface, cont) {
+			log.Println("The window can't be transparent, its surface has no alpha channel")
+			opaquereported = true
+		}
+
+		dirty, err := renderer.Render(cont, surface)
+		if err != nil {
+			log.Println(err)
+		}
+		if len(dirty) > 0 {
+			window.UpdateSurfaceRects(dirty)
+		}
+
+		// don't draw more frames than needed
+		time.Sleep(frameTime - time.Since(frameStart))
 
 	}
 
 	return
 }
 
+// parses the container so variables and functions can update.
+// If that fails, the last container that could be parsed is kept.
+// Events go to the items of the container that is drawn
+func parseFrame(xmlwindow *parser.XMLWindow, cont *data.BaseContainer, lasterror *string) *data.BaseContainer {
+	parsed, err := xmlwindow.Parse()
+	if err != nil {
+		// only report an error once, not every frame
+		if err.Error() != *lasterror {
+			log.Println(err)
+			*lasterror = err.Error()
+		}
+
+		// the changes of the kept container were drawn already
+		clearChanged(cont)
+		return cont
+	}
+
+	*lasterror = ""
+	cont = parsed.(*data.BaseContainer)
+	backend.Init(cont)
+
+	return cont
+}
+
+// marks an item and all its children as unchanged
+func clearChanged(item data.Item) {
+	item.SetHasChanged(false)
+
+	if cont, ok := item.(data.Container); ok {
+		for _, child := range cont.GetItems() {
+			clearChanged(child)
+		}
+	}
+}
+
+// checks if the background of the window can be seen through where
+// it is transparent. The background is copied onto the window surface
+// with its alpha, which is only kept if the surface has an alpha channel.
+// That needs a compositor, without one the background is drawn opaque
+func canBeTransparent(surface *sdl.Surface, cont data.Container) bool {
+	if image.UInt32ToColor(cont.GetBGcolor()).A == 0xFF {
+		return true
+	}
+
+	return surface.Format.Amask != 0
+}
+
+// replaces the xml window if any of its files changed.
+// If the changed files are not valid, the old window is kept.
+func reload(watcher *fileWatcher, xmlwindow *parser.XMLWindow) {
+	changed, err := watcher.poll()
+	if err != nil {
+		log.Println("Watching files failed:", err)
+		return
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	window, err := parser.ReloadXMLFile(changed)
+	if err != nil {
+		log.Println("Could not reload window, keeping the previous one:", err)
+		return
+	}
+
+	*xmlwindow = window
+	log.Println("Reloaded window after changes in", changed)
+
+	// new extensions or textures may be used now
+	err = watcher.watch(parser.GetLoadedFiles())
+	if err != nil {
+		log.Println("Watching files failed:", err)
+	}
+}
+
+/*
+##############################################################
+# Section: Scrolling
+##############################################################
+*/
+
+// the distance scrolled by one step of the mouse wheel
+const scrollStep = 40
+
+// scrolls the container under the mouse by the steps of the mouse wheel
+func scrollWheel(cont data.Container, event *sdl.MouseWheelEvent) {
+	x, y, _ := sdl.GetMouseState()
+	scrollable, ok := data.ScrollableAt(cont, data.Vector{X: x, Y: y})
+	if !ok {
+		return
+	}
+
+	delta := data.Vector{X: event.X * scrollStep, Y: -event.Y * scrollStep}
+	if event.Direction == sdl.MOUSEWHEEL_FLIPPED {
+		delta = data.Vector{X: -delta.X, Y: -delta.Y}
+	}
+
+	// the vertical wheel scrolls containers that only scroll horizontally
+	if delta.X == 0 && scrollable.GetScrollMode() == data.ScrollHorizontal {
+		delta.X = delta.Y
+	}
+
+	data.Scroll(scrollable, delta)
+}
+
+// scrolls the container under the mouse by a page, if key is PageUp or PageDown
+func scrollPage(cont data.Container, key sdl.Keycode) {
+	if key != sdl.K_PAGEUP && key != sdl.K_PAGEDOWN {
+		return
+	}
+
+	x, y, _ := sdl.GetMouseState()
+	scrollable, ok := data.ScrollableAt(cont, data.Vector{X: x, Y: y})
+	if !ok {
+		return
+	}
+
+	// keep a bit of the last page visible
+	size := scrollable.GetSize()
+	delta := data.Vector{Y: size.Y * 9 / 10}
+	if scrollable.GetScrollMode() == data.ScrollHorizontal {
+		delta = data.Vector{X: size.X * 9 / 10}
+	}
+	if key == sdl.K_PAGEUP {
+		delta = data.Vector{X: -delta.X, Y: -delta.Y}
+	}
+
+	data.Scroll(scrollable, delta)
+}
+
+/*
+##############################################################
+# Section: Entries
+##############################################################
+*/
+
+// lets the text typed go to the entry clicked on, with the cursor put where
+// it was clicked (selecting up to there while shift is held).
+// Clicking anywhere else lets no entry get the text typed
+func focusEntry(cont data.Container, event *sdl.MouseButtonEvent) {
+	if event.State != sdl.PRESSED || event.Button != sdl.BUTTON_LEFT {
+		return
+	}
+
+	item, position := data.ItemAt(cont, data.Vector{X: event.X, Y: event.Y})
+	clicked, ok := item.(*data.Entry)
+	if !ok {
+		input.Unfocus()
+		return
+	}
+
+	input.Focus(clicked.GetUID())
+
+	index, err := clicked.IndexAt(position.X)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	selecting := sdl.GetModState()&sdl.KMOD_SHIFT != 0
+	input.FocusedEntry().MoveTo(index, selecting)
+}
+
 /*
 ##############################################################
 # Section: Window Handlers
@@ -169,11 +419,9 @@ func createWindow(handler windowHandler, xmlwindow *parser.XMLWindow, windowtype
 */
 
 type normalWindowHandler struct {
-	cont            *data.BaseContainer
-	running         *bool
-	initializeFunc  func(*data.BaseContainer, *bool)
-	updateFunc      func()
-	handleeventFunc func(sdl.Event)
+	cont    *data.BaseContainer
+	running *bool
+	backend backend.Backend
 }
 
 func (nwh normalWindowHandler) init(c *data.BaseContainer, r *bool) {
@@ -181,15 +429,15 @@ func (nwh normalWindowHandler) init(c *data.BaseContainer, r *bool) {
 	nwh.cont = c
 	nwh.running = r
 
-	nwh.initializeFunc(c, r)
+	nwh.backend.Init(c, r)
 }
 
 func (nwh normalWindowHandler) update() {
-	nwh.updateFunc()
+	nwh.backend.Update()
 }
 
 func (nwh normalWindowHandler) handleEvent(event sdl.Event) {
-	nwh.handleeventFunc(event)
+	nwh.backend.HandleEvent(event)
 }
 
 func (nwh normalWindowHandler) GetContainer() (cont *data.BaseContainer) {
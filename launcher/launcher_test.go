@@ -0,0 +1,41 @@
+package launcher
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/phoenixdevelops/fliw/backend"
+	"github.com/phoenixdevelops/fliw/data"
+	"github.com/phoenixdevelops/fliw/parser"
+)
+
+func TestParseFrame(t *testing.T) {
+	var called string
+	backend.RegisterBackend("/test/launcher.so", &backend.Memory{Functions: map[string]func(...string) string{
+		"First":  func(...string) string { called = "First"; return "" },
+		"Second": func(...string) string { called = "Second"; return "" },
+	}})
+	parser.SetBackend("/test/launcher.so")
+
+	var cont *data.BaseContainer
+	var lasterror string
+
+	// a reloaded window replaces the xml window,
+	// its events go to the handlers of the new window
+	for _, function := range []string{"First", "Second"} {
+		var window parser.XMLWindow
+		if err := xml.Unmarshal([]byte(`<window onevent="mouseclick:`+function+`()"/>`), &window); err != nil {
+			t.Fatal(err)
+		}
+
+		cont = parseFrame(&window, cont, &lasterror)
+		if lasterror != "" {
+			t.Fatal(lasterror)
+		}
+		backend.Invoke(backend.Event{Name: backend.MouseclickEvent})
+
+		if called != function {
+			t.Error("Expected ", function, " to be called, gave ", called)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package launcher
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+/*
+##############################################################
+# Section: File Watcher
+##############################################################
+*/
+
+// how long no file may change before a reload happens,
+// so editors writing a file in multiple steps only cause one reload
+const reloadDelay = 100 * time.Millisecond
+
+// fileWatcher watches the files a window is made of.
+// The directories of the files are watched instead of the files
+// themselves, because many editors replace a file when saving it
+type fileWatcher struct {
+	watcher *fsnotify.Watcher
+
+	// watched directories
+	dirs map[string]bool
+
+	// files a reload should happen for
+	files map[string]bool
+
+	// changed files since the last reload
+	changed   map[string]bool
+	lastEvent time.Time
+}
+
+// creates a new file watcher
+func newFileWatcher() (fw *fileWatcher, err error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return
+	}
+
+	return &fileWatcher{
+		watcher: watcher,
+		dirs:    make(map[string]bool),
+		files:   make(map[string]bool),
+		changed: make(map[string]bool),
+	}, nil
+}
+
+// watch sets the files to watch.
+// Directories that aren't needed anymore are still watched.
+func (fw *fileWatcher) watch(files []string) (err error) {
+	fw.files = make(map[string]bool)
+
+	for _, file := range files {
+		file = filepath.Clean(file)
+		fw.files[file] = true
+
+		dir := filepath.Dir(file)
+		if fw.dirs[dir] {
+			continue
+		}
+
+		err = fw.watcher.Add(dir)
+		if err != nil {
+			return
+		}
+		fw.dirs[dir] = true
+	}
+
+	return nil
+}
+
+// poll gets the files that changed since the last time
+// poll returned any, once no file changed for reloadDelay.
+// It doesn't block.
+func (fw *fileWatcher) poll() (changed []string, err error) {
+	for {
+		select {
+		case event := <-fw.watcher.Events:
+			// permission changes don't change the content
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			file := filepath.Clean(event.Name)
+			if fw.files[file] {
+				fw.changed[file] = true
+				fw.lastEvent = time.Now()
+			}
+		case err = <-fw.watcher.Errors:
+			return
+		default:
+			if len(fw.changed) == 0 || time.Since(fw.lastEvent) < reloadDelay {
+				return nil, nil
+			}
+
+			for file := range fw.changed {
+				changed = append(changed, file)
+			}
+			fw.changed = make(map[string]bool)
+
+			return changed, nil
+		}
+	}
+}
+
+// close stops watching all files
+func (fw *fileWatcher) close() error {
+	return fw.watcher.Close()
+}
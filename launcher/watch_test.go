@@ -0,0 +1,52 @@
+package launcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fliwwatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	style := filepath.Join(dir, "style.xml")
+	other := filepath.Join(dir, "other.xml")
+	ioutil.WriteFile(style, []byte("<window/>"), 0644)
+
+	watcher, err := newFileWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.close()
+
+	err = watcher.watch([]string{style})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// files that are not watched are ignored
+	ioutil.WriteFile(other, []byte("<extension/>"), 0644)
+	ioutil.WriteFile(style, []byte("<window></window>"), 0644)
+
+	var changed []string
+	for timeout := time.Now().Add(2 * time.Second); time.Now().Before(timeout); {
+		changed, err = watcher.poll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changed) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if len(changed) != 1 || changed[0] != style {
+		t.Error("Expected ", []string{style}, ", gave ", changed)
+	}
+}
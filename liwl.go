@@ -11,37 +11,13 @@ import (
 	"os"
 
 	"github.com/phoenixdevelops/fliw/cli"
-	"github.com/veandco/go-sdl2/sdl"
-	"github.com/veandco/go-sdl2/ttf"
 )
 
 func main() {
-	args := os.Args[1:]
-	log.Println(args)
-
-	// Initialization
-	err := initialize()
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// TODO use arg instead of predefined strings
-	//cli.RunCommand("build", "/home/lukas/swayland/files/home/.scripts/sidebar/power")
-	//cli.RunCommand("run", "/home/lukas/swayland/files/home/.scripts/sidebar/power")
-	cli.RunCommand("build", "/home/lukas/go/src/fliwexamples/basic")
-	cli.RunCommand("run", "/home/lukas/go/src/fliwexamples/basic")
-}
-
-func initialize() (err error) {
-	err = sdl.Init(sdl.INIT_EVERYTHING)
-	if err != nil {
-		return err
-	}
-
-	err = ttf.Init()
+	err := cli.RunCommand(os.Args[1:]...)
 	if err != nil {
-		return err
+		log.Println(err)
 	}
 
-	return nil
+	os.Exit(cli.ExitCode(err))
 }
@@ -0,0 +1,158 @@
+package parser
+
+import (
+	"errors"
+	"time"
+
+	"github.com/phoenixdevelops/fliw/data"
+	"github.com/phoenixdevelops/fliw/image"
+	"github.com/phoenixdevelops/fliw/transition"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/*
+picks the frames of animated textures, e.g.
+<texture fps="12" play="$loading">spinner.gif</texture>
+*/
+
+// the frames per second of sprite sheets if they don't have any
+const defaultFPS = 10
+
+// animationState is how long the animation of a texture was played
+type animationState struct {
+	// the image the texture showed, the animation
+	// starts again if it shows another one
+	path string
+
+	// the time played until the animation was paused last
+	played time.Duration
+
+	// when the animation was played again, zero while it is paused
+	resumed time.Time
+}
+
+// the states of the animations of textures by their uid
+var animations = make(map[uint]*animationState)
+
+// now gets the time of the frame, which animations are based on
+var now = transition.Now
+
+// spriteSheet is an image split into frames
+type spriteSheet struct {
+	path   string
+	frames int
+}
+
+// a map of sprite sheets already split into frames
+var spritesheets = make(map[spriteSheet]*image.Animation)
+
+// gets the frame of the image of a texture that is shown now.
+// Svg images are rasterized at the size they have when fitted into size
+func (tex XMLTexture) parseFrame(size data.Vector, fit data.Fit, plugin string) (result *sdl.Surface, err error) {
+	imagepath, err := parsePath(tex.Texture, plugin)
+	if err != nil {
+		return nil, tex.attrError(contentAttribute, err)
+	}
+
+	if image.IsSVG(imagepath) {
+		result, err = loadImage(imagepath, size, fit, false)
+		if err != nil {
+			return nil, tex.attrError(contentAttribute, err)
+		}
+		return result, nil
+	}
+
+	anim, err := loadAnimation(imagepath)
+	if err != nil {
+		return nil, tex.attrError(contentAttribute, err)
+	}
+
+	frames, err := parseInt(tex.Frames, plugin)
+	if err != nil {
+		return nil, tex.attrError("frames", err)
+	}
+	if frames > 0 {
+		anim, err = loadSpriteSheet(imagepath, anim.Frames[0], frames)
+		if err != nil {
+			return nil, tex.attrError("frames", err)
+		}
+	}
+
+	if len(anim.Frames) == 1 {
+		return anim.Frames[0], nil
+	}
+
+	// sprite sheets have no delays, fps replaces the delays of other images
+	fps, err := parseFloat(tex.FPS, plugin)
+	if err != nil {
+		return nil, tex.attrError("fps", err)
+	}
+	if fps < 0 {
+		return nil, tex.attrError("fps", errors.New("Negative fps: "+tex.FPS))
+	}
+	if fps == 0 && frames > 0 {
+		fps = defaultFPS
+	}
+	if fps > 0 {
+		anim = &image.Animation{Frames: anim.Frames, Delays: make([]time.Duration, len(anim.Frames))}
+		for i := range anim.Delays {
+			anim.Delays[i] = time.Duration(float64(time.Second) / fps)
+		}
+	}
+
+	// animations play and loop by default
+	play, loop := true, true
+	if cleanString(tex.Play) != "" {
+		if play, err = parseBool(tex.Play, plugin); err != nil {
+			return nil, tex.attrError("play", err)
+		}
+	}
+	if cleanString(tex.Loop) != "" {
+		if loop, err = parseBool(tex.Loop, plugin); err != nil {
+			return nil, tex.attrError("loop", err)
+		}
+	}
+
+	elapsed := playAnimation(tex.UID, imagepath, play)
+	return anim.Frames[anim.FrameAt(elapsed, loop)], nil
+}
+
+// gets how long the animation of the texture with uid was played.
+// It only goes on while play is set
+func playAnimation(uid uint, imagepath string, play bool) time.Duration {
+	state, ok := animations[uid]
+	if !ok || state.path != imagepath {
+		state = &animationState{path: imagepath}
+		animations[uid] = state
+	}
+
+	current := now()
+	if play && state.resumed.IsZero() {
+		state.resumed = current
+	} else if !play && !state.resumed.IsZero() {
+		state.played += current.Sub(state.resumed)
+		state.resumed = time.Time{}
+	}
+
+	if state.resumed.IsZero() {
+		return state.played
+	}
+	return state.played + current.Sub(state.resumed)
+}
+
+// splits an image into frames if that wasn't done yet
+func loadSpriteSheet(imagepath string, sheet *sdl.Surface, frames int) (anim *image.Animation, err error) {
+	key := spriteSheet{imagepath, frames}
+	if anim, ok := spritesheets[key]; ok {
+		return anim, nil
+	}
+
+	split, err := image.SplitFrames(sheet, frames)
+	if err != nil {
+		return
+	}
+
+	anim = &image.Animation{Frames: split, Delays: make([]time.Duration, frames)}
+	spritesheets[key] = anim
+	return anim, nil
+}
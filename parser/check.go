@@ -0,0 +1,456 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/phoenixdevelops/fliw/backend"
+)
+
+/*
+checks a fliw package for errors without running it
+*/
+
+// Problem is an error found in a file of a fliw package
+type Problem struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+	}
+
+	return fmt.Sprintf("%s: %s", p.File, p.Message)
+}
+
+// events an item can react to
+var knownEvents = map[string]bool{
+	string(backend.MouseclickEvent):      true,
+	string(backend.MouserightclickEvent): true,
+	string(backend.MousereleaseEvent):    true,
+	string(backend.KeydownEvent):         true,
+	string(backend.KeyupEvent):           true,
+}
+
+var variableRefRegex = regexp.MustCompile(`\$([a-zA-Z0-9_]+)`)
+var functionRefRegex = regexp.MustCompile(`@([a-zA-Z0-9_]+)\(`)
+
+type checker struct {
+	moduledir string
+	problems  []Problem
+
+	// extension files which were already checked
+	checked map[string]bool
+
+	// opened backends, nil if the backend could not be opened
+	plugins map[string]backend.Backend
+}
+
+// CheckModule checks the style.xml file of a module, all linked
+// extension files and their backends for errors and gives back
+// a list of all problems found. backendpath is the backend of
+// style.xml, it defaults to app.so in the module directory
+func CheckModule(path string, backendpath string) (problems []Problem) {
+	c := checker{
+		moduledir: path,
+		checked:   make(map[string]bool),
+		plugins:   make(map[string]backend.Backend),
+	}
+
+	if backendpath == "" {
+		backendpath = path + "/app.so"
+	}
+
+	c.checkFile(path+"/style.xml", "assets/style.xsd", backendpath)
+
+	// stop remote backends
+	for _, plug := range c.plugins {
+		if plug != nil {
+			plug.Close()
+		}
+	}
+
+	return c.problems
+}
+
+// adds a problem to the list of problems
+func (c *checker) report(file string, line int, format string, args ...interface{}) {
+	c.problems = append(c.problems, Problem{file, line, fmt.Sprintf(format, args...)})
+}
+
+// gets the backend at path, reporting it once if it can't be opened
+func (c *checker) getPlugin(file string, line int, path string) (plug backend.Backend) {
+	if plug, ok := c.plugins[path]; ok {
+		return plug
+	}
+
+	plug, err := backend.OpenBackend(path)
+	if err != nil {
+		c.report(file, line, "could not open backend %s (try fliw build): %s", path, err)
+		plug = nil
+	}
+
+	c.plugins[path] = plug
+	return plug
+}
+
+// checks an xml file of the module and all extensions linked from it.
+// pluginpath is the backend used by the file (or the backend of the parent
+// file in case of an extension)
+func (c *checker) checkFile(path string, xsdpath string, pluginpath string) {
+	if c.checked[path] {
+		return
+	}
+	c.checked[path] = true
+
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		c.report(path, 0, "%s", err)
+		return
+	}
+
+	// schema errors
+	validationErrors, err := getValidationErrors(file, xsdpath)
+	if err != nil {
+		c.report(path, 0, "could not validate file: %s", err)
+	}
+	for _, verr := range validationErrors {
+		c.report(path, verr.Line, "%s", strings.TrimSpace(verr.Message))
+	}
+
+	// walk through all elements
+	decoder := xml.NewDecoder(bytes.NewReader(file))
+
+	// the element names and lines of the open elements
+	type openElement struct {
+		name string
+		line int
+		text string
+
+		// textures showing an icon have no file
+		icon bool
+	}
+	var stack []openElement
+
+	for {
+		offset := decoder.InputOffset()
+
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			c.report(path, lineAt(file, offset), "%s", err)
+			return
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			line := lineAt(file, offset)
+
+			for _, attr := range t.Attr {
+				switch attr.Name.Local {
+				case "onevent":
+					c.checkEvents(path, line, attr.Value, pluginpath)
+				case "onchange", "onsubmit":
+					c.checkCallback(path, line, attr.Value, pluginpath)
+				case "backend":
+					// the backend of an extension is resolved
+					// with the backend of the linking file
+					c.checkReferences(path, line, attr.Value, pluginpath)
+					if t.Name.Local == "extension" && isLiteral(attr.Value) {
+						pluginpath = resolvePath(c.moduledir, attr.Value)
+					}
+				default:
+					c.checkReferences(path, line, attr.Value, pluginpath)
+				}
+			}
+
+			icon := false
+			for _, attr := range t.Attr {
+				icon = icon || attr.Name.Local == "icon"
+			}
+
+			stack = append(stack, openElement{t.Name.Local, line, "", icon})
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		case xml.EndElement:
+			if len(stack) == 0 {
+				break
+			}
+			elem := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			text := cleanString(elem.text)
+			c.checkReferences(path, elem.line, text, pluginpath)
+
+			if !isLiteral(text) {
+				break
+			}
+
+			switch elem.name {
+			case "texture":
+				if elem.icon {
+					break
+				}
+				texpath := resolvePath(c.moduledir, text)
+				if _, err := os.Stat(texpath); err != nil {
+					c.report(path, elem.line, "texture %s does not exist", texpath)
+				}
+			case "link":
+				linkpath := resolvePath(c.moduledir, text)
+				if _, err := os.Stat(linkpath); err != nil {
+					c.report(path, elem.line, "linked file %s does not exist", linkpath)
+					break
+				}
+				c.checkFile(linkpath, "assets/extension.xsd", pluginpath)
+			}
+		}
+	}
+}
+
+// checks the variables and functions referenced in a value
+func (c *checker) checkReferences(file string, line int, value string, pluginpath string) {
+	variables := variableRefRegex.FindAllStringSubmatch(value, -1)
+	functions := functionRefRegex.FindAllStringSubmatchIndex(value, -1)
+
+	if len(variables) == 0 && len(functions) == 0 {
+		return
+	}
+
+	plug := c.getPlugin(file, line, pluginpath)
+	if plug == nil {
+		return
+	}
+
+	for _, variable := range variables {
+		if err := plug.CheckVariable(variable[1]); err != nil {
+			c.report(file, line, "%s", err)
+		}
+	}
+
+	for _, function := range functions {
+		name := value[function[2]:function[3]]
+		args, ok := getCallArgs(value[function[1]-1:])
+		if !ok {
+			c.report(file, line, "function call %s is missing a closing bracket", name)
+			continue
+		}
+
+		if err := plug.CheckFunction(name, args != "", true); err != nil {
+			c.report(file, line, "%s", err)
+		}
+	}
+}
+
+// checks the event names and functions of an onevent attribute
+func (c *checker) checkEvents(file string, line int, onevent string, pluginpath string) {
+	for _, entry := range strings.Split(cleanString(onevent), ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		data := strings.Split(entry, ":")
+		if len(data) != 2 {
+			c.report(file, line, "invalid event assignment %q, expected event:function()", entry)
+			continue
+		}
+
+		event := strings.TrimSpace(data[0])
+		function := strings.TrimSpace(data[1])
+
+		if isLiteral(event) && !knownEvents[event] {
+			c.report(file, line, "unknown event %s", event)
+		}
+
+		if !isLiteral(function) {
+			c.checkReferences(file, line, function, pluginpath)
+			continue
+		}
+
+		bracket := strings.Index(function, "(")
+		if bracket < 0 {
+			c.report(file, line, "function call %s is missing brackets", function)
+			continue
+		}
+
+		args, ok := getCallArgs(function[bracket:])
+		if !ok {
+			c.report(file, line, "function call %s is missing a closing bracket", function)
+			continue
+		}
+
+		plug := c.getPlugin(file, line, pluginpath)
+		if plug == nil {
+			continue
+		}
+
+		if err := plug.CheckFunction(function[:bracket], args != "", false); err != nil {
+			c.report(file, line, "%s", err)
+		}
+	}
+}
+
+// checks the function of an onchange or onsubmit attribute,
+// which is called with the text of an entry
+func (c *checker) checkCallback(file string, line int, function string, pluginpath string) {
+	function = cleanString(function)
+	if !isLiteral(function) {
+		c.checkReferences(file, line, function, pluginpath)
+		return
+	}
+	if function == "" {
+		return
+	}
+
+	plug := c.getPlugin(file, line, pluginpath)
+	if plug == nil {
+		return
+	}
+
+	if err := plug.CheckFunction(function, true, false); err != nil {
+		c.report(file, line, "%s", err)
+	}
+}
+
+// gets the arguments of a function call given the string
+// starting at the opening bracket
+func getCallArgs(call string) (args string, ok bool) {
+	end := getMatchingBracket(call)
+	if end < 0 {
+		return "", false
+	}
+
+	return strings.TrimSpace(call[1:end]), true
+}
+
+// gets the index of the bracket closing the bracket at index 0
+func getMatchingBracket(s string) (index int) {
+	depth := 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// tells wether a value contains no variables or functions
+func isLiteral(value string) bool {
+	return !strings.ContainsAny(value, "$@")
+}
+
+// gets the line number of a byte offset in a file
+func lineAt(file []byte, offset int64) (line int) {
+	if offset > int64(len(file)) {
+		offset = int64(len(file))
+	}
+
+	return bytes.Count(file[:offset], []byte("\n")) + 1
+}
+
+// LinkedBackends gets the backends of all extension files linked
+// by the style.xml file of a module, directly or through other
+// extension files. Links and backends depending on a backend
+// themselves are skipped, as they are only known at runtime
+func LinkedBackends(path string) (backends []string, err error) {
+	found := make(map[string]bool)
+	visited := make(map[string]bool)
+
+	err = collectLinkedBackends(path, path+"/style.xml", found, visited)
+	if err != nil {
+		return
+	}
+
+	for backendpath := range found {
+		backends = append(backends, backendpath)
+	}
+	sort.Strings(backends)
+
+	return
+}
+
+// adds the backends of all extension files linked by an xml file to found
+func collectLinkedBackends(moduledir string, path string, found map[string]bool, visited map[string]bool) error {
+	if visited[path] {
+		return nil
+	}
+	visited[path] = true
+
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(file))
+
+	var links []string
+	inLink := false
+	text := ""
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.New(path + ": " + err.Error())
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "link" {
+				inLink = true
+				text = ""
+			}
+			if t.Name.Local != "extension" {
+				break
+			}
+			for _, attr := range t.Attr {
+				if attr.Name.Local == "backend" && isLiteral(attr.Value) && cleanString(attr.Value) != "" {
+					found[resolvePath(moduledir, attr.Value)] = true
+				}
+			}
+		case xml.CharData:
+			if inLink {
+				text += string(t)
+			}
+		case xml.EndElement:
+			if t.Name.Local == "link" {
+				inLink = false
+				if isLiteral(text) {
+					links = append(links, resolvePath(moduledir, text))
+				}
+			}
+		}
+	}
+
+	for _, link := range links {
+		err = collectLinkedBackends(moduledir, link, found, visited)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
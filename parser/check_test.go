@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var getCallArgsTest = map[string]string{
+	`()`:              ``,
+	`( )`:             ``,
+	`($a, 2)`:         `$a, 2`,
+	`(@f($a), $b)+3`:  `@f($a), $b`,
+	`((1+2)*3) rest)`: `(1+2)*3`,
+}
+
+func TestGetCallArgs(t *testing.T) {
+	for call, expected := range getCallArgsTest {
+		result, ok := getCallArgs(call)
+		if !ok {
+			t.Error("Expected closing bracket in ", call)
+		}
+		if result != expected {
+			t.Error("Expected ", expected, ", gave ", result)
+		}
+	}
+
+	if _, ok := getCallArgs(`($a, 2`); ok {
+		t.Error("Expected missing closing bracket to be reported")
+	}
+}
+
+func TestLineAt(t *testing.T) {
+	file := []byte("<a>\n<b/>\n<c/>")
+
+	if line := lineAt(file, 0); line != 1 {
+		t.Error("Expected line 1, gave ", line)
+	}
+	if line := lineAt(file, 9); line != 3 {
+		t.Error("Expected line 3, gave ", line)
+	}
+}
+
+func TestCheckModuleMissingFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fliwtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	style := `<window>
+	<texture>missing.png</texture>
+	<link>missing.xml</link>
+	<label onevent="mouseclick" textsize="12" fgcolor="#ffffff">Hello</label>
+	<texture icon="battery-low" size="24"/>
+</window>`
+	err = ioutil.WriteFile(filepath.Join(dir, "style.xml"), []byte(style), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	problems := CheckModule(dir, "")
+	lines := map[int]bool{}
+	for _, problem := range problems {
+		lines[problem.Line] = true
+	}
+
+	for _, line := range []int{2, 3, 4} {
+		if !lines[line] {
+			t.Error("Expected a problem in line ", line, ", gave ", problems)
+		}
+	}
+
+	// textures showing an icon have no file
+	if lines[5] {
+		t.Error("Expected no problem in line 5, gave ", problems)
+	}
+}
+
+func TestLinkedBackends(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fliwtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"style.xml": `<window>
+	<link>ext/battery.xml</link>
+	<link>$linked</link>
+</window>`,
+		"ext/battery.xml": `<extension backend="/opt/battery/app.so">
+	<link>clock.xml</link>
+</extension>`,
+		"clock.xml": `<extension backend="clock.so"><link>ext/battery.xml</link></extension>`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := LinkedBackends(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"/opt/battery/app.so", dir + "/clock.so"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Error("Expected: ", expected, ", Gave: ", result)
+	}
+}
@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"encoding/xml"
+	"log"
+
+	"github.com/phoenixdevelops/fliw/backend"
+	"github.com/phoenixdevelops/fliw/data"
+	"github.com/phoenixdevelops/fliw/input"
+)
+
+/*
+text entries, e.g.
+<entry textsize="14" placeholder="Search" onchange="Search" onsubmit="Run">$query</entry>
+*/
+
+// a half transparent blue behind selected text
+const defaultSelectionColor = 0x80E48435
+
+// XMLEntry is an item text can be typed into.
+// Its content is the text it starts with, the typed text is
+// replaced when the content changes
+type XMLEntry struct {
+	XMLName xml.Name `xml:"entry"`
+	XMLBase
+	TextSize         string `xml:"textsize,attr"`
+	FGColor          string `xml:"fgcolor,attr"`
+	BGColor          string `xml:"bgcolor,attr"`
+	Font             string `xml:"font,attr"`
+	Weight           string `xml:"weight,attr"`
+	Italic           string `xml:"italic,attr"`
+	Placeholder      string `xml:"placeholder,attr"`
+	PlaceholderColor string `xml:"placeholdercolor,attr"`
+	SelectionColor   string `xml:"selectioncolor,attr"`
+	Password         string `xml:"password,attr"`
+	OnChange         string `xml:"onchange,attr"`
+	OnSubmit         string `xml:"onsubmit,attr"`
+	Text             string `xml:",chardata"`
+}
+
+// the content of entries in the last frame by their uid
+var entryContents = make(map[uint]string)
+
+// the uids of the entries parsed in this frame
+var parsedEntries = make(map[uint]bool)
+
+// entries change while they are typed into, so they are never static
+func (ent XMLEntry) isStatic(plugin string) (bool, error) {
+	return false, nil
+}
+
+// converts XMLEntry to data.Entry
+func (ent XMLEntry) parse(psize data.Vector, plugin string) (item data.Item, err error) {
+	itembase, err := ent.parseItemBase(psize, plugin)
+	if err != nil {
+		return
+	}
+
+	result := &data.Entry{ItemBase: itembase}
+
+	if result.Textsize, err = parseInt(ent.TextSize, plugin); err != nil {
+		return nil, ent.attrError("textsize", err)
+	}
+	if result.Color, err = parseShapeColor(ent.FGColor, opaqueBlack, plugin); err != nil {
+		return nil, ent.attrError("fgcolor", err)
+	}
+	if result.BGcolor, err = parseColor(ent.BGColor, plugin); err != nil {
+		return nil, ent.attrError("bgcolor", err)
+	}
+	if result.Font, err = parseFont(ent.Font, plugin); err != nil {
+		return nil, ent.attrError("font", err)
+	}
+	if result.Weight, err = parseWeight(ent.Weight, plugin); err != nil {
+		return nil, ent.attrError("weight", err)
+	}
+	if result.Italic, err = parseBool(ent.Italic, plugin); err != nil {
+		return nil, ent.attrError("italic", err)
+	}
+	if result.Placeholder, err = parseText(ent.Placeholder, plugin); err != nil {
+		return nil, ent.attrError("placeholder", err)
+	}
+
+	// the placeholder is the text color, half as opaque
+	faded := result.Color&0x00FFFFFF | (result.Color>>25)<<24
+	if result.PlaceholderColor, err = parseShapeColor(ent.PlaceholderColor, faded, plugin); err != nil {
+		return nil, ent.attrError("placeholdercolor", err)
+	}
+	if result.SelectionColor, err = parseShapeColor(ent.SelectionColor, defaultSelectionColor, plugin); err != nil {
+		return nil, ent.attrError("selectioncolor", err)
+	}
+	if result.Password, err = parseBool(ent.Password, plugin); err != nil {
+		return nil, ent.attrError("password", err)
+	}
+
+	content, err := parseText(ent.Text, plugin)
+	if err != nil {
+		return nil, ent.attrError(contentAttribute, err)
+	}
+
+	onchange, err := parseText(ent.OnChange, plugin)
+	if err != nil {
+		return nil, ent.attrError("onchange", err)
+	}
+	onsubmit, err := parseText(ent.OnSubmit, plugin)
+	if err != nil {
+		return nil, ent.attrError("onsubmit", err)
+	}
+
+	// the text typed is kept between frames, unless the content changes.
+	// A content that is the typed text, e.g. because the backend stored
+	// it, keeps the cursor where it is
+	entry, created := input.GetEntry(ent.UID)
+	previous, ok := entryContents[ent.UID]
+	if (created || !ok || previous != content) && content != entry.String() {
+		entry.SetText(content)
+	}
+	entryContents[ent.UID] = content
+	parsedEntries[ent.UID] = true
+
+	entry.Password = result.Password
+	entry.OnChange = entryCallback(onchange, plugin)
+	entry.OnSubmit = entryCallback(onsubmit, plugin)
+
+	result.Text = entry.String()
+	result.Focused = input.IsFocused(ent.UID)
+	result.Cursor = entry.Cursor
+	result.SelStart, result.SelEnd = entry.Selection()
+
+	return result, nil
+}
+
+// removes the entries of items that were not parsed in this frame,
+// as they are not in the window anymore
+func pruneEntries() {
+	for uid := range entryContents {
+		if !parsedEntries[uid] {
+			delete(entryContents, uid)
+		}
+	}
+
+	input.KeepEntries(parsedEntries)
+	parsedEntries = make(map[uint]bool)
+}
+
+// gets a function calling the function of the backend with the text of an entry,
+// nil if there is no function
+func entryCallback(function string, plugin string) func(string) {
+	if function == "" {
+		return nil
+	}
+
+	return func(text string) {
+		plug, err := backend.GetBackend(plugin)
+		if err == nil {
+			_, err = plug.CallFunction(function, []string{text})
+		}
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
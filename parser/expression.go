@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/phoenixdevelops/fliw/backend"
+)
+
+/*
+compiles attribute values once, so parsing a window every
+frame only has to evaluate them
+*/
+
+// expression is a compiled attribute value
+type expression struct {
+	// the value without surrounding whitespace
+	source string
+
+	// the parsed value, nil if it's not a valid expression
+	expr backend.Expr
+	err  error
+
+	// expr compiled for each backend it was used with
+	compiled map[string]backend.Expr
+}
+
+// compiled attribute values by their source
+var expressions = make(map[string]*expression)
+
+// gets the compiled form of an attribute value,
+// compiling it if it wasn't compiled yet
+func getExpression(source string) (expr *expression) {
+	if expr, ok := expressions[source]; ok {
+		return expr
+	}
+
+	expr = &expression{
+		source:   cleanString(source),
+		compiled: make(map[string]backend.Expr),
+	}
+	if expr.source != "" {
+		expr.expr, expr.err = backend.ParseExpression(expr.source)
+	}
+
+	expressions[source] = expr
+	return expr
+}
+
+// tells wether the value is a reference to the backend,
+// otherwise it is taken as it is if it is used as text
+func (e *expression) isReference() bool {
+	return strings.HasPrefix(e.source, "$") || strings.HasPrefix(e.source, "@")
+}
+
+// evaluates the expression with the backend at plugin
+func (e *expression) eval(plugin string) (value backend.Value, err error) {
+	if e.err != nil {
+		return value, e.err
+	}
+
+	plug, err := backend.GetBackend(plugin)
+	if err != nil {
+		return
+	}
+
+	compiled, ok := e.compiled[plugin]
+	if !ok {
+		compiled = backend.Compile(e.expr, plug)
+		e.compiled[plugin] = compiled
+	}
+
+	return compiled.Eval(plug)
+}
+
+// evaluates a value used as text.
+// Values starting with $ or @ are evaluated,
+// everything else is taken as it is (see backend.PreParseString)
+func evalText(source string, plugin string) (result string, err error) {
+	expr := getExpression(source)
+	if !expr.isReference() {
+		return expr.source, nil
+	}
+
+	value, err := expr.eval(plugin)
+	if err != nil {
+		return
+	}
+
+	return value.String(), nil
+}
+
+// evaluates a value used as number. Defaults to 0 if empty
+func evalNumber(source string, plugin string) (value backend.Value, err error) {
+	expr := getExpression(source)
+	if expr.source == "" {
+		return backend.NumberOf(0), nil
+	}
+
+	value, err = expr.eval(plugin)
+	if err != nil {
+		return
+	}
+
+	return value.ToNumber()
+}
+
+// compiles all attribute values and texts of an element and its children.
+// Attribute values are compiled when they are used first anyway,
+// this just moves the work to the time the file is loaded
+func compileExpressions(element interface{}) {
+	walkSources(reflect.ValueOf(element), func(source string) {
+		getExpression(source)
+	})
+}
+
+// calls visit with every attribute value and text of an element and its children
+func walkSources(value reflect.Value, visit func(string)) {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if !value.IsNil() {
+			walkSources(value.Elem(), visit)
+		}
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			walkSources(value.Index(i), visit)
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			tag := field.Tag.Get("xml")
+
+			if field.Type.Kind() == reflect.String &&
+				(strings.HasSuffix(tag, ",attr") || tag == ",chardata") {
+				visit(value.Field(i).String())
+			} else {
+				walkSources(value.Field(i), visit)
+			}
+		}
+	}
+}
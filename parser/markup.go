@@ -0,0 +1,229 @@
+package parser
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/phoenixdevelops/fliw/data"
+	"github.com/phoenixdevelops/fliw/font"
+)
+
+/*
+parses the markup inside of labels into styled spans, e.g.
+<label>CPU <span color="#FF0000" weight="bold">$cpu</span></label>
+*/
+
+// references inside of the text of a span, e.g. "by $author"
+var runVariableRegex = regexp.MustCompile(`^\$[a-zA-Z_][a-zA-Z0-9_]*`)
+var runFunctionRegex = regexp.MustCompile(`^@[a-zA-Z_][a-zA-Z0-9_]*\(`)
+
+// tells wether the content of an element contains markup
+func hasMarkup(inner string) bool {
+	return strings.Contains(inner, "<")
+}
+
+// parses the inner xml of a label into spans.
+// base is the style of the label itself
+func parseMarkup(markup string, base data.Span, plugin string) (spans []data.Span, err error) {
+	decoder := xml.NewDecoder(strings.NewReader("<markup>" + markup + "</markup>"))
+
+	// the styles of the enclosing elements
+	var styles []data.Span
+	current := base
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			styles = append(styles, current)
+
+			// the wrapping markup element has the style of the label
+			if len(styles) == 1 {
+				continue
+			}
+
+			current, err = parseSpanStyle(token, current, plugin)
+			if err != nil {
+				return nil, err
+			}
+		case xml.EndElement:
+			current = styles[len(styles)-1]
+			styles = styles[:len(styles)-1]
+		case xml.CharData:
+			span := current
+			span.Text, err = evalRun(string(token), plugin)
+			if err != nil {
+				return nil, err
+			}
+
+			// spaces between runs are collapsed too, the whole
+			// text doesn't start with a space
+			if len(spans) == 0 || strings.HasSuffix(spans[len(spans)-1].Text, " ") {
+				span.Text = strings.TrimLeft(span.Text, " ")
+			}
+
+			if span.Text != "" {
+				spans = append(spans, span)
+			}
+		}
+	}
+
+	// the whole text doesn't end with a space either
+	for len(spans) > 0 {
+		last := &spans[len(spans)-1]
+		last.Text = strings.TrimRight(last.Text, " ")
+		if last.Text != "" {
+			break
+		}
+		spans = spans[:len(spans)-1]
+	}
+
+	return spans, nil
+}
+
+// gets the style of a markup element inside of an element with style parent
+func parseSpanStyle(element xml.StartElement, parent data.Span, plugin string) (style data.Span, err error) {
+	style = parent
+
+	switch element.Name.Local {
+	case "b":
+		style.Weight = font.Bold
+		return
+	case "i":
+		style.Italic = true
+		return
+	case "span":
+	default:
+		return style, errors.New("Unknown markup element: " + element.Name.Local)
+	}
+
+	for _, attr := range element.Attr {
+		switch attr.Name.Local {
+		case "color":
+			style.Color, err = parseColor(attr.Value, plugin)
+		case "font":
+			style.Font, err = parseFont(attr.Value, plugin)
+		case "italic":
+			style.Italic, err = parseBool(attr.Value, plugin)
+		case "weight":
+			var weight int
+			weight, err = parseWeight(attr.Value, plugin)
+			if weight != 0 {
+				style.Weight = weight
+			}
+		case "size":
+			style.Textsize, err = parseSize(attr.Value, parent.Textsize, plugin)
+		default:
+			err = errors.New("Unknown span attribute: " + attr.Name.Local)
+		}
+
+		if err != nil {
+			return style, errors.New("span " + attr.Name.Local + ": " + err.Error())
+		}
+	}
+
+	return
+}
+
+// parses a text size, relative values are relative to the size of the parent
+func parseSize(size string, parent int, plugin string) (result int, err error) {
+	value, err := evalNumber(size, plugin)
+	if err != nil {
+		return
+	}
+	if cleanString(size) == "" {
+		return parent, nil
+	}
+
+	return int(math.Round(value.Resolve(float64(parent)))), nil
+}
+
+// gets the text of a run of character data. Whitespace is collapsed
+// to single spaces and references are replaced by their values
+func evalRun(text string, plugin string) (result string, err error) {
+	words := strings.Join(strings.Fields(text), " ")
+	if words == "" {
+		if text != "" {
+			return " ", nil
+		}
+		return "", nil
+	}
+
+	if words, err = substitute(words, plugin); err != nil {
+		return
+	}
+
+	// keep the spaces separating the run from its neighbours
+	if strings.TrimLeft(text, " \t\r\n") != text {
+		words = " " + words
+	}
+	if strings.TrimRight(text, " \t\r\n") != text {
+		words = words + " "
+	}
+
+	return words, nil
+}
+
+// replaces the references in a text by their values. A text starting
+// with a reference that is an expression as a whole is evaluated, like
+// the text of a label without markup (e.g. "$cpu / 2"). In other texts
+// each variable and function call is replaced (e.g. "by $author")
+func substitute(text string, plugin string) (result string, err error) {
+	if expr := getExpression(text); expr.isReference() && expr.err == nil {
+		return evalText(text, plugin)
+	}
+
+	for {
+		start := strings.IndexAny(text, "$@")
+		if start < 0 {
+			return result + text, nil
+		}
+
+		length := referenceLength(text[start:])
+		if length == 0 {
+			// a $ or @ on its own is just a character
+			result += text[:start+1]
+			text = text[start+1:]
+			continue
+		}
+
+		value, err := evalText(text[start:start+length], plugin)
+		if err != nil {
+			return "", err
+		}
+
+		result += text[:start] + value
+		text = text[start+length:]
+	}
+}
+
+// gets the length of the variable or function call
+// at the start of a text, 0 if there is none
+func referenceLength(text string) int {
+	if name := runVariableRegex.FindString(text); name != "" {
+		return len(name)
+	}
+
+	call := runFunctionRegex.FindString(text)
+	if call == "" {
+		return 0
+	}
+
+	end := getMatchingBracket(text[len(call)-1:])
+	if end < 0 {
+		return 0
+	}
+
+	return len(call) + end
+}
@@ -0,0 +1,223 @@
+package parser
+
+import (
+	"encoding/xml"
+	"errors"
+	"sort"
+
+	"github.com/phoenixdevelops/fliw/backend"
+	"github.com/phoenixdevelops/fliw/data"
+)
+
+/*
+parses the items showing a value inside of a range, e.g.
+<progress value="$battery"><threshold below="15%" color="#FF0000" /></progress>
+*/
+
+// XMLMeter is the base of items showing a value inside of a range
+type XMLMeter struct {
+	XMLBase
+	Value      string         `xml:"value,attr"`
+	Min        string         `xml:"min,attr"`
+	Max        string         `xml:"max,attr"`
+	Color      string         `xml:"color,attr"`
+	Track      string         `xml:"track,attr"`
+	Thresholds []XMLThreshold `xml:"threshold"`
+}
+
+// XMLThreshold is the color of a meter for values below a limit
+type XMLThreshold struct {
+	Below string `xml:"below,attr"`
+	Color string `xml:"color,attr"`
+}
+
+// XMLProgress is a bar filled by its value
+type XMLProgress struct {
+	XMLName xml.Name `xml:"progress"`
+	XMLMeter
+	Orientation string `xml:"orientation,attr"`
+}
+
+// XMLGauge is an arc filled by its value
+type XMLGauge struct {
+	XMLName xml.Name `xml:"gauge"`
+	XMLMeter
+	Thickness string `xml:"thickness,attr"`
+	Start     string `xml:"start,attr"`
+	Sweep     string `xml:"sweep,attr"`
+}
+
+// XMLLevelBar is a bar of blocks filled by its value
+type XMLLevelBar struct {
+	XMLName xml.Name `xml:"levelbar"`
+	XMLMeter
+	Orientation string `xml:"orientation,attr"`
+	Blocks      string `xml:"blocks,attr"`
+	Spacing     string `xml:"spacing,attr"`
+}
+
+// parses the value, range and colors of a meter
+func (meter XMLMeter) parseMeter(plugin string) (result data.Meter, err error) {
+	value, err := evalNumber(meter.Value, plugin)
+	if err != nil {
+		return result, meter.attrError("value", err)
+	}
+	if result.Min, err = parseFloat(meter.Min, plugin); err != nil {
+		return result, meter.attrError("min", err)
+	}
+
+	// the range is 0 to 100 by default
+	result.Max = 100
+	if cleanString(meter.Max) != "" {
+		if result.Max, err = parseFloat(meter.Max, plugin); err != nil {
+			return result, meter.attrError("max", err)
+		}
+	}
+
+	// percentages are relative to the range, so 50% is in its middle
+	resolve := func(value backend.Value) float64 {
+		if value.IsRelative() {
+			return value.Resolve(result.Max-result.Min) + result.Min
+		}
+		return value.Number
+	}
+
+	result.Value = resolve(value)
+
+	// the filled part is white and the track is empty by default
+	if result.Color, err = parseShapeColor(meter.Color, 0xFFFFFFFF, plugin); err != nil {
+		return result, meter.attrError("color", err)
+	}
+	if result.Track, err = parseShapeColor(meter.Track, 0, plugin); err != nil {
+		return result, meter.attrError("track", err)
+	}
+
+	for _, threshold := range meter.Thresholds {
+		below, err := evalNumber(threshold.Below, plugin)
+		if err != nil {
+			return result, meter.attrError("below", err)
+		}
+
+		color, err := parseColor(threshold.Color, plugin)
+		if err != nil {
+			return result, meter.attrError("color", err)
+		}
+
+		result.Thresholds = append(result.Thresholds, data.Threshold{Below: resolve(below), Color: color})
+	}
+
+	// the lowest threshold the value is below is used
+	sort.SliceStable(result.Thresholds, func(i, j int) bool {
+		return result.Thresholds[i].Below < result.Thresholds[j].Below
+	})
+
+	return result, nil
+}
+
+// parses the orientation of a bar, which is horizontal by default
+func parseOrientation(orientation string, plugin string) (vertical bool, err error) {
+	orientation, err = evalText(orientation, plugin)
+	if err != nil {
+		return
+	}
+
+	switch cleanString(orientation) {
+	case "", "horizontal":
+		return false, nil
+	case "vertical":
+		return true, nil
+	}
+
+	return false, errors.New("Invalid orientation: " + orientation)
+}
+
+// converts XMLProgress to data.Progress
+func (prog XMLProgress) parse(psize data.Vector, plugin string) (progress data.Item, err error) {
+	itembase, err := prog.parseItemBase(psize, plugin)
+	if err != nil {
+		return
+	}
+
+	meter, err := prog.parseMeter(plugin)
+	if err != nil {
+		return
+	}
+
+	vertical, err := parseOrientation(prog.Orientation, plugin)
+	if err != nil {
+		return nil, prog.attrError("orientation", err)
+	}
+
+	return &data.Progress{ItemBase: itembase, Meter: meter, Vertical: vertical}, nil
+}
+
+// converts XMLGauge to data.Gauge
+func (gauge XMLGauge) parse(psize data.Vector, plugin string) (item data.Item, err error) {
+	itembase, err := gauge.parseItemBase(psize, plugin)
+	if err != nil {
+		return
+	}
+
+	meter, err := gauge.parseMeter(plugin)
+	if err != nil {
+		return
+	}
+
+	result := &data.Gauge{ItemBase: itembase, Meter: meter}
+
+	thickness, err := parseInt(gauge.Thickness, plugin)
+	if err != nil {
+		return nil, gauge.attrError("thickness", err)
+	}
+	result.Thickness = int32(thickness)
+
+	// the arc is open at the bottom by default
+	result.Start, result.Sweep = 135, 270
+	if cleanString(gauge.Start) != "" {
+		if result.Start, err = parseAngle(gauge.Start, plugin); err != nil {
+			return nil, gauge.attrError("start", err)
+		}
+	}
+	if cleanString(gauge.Sweep) != "" {
+		if result.Sweep, err = parseAngle(gauge.Sweep, plugin); err != nil {
+			return nil, gauge.attrError("sweep", err)
+		}
+	}
+
+	return result, nil
+}
+
+// converts XMLLevelBar to data.LevelBar
+func (bar XMLLevelBar) parse(psize data.Vector, plugin string) (item data.Item, err error) {
+	itembase, err := bar.parseItemBase(psize, plugin)
+	if err != nil {
+		return
+	}
+
+	meter, err := bar.parseMeter(plugin)
+	if err != nil {
+		return
+	}
+
+	result := &data.LevelBar{ItemBase: itembase, Meter: meter}
+
+	if result.Vertical, err = parseOrientation(bar.Orientation, plugin); err != nil {
+		return nil, bar.attrError("orientation", err)
+	}
+
+	// there are 5 blocks by default
+	result.Blocks = 5
+	if cleanString(bar.Blocks) != "" {
+		if result.Blocks, err = parseInt(bar.Blocks, plugin); err != nil {
+			return nil, bar.attrError("blocks", err)
+		}
+	}
+
+	spacing, err := parseInt(bar.Spacing, plugin)
+	if err != nil {
+		return nil, bar.attrError("spacing", err)
+	}
+	result.Spacing = int32(spacing)
+
+	return result, nil
+}
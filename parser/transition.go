@@ -0,0 +1,430 @@
+package parser
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phoenixdevelops/fliw/data"
+	"github.com/phoenixdevelops/fliw/image"
+	"github.com/phoenixdevelops/fliw/transition"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+/*
+lets the values of items move smoothly when they change, e.g.
+<unicolor x="$x" transition="x 200ms ease-out, color 150ms">#FF0000</unicolor>
+*/
+
+// the properties that can be transitioned, colors are
+// transitioned by channel, all other properties are numbers
+var transitionProperties = []string{"x", "y", "width", "height", "opacity", "value",
+	"color", "fgcolor", "bgcolor", "fill", "stroke"}
+
+// propertyTransition is a property of an item moving to a value
+type propertyTransition struct {
+	// the value shown when the transition started and the value it moves to
+	from   []float64
+	target []float64
+
+	start  time.Time
+	timing transition.Timing
+}
+
+// the transitions of items by their uid and the name of the property
+var transitions = make(map[uint]map[string]*propertyTransition)
+
+// gets the value of the transition at a time
+func (trans *propertyTransition) value(at time.Time) []float64 {
+	progress := trans.timing.Progress(trans.start, at)
+
+	value := make([]float64, len(trans.target))
+	for i := range value {
+		value[i] = transition.Lerp(trans.from[i], trans.target[i], progress)
+	}
+
+	return value
+}
+
+// lets the properties of an item listed in the transition attribute
+// move to the values they have now instead of jumping to them
+func applyTransitions(xmlitem XMLItem, item data.Item, plugin string) (err error) {
+	timings, err := xmlitem.parseTransitions(plugin)
+	if err != nil {
+		return
+	}
+
+	uid := item.GetUID()
+	if len(timings) == 0 {
+		delete(transitions, uid)
+		return nil
+	}
+
+	states, ok := transitions[uid]
+	if !ok {
+		states = make(map[string]*propertyTransition)
+		transitions[uid] = states
+	}
+
+	current := now()
+	for name, timing := range timings {
+		value, ok := getProperty(item, name)
+		if !ok {
+			continue
+		}
+
+		// values of the first frame are shown without transition
+		state, ok := states[name]
+		if !ok {
+			states[name] = &propertyTransition{from: value, target: value, start: current, timing: timing}
+			continue
+		}
+
+		// a new value starts a transition from the value shown now
+		if !sameChannels(value, state.target) {
+			state.from = state.value(current)
+			state.target = value
+			state.start = current
+		}
+		state.timing = timing
+
+		setProperty(item, name, state.value(current))
+	}
+
+	return nil
+}
+
+// parses the transition attribute, like css transitions a list of
+// "property duration [easing] [delay]", e.g. "x 200ms ease-out, color 1s".
+// all gives all properties the same transition
+func (base XMLBase) parseTransitions(plugin string) (timings map[string]transition.Timing, err error) {
+	value, err := evalText(base.Transition, plugin)
+	if err != nil {
+		return nil, base.attrError("transition", err)
+	}
+
+	for _, part := range splitOutside(value, ',') {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		// easings like cubic-bezier(0.4, 0, 0.2, 1) can contain spaces
+		fields := splitOutside(strings.Join(strings.Fields(part), " "), ' ')
+
+		property := fields[0]
+		if property != "all" && !isTransitionProperty(property) {
+			return nil, base.attrError("transition", errors.New("Unknown property: "+property))
+		}
+
+		timing, err := parseTiming(fields[1:])
+		if err != nil {
+			return nil, base.attrError("transition", err)
+		}
+
+		if timings == nil {
+			timings = make(map[string]transition.Timing)
+		}
+
+		if property == "all" {
+			for _, name := range transitionProperties {
+				timings[name] = timing
+			}
+		} else {
+			timings[property] = timing
+		}
+	}
+
+	return timings, nil
+}
+
+// parses the duration, easing and delay of a transition
+func parseTiming(fields []string) (timing transition.Timing, err error) {
+	durations := 0
+	for _, field := range fields {
+		if easing, ok := transition.Easings[field]; ok {
+			timing.Easing = easing
+			continue
+		}
+
+		if strings.HasPrefix(field, "cubic-bezier(") {
+			timing.Easing, err = parseCubicBezier(field)
+			if err != nil {
+				return
+			}
+			continue
+		}
+
+		duration, err := parseDuration(field)
+		if err != nil {
+			return timing, err
+		}
+
+		// the first time is the duration, the second the delay
+		switch durations {
+		case 0:
+			timing.Duration = duration
+		case 1:
+			timing.Delay = duration
+		default:
+			return timing, errors.New("Too many times: " + field)
+		}
+		durations++
+	}
+
+	if durations == 0 {
+		return timing, errors.New("Missing duration")
+	}
+
+	return timing, nil
+}
+
+// parses a time in seconds (1.5s) or milliseconds (200ms)
+func parseDuration(duration string) (result time.Duration, err error) {
+	unit := time.Second
+	number := strings.TrimSuffix(duration, "s")
+	if strings.HasSuffix(number, "m") {
+		unit = time.Millisecond
+		number = strings.TrimSuffix(number, "m")
+	}
+
+	if number == duration {
+		return 0, errors.New("Invalid time: " + duration)
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, errors.New("Invalid time: " + duration)
+	}
+
+	return time.Duration(value * float64(unit)), nil
+}
+
+// parses an easing function like cubic-bezier(0.4, 0, 0.2, 1)
+func parseCubicBezier(easing string) (result transition.Easing, err error) {
+	args := strings.TrimSuffix(strings.TrimPrefix(easing, "cubic-bezier("), ")")
+	parts := strings.Split(args, ",")
+	if len(parts) != 4 || !strings.HasSuffix(easing, ")") {
+		return nil, errors.New("Invalid easing: " + easing)
+	}
+
+	var points [4]float64
+	for i, part := range parts {
+		points[i], err = strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, errors.New("Invalid easing: " + easing)
+		}
+	}
+
+	if points[0] < 0 || points[0] > 1 || points[2] < 0 || points[2] > 1 {
+		return nil, errors.New("The x values of a cubic bézier have to be between 0 and 1: " + easing)
+	}
+
+	return transition.CubicBezier(points[0], points[1], points[2], points[3]), nil
+}
+
+// splits a string at sep, except inside of brackets
+func splitOutside(s string, sep rune) (parts []string) {
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+func isTransitionProperty(name string) bool {
+	for _, property := range transitionProperties {
+		if property == name {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+# Properties
+*/
+
+// gets a property of an item as channels, one for numbers and
+// four for colors. It is not ok if the item doesn't have the property
+func getProperty(item data.Item, name string) (value []float64, ok bool) {
+	switch name {
+	case "x":
+		return []float64{float64(item.GetPosition().X)}, true
+	case "y":
+		return []float64{float64(item.GetPosition().Y)}, true
+	case "width":
+		return []float64{float64(item.GetSize().X)}, true
+	case "height":
+		return []float64{float64(item.GetSize().Y)}, true
+	case "opacity":
+		return []float64{1 - item.GetStyle().Transparency}, true
+	}
+
+	if number := numberProperty(item, name); number != nil {
+		return []float64{*number}, true
+	}
+
+	if color, ok := getColor(item, name); ok {
+		c := image.UInt32ToColor(color)
+		return []float64{float64(c.R), float64(c.G), float64(c.B), float64(c.A)}, true
+	}
+
+	return nil, false
+}
+
+// sets a property of an item to channels got by getProperty
+func setProperty(item data.Item, name string, value []float64) {
+	switch name {
+	case "x":
+		item.SetPosition(data.Vector{X: int32(math.Round(value[0])), Y: item.GetPosition().Y})
+	case "y":
+		item.SetPosition(data.Vector{X: item.GetPosition().X, Y: int32(math.Round(value[0]))})
+	case "width":
+		item.SetSize(data.Vector{X: int32(math.Round(value[0])), Y: item.GetSize().Y})
+	case "height":
+		item.SetSize(data.Vector{X: item.GetSize().X, Y: int32(math.Round(value[0]))})
+	case "opacity":
+		style := item.GetStyle()
+		style.Transparency = 1 - value[0]
+		item.SetStyle(style)
+	}
+
+	if number := numberProperty(item, name); number != nil {
+		*number = value[0]
+	}
+
+	if len(value) == 4 {
+		channel := func(i int) uint8 {
+			return uint8(math.Round(math.Max(0, math.Min(255, value[i]))))
+		}
+		setColor(item, name, image.ColorToUInt32(sdl.Color{R: channel(0), G: channel(1), B: channel(2), A: channel(3)}))
+	}
+}
+
+// gets a pointer to a number property of an item, nil if it has none
+func numberProperty(item data.Item, name string) *float64 {
+	if name != "value" {
+		return nil
+	}
+
+	switch item := item.(type) {
+	case *data.Progress:
+		return &item.Value
+	case *data.Gauge:
+		return &item.Value
+	case *data.LevelBar:
+		return &item.Value
+	}
+
+	return nil
+}
+
+// gets a pointer to a color property of an item, nil if it has none
+func colorProperty(item data.Item, name string) *uint32 {
+	switch item := item.(type) {
+	case *data.Unicolor:
+		if name == "color" {
+			return &item.Color
+		}
+	case *data.Label:
+		return labelColor(item, name)
+	case *data.Text:
+		return labelColor(&item.Label, name)
+	case *data.Rectangle:
+		return shapeColor(&item.Shape, name)
+	case *data.Ellipse:
+		return shapeColor(&item.Shape, name)
+	case *data.Line:
+		return shapeColor(&item.Shape, name)
+	case *data.Polygon:
+		return shapeColor(&item.Shape, name)
+	case *data.Progress:
+		return meterColor(&item.Meter, name)
+	case *data.Gauge:
+		return meterColor(&item.Meter, name)
+	case *data.LevelBar:
+		return meterColor(&item.Meter, name)
+	}
+
+	return nil
+}
+
+// gets a color property of an item
+func getColor(item data.Item, name string) (color uint32, ok bool) {
+	if cont, isCont := item.(data.Container); isCont && name == "color" {
+		return cont.GetBGcolor(), true
+	}
+
+	if color := colorProperty(item, name); color != nil {
+		return *color, true
+	}
+
+	return 0, false
+}
+
+// sets a color property of an item
+func setColor(item data.Item, name string, color uint32) {
+	if cont, isCont := item.(data.Container); isCont && name == "color" {
+		cont.SetBGcolor(color)
+		return
+	}
+
+	if pointer := colorProperty(item, name); pointer != nil {
+		*pointer = color
+	}
+}
+
+func labelColor(label *data.Label, name string) *uint32 {
+	switch name {
+	case "fgcolor":
+		return &label.Color
+	case "bgcolor":
+		return &label.BGcolor
+	}
+	return nil
+}
+
+func shapeColor(shape *data.Shape, name string) *uint32 {
+	switch name {
+	case "fill":
+		return &shape.Fill
+	case "stroke":
+		return &shape.Stroke
+	}
+	return nil
+}
+
+func meterColor(meter *data.Meter, name string) *uint32 {
+	if name == "color" {
+		return &meter.Color
+	}
+	return nil
+}
+
+// tells wether two values got by getProperty are the same
+func sameChannels(a []float64, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -7,12 +7,18 @@ import (
 	"errors"
 	"io/ioutil"
 	"log"
+	"math"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/phoenixdevelops/fliw/backend"
 	"github.com/phoenixdevelops/fliw/data"
+	"github.com/phoenixdevelops/fliw/font"
 	"github.com/phoenixdevelops/fliw/image"
+	"github.com/phoenixdevelops/fliw/input"
+	"github.com/phoenixdevelops/fliw/transition"
 	xsdvalidate "github.com/terminalstatic/go-xsd-validate"
 	"github.com/veandco/go-sdl2/sdl"
 )
@@ -49,16 +55,17 @@ var WindowType = map[string]uint32{
 // XMLItem is an interface for an XML item.
 // It must be parsable to its data.Item equivalent
 type XMLItem interface {
-	parse(data.Vector, string) data.Item
-	isStatic(string) bool
+	parse(data.Vector, string) (data.Item, error)
+	isStatic(string) (bool, error)
 	getUID() uint
+	parseTransitions(string) (map[string]transition.Timing, error)
 }
 
 // XMLContainer is an extension to the XML item interface
 // allowing a parseToCont function
 type XMLContainer interface {
 	XMLItem
-	parseToCont(data.Vector, string) data.Container
+	parseToCont(data.Vector, string) (data.Container, error)
 }
 
 // XMLItem is the base of all XML elements
@@ -71,72 +78,397 @@ type XMLBase struct {
 	Height  string `xml:"height,attr"`
 	OnEvent string `xml:"onevent,attr"`
 	Static  string `xml:"static,attr"`
+	Grow    string `xml:"grow,attr"`
+	Shrink  string `xml:"shrink,attr"`
+	Basis   string `xml:"basis,attr"`
+	Column  string `xml:"column,attr"`
+	Row     string `xml:"row,attr"`
+	ColSpan string `xml:"colspan,attr"`
+	RowSpan string `xml:"rowspan,attr"`
+
+	Margin      string `xml:"margin,attr"`
+	Padding     string `xml:"padding,attr"`
+	Border      string `xml:"border,attr"`
+	BorderColor string `xml:"bordercolor,attr"`
+	Radius      string `xml:"radius,attr"`
+	Opacity     string `xml:"opacity,attr"`
+
+	// a list of properties that move to new values over time,
+	// e.g. "x 200ms ease-out, color 150ms"
+	Transition string `xml:"transition,attr"`
 }
 
 type XMLContainerBase struct {
 	XMLBase
 	Color     string             `xml:"color,attr"`
+	Scroll    string             `xml:"scroll,attr"`
+	Scrollbar string             `xml:"scrollbar,attr"`
 	Conts     []XMLBaseContainer `xml:"container"`
 	ListConts []XMLListContainer `xml:"listcontainer"`
+	HBoxes    []XMLHBox          `xml:"hbox"`
+	VBoxes    []XMLVBox          `xml:"vbox"`
+	Grids     []XMLGrid          `xml:"grid"`
 	Labels    []XMLLabel         `xml:"label"`
+	Texts     []XMLText          `xml:"text"`
 	Textures  []XMLTexture       `xml:"texture"`
 	Unicolors []XMLUnicolor      `xml:"unicolor"`
+	Gradients []XMLGradient      `xml:"gradient"`
+	Rects     []XMLRect          `xml:"rect"`
+	Ellipses  []XMLEllipse       `xml:"ellipse"`
+	Lines     []XMLLine          `xml:"line"`
+	Polygons  []XMLPolygon       `xml:"polygon"`
+	Progress  []XMLProgress      `xml:"progress"`
+	Gauges    []XMLGauge         `xml:"gauge"`
+	LevelBars []XMLLevelBar      `xml:"levelbar"`
+	Entries   []XMLEntry         `xml:"entry"`
 	Links     []XMLLink          `xml:"link"`
+
+	// the raw content, read to find the order of the items
+	// of boxes and grids. Other containers order them by element
+	Inner string `xml:",innerxml"`
+	order []string
 }
 
-func (base XMLBase) isStatic(plugin string) bool {
-	return parseBool(base.Static, plugin)
+func (base XMLBase) isStatic(plugin string) (static bool, err error) {
+	static, err = parseBool(base.Static, plugin)
+	if err != nil {
+		return false, base.attrError("static", err)
+	}
+
+	return
 }
 
 func (base XMLBase) getUID() uint {
 	return base.UID
 }
 
+// parses the attributes every item has
+func (base XMLBase) parseItemBase(psize data.Vector, plugin string) (itembase data.ItemBase, err error) {
+	itembase.UID = base.UID
+
+	itembase.Position, err = parseXY(base.X, base.Y, psize, plugin)
+	if err != nil {
+		return itembase, base.attrError("", err)
+	}
+
+	itembase.Size, err = parseWH(base.Width, base.Height, psize, plugin)
+	if err != nil {
+		return itembase, base.attrError("", err)
+	}
+
+	itembase.Events, err = parseEvents(base.OnEvent, plugin)
+	if err != nil {
+		return itembase, base.attrError("onevent", err)
+	}
+
+	itembase.Flex, err = base.parseFlex(psize, plugin)
+	if err != nil {
+		return itembase, base.attrError("", err)
+	}
+
+	itembase.GridCell, err = base.parseGridCell(plugin)
+	if err != nil {
+		return itembase, base.attrError("", err)
+	}
+
+	itembase.Style, err = base.parseStyle(plugin)
+	if err != nil {
+		return itembase, base.attrError("", err)
+	}
+
+	return itembase, nil
+}
+
+// parses the attributes telling how the item is sized inside of a box
+func (base XMLBase) parseFlex(psize data.Vector, plugin string) (flex data.Flex, err error) {
+	if flex.Grow, err = parseFactor(base.Grow, plugin); err != nil {
+		return flex, &ParseError{Attribute: "grow", Err: err}
+	}
+
+	// items shrink by default
+	flex.Shrink = 1
+	if cleanString(base.Shrink) != "" {
+		if flex.Shrink, err = parseFactor(base.Shrink, plugin); err != nil {
+			return flex, &ParseError{Attribute: "shrink", Err: err}
+		}
+	}
+
+	// the basis can be given relative to the width or height
+	if basis := cleanString(base.Basis); basis != "" && basis != "auto" {
+		if flex.Basis, err = parseXY(basis, basis, psize, plugin); err != nil {
+			err.(*ParseError).Attribute = "basis"
+			return
+		}
+	}
+
+	return flex, nil
+}
+
+// parses the attributes telling where the item is placed inside of a grid
+func (base XMLBase) parseGridCell(plugin string) (cell data.GridCell, err error) {
+	attributes := []struct {
+		name   string
+		value  string
+		result *int
+	}{
+		{"column", base.Column, &cell.Column},
+		{"row", base.Row, &cell.Row},
+		{"colspan", base.ColSpan, &cell.ColumnSpan},
+		{"rowspan", base.RowSpan, &cell.RowSpan},
+	}
+
+	for _, attr := range attributes {
+		if *attr.result, err = parseInt(attr.value, plugin); err != nil {
+			return cell, &ParseError{Attribute: attr.name, Err: err}
+		}
+		if *attr.result < 0 {
+			return cell, &ParseError{Attribute: attr.name, Err: errors.New("Negative value: " + attr.value)}
+		}
+	}
+
+	return cell, nil
+}
+
+// parses the attributes of the box model of the item
+func (base XMLBase) parseStyle(plugin string) (style data.Style, err error) {
+	if style.Margin, err = parseEdges(base.Margin, plugin); err != nil {
+		return style, &ParseError{Attribute: "margin", Err: err}
+	}
+	if style.Padding, err = parseEdges(base.Padding, plugin); err != nil {
+		return style, &ParseError{Attribute: "padding", Err: err}
+	}
+
+	border, err := parseInt(base.Border, plugin)
+	if err == nil && border < 0 {
+		err = errors.New("Negative value: " + base.Border)
+	}
+	if err != nil {
+		return style, &ParseError{Attribute: "border", Err: err}
+	}
+	style.Border = int32(border)
+
+	// borders are black if no color is given
+	style.BorderColor = opaqueBlack
+	if cleanString(base.BorderColor) != "" {
+		if style.BorderColor, err = parseColor(base.BorderColor, plugin); err != nil {
+			return style, &ParseError{Attribute: "bordercolor", Err: err}
+		}
+	}
+
+	radius, err := parseInt(base.Radius, plugin)
+	if err == nil && radius < 0 {
+		err = errors.New("Negative value: " + base.Radius)
+	}
+	if err != nil {
+		return style, &ParseError{Attribute: "radius", Err: err}
+	}
+	style.Radius = int32(radius)
+
+	// items are opaque if no opacity is given
+	if cleanString(base.Opacity) != "" {
+		opacity, err := parseFactor(base.Opacity, plugin)
+		if err == nil && opacity > 1 {
+			err = errors.New("Opacity above 1: " + base.Opacity)
+		}
+		if err != nil {
+			return style, &ParseError{Attribute: "opacity", Err: err}
+		}
+		style.Transparency = 1 - opacity
+	}
+
+	return style, nil
+}
+
+// attrError makes a ParseError for an attribute of this element
+func (base XMLBase) attrError(attribute string, err error) error {
+	perr, ok := err.(*ParseError)
+	if !ok {
+		perr = &ParseError{Attribute: attribute, Err: err}
+	}
+
+	perr.UID = base.UID
+	if perr.Attribute == "" {
+		perr.Attribute = attribute
+	}
+
+	return perr
+}
+
 var prevItemContent map[uint]*data.Item
 
 // gets a list of items in the container
-func (base XMLContainerBase) getItemList(psize data.Vector, plugin string) (list []data.Item, size data.Vector) {
+func (base XMLContainerBase) getItemList(psize data.Vector, plugin string) (list []data.Item, size data.Vector, err error) {
 	// get the size
-	size = parseWH(base.Width, base.Height, psize, plugin)
+	size, err = parseWH(base.Width, base.Height, psize, plugin)
+	if err != nil {
+		return nil, size, base.attrError("", err)
+	}
 
-	// list of data.Items
-	list = make([]data.Item,
-		len(base.Labels)+
-			len(base.Textures)+
-			len(base.Unicolors)+
-			len(base.Conts)+
-			len(base.ListConts)+
-			len(base.Links))
+	// the items are placed in the content area
+	style, err := base.parseStyle(plugin)
+	if err != nil {
+		return nil, size, base.attrError("", err)
+	}
+	content := style.Content(size)
+	csize := data.Vector{X: content.W, Y: content.H}
 
-	var index int
+	// list of data.Items
+	items := base.items()
+	list = make([]data.Item, 0, len(items))
 
 	// Add the items to the list
-	for _, item := range base.Labels {
-		list[index] = parseItem(item, size, plugin)
-		index++
-	}
-	for _, item := range base.Textures {
-		list[index] = parseItem(item, size, plugin)
-		index++
+	for _, item := range items {
+		parsed, err := parseItem(item, csize, plugin)
+		if err != nil {
+			return nil, size, err
+		}
+
+		list = append(list, parsed)
 	}
-	for _, item := range base.Unicolors {
-		list[index] = parseItem(item, size, plugin)
-		index++
+
+	return
+}
+
+// the item elements in the order they are listed by items,
+// if the order in the file is unknown
+var itemElements = []string{"label", "text", "texture", "unicolor", "gradient", "rect", "ellipse", "line", "polygon",
+	"progress", "gauge", "levelbar", "entry", "container", "listcontainer", "hbox", "vbox", "grid", "link"}
+
+// gets the items of the container in the order they are in the file
+// if it was read (see readOrder), otherwise ordered by element
+func (base XMLContainerBase) items() (items []XMLItem) {
+	order := base.order
+	if order == nil {
+		for _, name := range itemElements {
+			for i := 0; i < base.count(name); i++ {
+				order = append(order, name)
+			}
+		}
 	}
-	for _, item := range base.Conts {
-		list[index] = parseItem(item, size, plugin)
-		index++
+
+	next := make(map[string]int)
+	for _, name := range order {
+		i := next[name]
+		next[name]++
+
+		if i >= base.count(name) {
+			continue
+		}
+
+		switch name {
+		case "label":
+			items = append(items, base.Labels[i])
+		case "text":
+			items = append(items, base.Texts[i])
+		case "texture":
+			items = append(items, base.Textures[i])
+		case "unicolor":
+			items = append(items, base.Unicolors[i])
+		case "gradient":
+			items = append(items, base.Gradients[i])
+		case "rect":
+			items = append(items, base.Rects[i])
+		case "ellipse":
+			items = append(items, base.Ellipses[i])
+		case "line":
+			items = append(items, base.Lines[i])
+		case "polygon":
+			items = append(items, base.Polygons[i])
+		case "progress":
+			items = append(items, base.Progress[i])
+		case "gauge":
+			items = append(items, base.Gauges[i])
+		case "levelbar":
+			items = append(items, base.LevelBars[i])
+		case "entry":
+			items = append(items, base.Entries[i])
+		case "container":
+			items = append(items, base.Conts[i])
+		case "listcontainer":
+			items = append(items, base.ListConts[i])
+		case "hbox":
+			items = append(items, base.HBoxes[i])
+		case "vbox":
+			items = append(items, base.VBoxes[i])
+		case "grid":
+			items = append(items, base.Grids[i])
+		case "link":
+			items = append(items, base.Links[i])
+		}
 	}
-	for _, item := range base.ListConts {
-		list[index] = parseItem(item, size, plugin)
-		index++
+
+	return
+}
+
+// gets the number of item elements with a name
+func (base XMLContainerBase) count(name string) int {
+	switch name {
+	case "label":
+		return len(base.Labels)
+	case "text":
+		return len(base.Texts)
+	case "texture":
+		return len(base.Textures)
+	case "unicolor":
+		return len(base.Unicolors)
+	case "gradient":
+		return len(base.Gradients)
+	case "rect":
+		return len(base.Rects)
+	case "ellipse":
+		return len(base.Ellipses)
+	case "line":
+		return len(base.Lines)
+	case "polygon":
+		return len(base.Polygons)
+	case "progress":
+		return len(base.Progress)
+	case "gauge":
+		return len(base.Gauges)
+	case "levelbar":
+		return len(base.LevelBars)
+	case "entry":
+		return len(base.Entries)
+	case "container":
+		return len(base.Conts)
+	case "listcontainer":
+		return len(base.ListConts)
+	case "hbox":
+		return len(base.HBoxes)
+	case "vbox":
+		return len(base.VBoxes)
+	case "grid":
+		return len(base.Grids)
+	case "link":
+		return len(base.Links)
 	}
-	for _, item := range base.Links {
-		list[index] = parseItem(item, size, plugin)
-		index++
+
+	return 0
+}
+
+// reads the order of the item elements from the raw content
+func (base *XMLContainerBase) readOrder() {
+	decoder := xml.NewDecoder(strings.NewReader(base.Inner))
+	depth := 0
+
+	base.order = []string{}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				base.order = append(base.order, token.Name.Local)
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		}
 	}
 
-	return
 }
 
 // XMLWindow is the base XML element of each
@@ -144,6 +476,7 @@ func (base XMLContainerBase) getItemList(psize data.Vector, plugin string) (list
 type XMLWindow struct {
 	XMLName    xml.Name `xml:"window"`
 	WindowType string   `xml:"windowtype,attr"`
+	IconTheme  string   `xml:"icontheme,attr"`
 	XMLBaseContainer
 }
 
@@ -168,6 +501,37 @@ type XMLListContainer struct {
 	XMLContainerBase
 }
 
+// XMLBox is the base of hbox and vbox. It lists
+// its items in a row or column and sizes them to fill it
+type XMLBox struct {
+	XMLContainerBase
+	Spacing string `xml:"spacing,attr"`
+	Align   string `xml:"align,attr"`
+	Justify string `xml:"justify,attr"`
+}
+
+// XMLHBox is a box listing its items from left to right
+type XMLHBox struct {
+	XMLName xml.Name `xml:"hbox"`
+	XMLBox
+}
+
+// XMLVBox is a box listing its items from top to bottom
+type XMLVBox struct {
+	XMLName xml.Name `xml:"vbox"`
+	XMLBox
+}
+
+// XMLGrid is a container placing its items in the cells of a table
+type XMLGrid struct {
+	XMLName xml.Name `xml:"grid"`
+	XMLContainerBase
+	Columns   string `xml:"columns,attr"`
+	Rows      string `xml:"rows,attr"`
+	ColumnGap string `xml:"columngap,attr"`
+	RowGap    string `xml:"rowgap,attr"`
+}
+
 // XMLLabel is an item displaying basic short text
 type XMLLabel struct {
 	XMLName xml.Name `xml:"label"`
@@ -178,7 +542,20 @@ type XMLLabel struct {
 	FGColor  string `xml:"fgcolor,attr"`
 	BGColor  string `xml:"bgcolor,attr"`
 	Bold     string `xml:"bold,attr"`
+	Font     string `xml:"font,attr"`
+	Weight   string `xml:"weight,attr"`
+	Italic   string `xml:"italic,attr"`
 	Text     string `xml:",chardata"`
+	Markup   string `xml:",innerxml"`
+}
+
+// XMLText is an item displaying text spanning multiple lines
+type XMLText struct {
+	XMLName xml.Name `xml:"text"`
+	XMLLabel
+	Maxlines   string `xml:"maxlines,attr"`
+	LineHeight string `xml:"lineheight,attr"`
+	Justify    string `xml:"justify,attr"`
 }
 
 // XMLTexture is an item displaying a texture (picture)
@@ -187,6 +564,17 @@ type XMLTexture struct {
 	XMLBase
 	Texture   string `xml:",chardata"`
 	ScaleDown string `xml:"scaledown,attr"`
+	Fit       string `xml:"fit,attr"`
+	VAlign    string `xml:"valign,attr"`
+	HAlign    string `xml:"halign,attr"`
+	Slice     string `xml:"slice,attr"`
+	Icon      string `xml:"icon,attr"`
+	IconSize  string `xml:"size,attr"`
+	IconScale string `xml:"scale,attr"`
+	Frames    string `xml:"frames,attr"`
+	FPS       string `xml:"fps,attr"`
+	Play      string `xml:"play,attr"`
+	Loop      string `xml:"loop,attr"`
 }
 
 // XMLUnicolor is an item displaying only a single color
@@ -196,6 +584,59 @@ type XMLUnicolor struct {
 	Color string `xml:",chardata"`
 }
 
+// XMLGradient is an item filled with colors blending into each other
+type XMLGradient struct {
+	XMLName xml.Name `xml:"gradient"`
+	XMLBase
+	Type  string    `xml:"type,attr"`
+	Angle string    `xml:"angle,attr"`
+	Stops []XMLStop `xml:"stop"`
+}
+
+// XMLStop is a color of a gradient
+type XMLStop struct {
+	Offset string `xml:"offset,attr"`
+	Color  string `xml:"color,attr"`
+}
+
+// XMLShape is the base of geometric items
+type XMLShape struct {
+	XMLBase
+	Fill        string `xml:"fill,attr"`
+	Stroke      string `xml:"stroke,attr"`
+	StrokeWidth string `xml:"stroke-width,attr"`
+}
+
+// XMLRect is an item filled with a rectangle
+type XMLRect struct {
+	XMLName xml.Name `xml:"rect"`
+	XMLShape
+}
+
+// XMLEllipse is an item filled with an ellipse
+type XMLEllipse struct {
+	XMLName xml.Name `xml:"ellipse"`
+	XMLShape
+}
+
+// XMLLine is an item with a line between two points
+type XMLLine struct {
+	XMLName xml.Name `xml:"line"`
+	XMLShape
+	X1 string `xml:"x1,attr"`
+	Y1 string `xml:"y1,attr"`
+	X2 string `xml:"x2,attr"`
+	Y2 string `xml:"y2,attr"`
+}
+
+// XMLPolygon is an item filled with a polygon.
+// Its points are separated by semicolons, like "0,0; 100%,50%"
+type XMLPolygon struct {
+	XMLName xml.Name `xml:"polygon"`
+	XMLShape
+	Points string `xml:"points,attr"`
+}
+
 // XMLLink links to another XML file.
 // It has no data counterpart
 type XMLLink struct {
@@ -204,6 +645,41 @@ type XMLLink struct {
 	Link string `xml:",chardata"`
 }
 
+/*
+###########################################
+# Errors
+###########################################
+*/
+
+// ParseError is an error in the value of an attribute
+// (or the content) of an element
+type ParseError struct {
+	// the xml file containing the element
+	File string
+	// the UID of the element
+	UID uint
+	// the name of the attribute, (content) for the text content
+	Attribute string
+	Err       error
+}
+
+// the attribute name used for the text content of an element
+const contentAttribute = "(content)"
+
+func (e *ParseError) Error() string {
+	return e.File + ": element " + strconv.FormatUint(uint64(e.UID), 10) +
+		", " + e.Attribute + ": " + e.Err.Error()
+}
+
+// sets the file of a ParseError if it doesn't have one yet
+func setErrorFile(err error, file string) error {
+	if perr, ok := err.(*ParseError); ok && perr.File == "" {
+		perr.File = file
+	}
+
+	return err
+}
+
 /*
 ###########################################
 # Parser and window information
@@ -211,50 +687,51 @@ type XMLLink struct {
 */
 
 var bgcolor uint32
+
+// black without any transparency
+const opaqueBlack = 0xFF000000
+
 var bounds sdl.Rect
 var dirpath string
 
+// the backend of style.xml
+var mainplugin string
+
+// the icon theme icons of textures are looked up in
+var icontheme string
+
 var staticItems map[uint]*data.Item
 
 var uidIndex uint
 
-// UnmarshalXMLFile gives back a XMLWIndow object which can be parsed further
-func UnmarshalXMLFile(path string) (window XMLWindow, windowtype uint32, err error) {
+// UnmarshalXMLFile gives back a XMLWIndow object which can be parsed further.
+// Sizes relative to the screen are based on the given display.
+func UnmarshalXMLFile(path string, display int) (window XMLWindow, windowtype uint32, err error) {
 	dirpath = path
 	staticItems = make(map[uint]*data.Item)
 	prevItemContent = make(map[uint]*data.Item)
+	animations = make(map[uint]*animationState)
+	transitions = make(map[uint]map[string]*propertyTransition)
+	entryContents = make(map[uint]string)
+	input.ResetEntries()
 
-	// open the file
-	file, err := ioutil.ReadFile(path + "/style.xml")
+	win, valid, err := readWindowFile(path + "/style.xml")
 	if err != nil {
 		return
 	}
-
-	// validate xml file.
-	// it will be validated against
-	// assets/style.xsd
-	ok, err := validateXMLFile(file, "assets/style.xsd")
-	if !ok {
+	if !valid {
 		log.Println("XML config contained errors.")
 	}
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	win := XMLWindow{
-		WindowType: "popup_menu",
-	}
-
-	// unmarshal the file
-	err = xml.Unmarshal([]byte(file), &win)
-	if err != nil {
-		return
-	}
 
 	win.XMLBaseContainer.assignUIDs()
+	compileExpressions(&win)
+
+	// fonts and icons bundled with the module
+	font.AddDir(path + "/fonts")
+	image.AddIconDir(path + "/icons")
 
 	// get the display size
-	bounds, err = sdl.GetDisplayBounds(0)
+	bounds, err = sdl.GetDisplayBounds(display)
 	if err != nil {
 		return
 	}
@@ -267,107 +744,372 @@ func UnmarshalXMLFile(path string) (window XMLWindow, windowtype uint32, err err
 	return win, WindowType[win.WindowType], err
 }
 
-func validateXMLFile(file []byte, xsdpath string) (valid bool, err error) {
-	// validate xml with an xsd file
-	xsdvalidate.Init()
-	defer xsdvalidate.Cleanup()
-	xsdhandler, err := xsdvalidate.NewXsdHandlerUrl(xsdpath, xsdvalidate.ParsErrDefault)
+// ReloadXMLFile reads the style.xml file of the window loaded by UnmarshalXMLFile
+// and all extension files linked from it again. changed lists the files that changed
+// since the last load, so textures can be reloaded. If any of the files is not valid,
+// an error is returned and the previously loaded files stay in use.
+func ReloadXMLFile(changed []string) (window XMLWindow, err error) {
+	window, valid, err := readWindowFile(dirpath + "/style.xml")
 	if err != nil {
-		return true, err
+		return
 	}
-	defer xsdhandler.Free()
-
-	xmlhandler, err := xsdvalidate.NewXmlHandlerMem(file, xsdvalidate.ParsErrDefault)
-	if err != nil {
-		return true, err
+	if !valid {
+		return window, errors.New("XML config contained errors")
 	}
-	defer xmlhandler.Free()
 
-	err = xsdhandler.Validate(xmlhandler, xsdvalidate.ValidErrDefault)
+	window.XMLBaseContainer.assignUIDs()
+
+	// load all extensions before replacing anything,
+	// so the old ones can still be used if one of them is not valid
+	newlinks := make(map[string]*XMLExtension)
+	err = preloadLinks(&window.XMLContainerBase, newlinks)
 	if err != nil {
-		switch err.(type) {
-		case xsdvalidate.ValidationError:
-			// there was an error in the file, not our fault
-			log.Println(err)
-			log.Printf("Error in line: %d\n", err.(xsdvalidate.ValidationError).Errors[0].Line)
-			log.Println(err.(xsdvalidate.ValidationError).Errors[0].Message)
-			return false, nil
-		default:
-			// our fault?
-			return true, err
-		}
+		return
 	}
 
-	return true, nil
-}
+	links = newlinks
 
-// parse any item to its data.Item counterpart
-func parseItem(item XMLItem, psize data.Vector, plugin string) data.Item {
-	if item.isStatic(plugin) {
-		if val, ok := staticItems[item.getUID()]; ok {
-			if testItemChange(val) {
-				(*val).SetHasChanged(false)
-			} else {
-				(*val).SetHasChanged(true)
-			}
-			prevItemContent[item.getUID()] = val
-			return *val
-		} else {
-			val := item.parse(psize, plugin)
-			// something definetly has changed if the item is static but not in the statics map
-			// so there's no need to check for changes
-			prevItemContent[item.getUID()] = &val
+	// expressions are compiled again, as the backends
+	// they were compiled for may have been restarted
+	expressions = make(map[string]*expression)
+	compileExpressions(&window)
+	for _, ext := range links {
+		compileExpressions(ext)
+	}
 
-			staticItems[item.getUID()] = &val
-			return val
+	// the uids changed, so nothing from the last window can be reused
+	staticItems = make(map[uint]*data.Item)
+	prevItemContent = make(map[uint]*data.Item)
+	animations = make(map[uint]*animationState)
+	transitions = make(map[uint]map[string]*propertyTransition)
+	entryContents = make(map[uint]string)
+	input.ResetEntries()
+
+	// icons may have been installed since they were looked up
+	image.ForgetIcons()
+
+	// the surfaces are not freed, as items of the current
+	// frame could still be using them
+	for _, path := range changed {
+		delete(loadedimages, path)
+		delete(loadedsvgs, path)
+
+		for key := range spritesheets {
+			if key.path == path {
+				delete(spritesheets, key)
+			}
 		}
-	}
 
-	val := item.parse(psize, plugin)
-	if testItemChange(&val) {
-		val.SetHasChanged(false)
-	} else {
-		val.SetHasChanged(true)
+		delete(scaledimages, path)
 	}
 
-	prevItemContent[item.getUID()] = &val
-	return val
+	return window, nil
 }
 
-func testItemChange(item *data.Item) bool {
-	prev, ok := prevItemContent[(*item).GetUID()]
+// GetLoadedFiles lists the style.xml file, all extension files and
+// all textures that were loaded for the window so far
+func GetLoadedFiles() (files []string) {
+	files = append(files, dirpath+"/style.xml")
 
-	if ok {
-		if *prev == *item {
-			return true
-		}
+	for path := range links {
+		files = append(files, path)
+	}
+	for path := range loadedimages {
+		files = append(files, path)
+	}
+	for path := range loadedsvgs {
+		files = append(files, path)
 	}
 
-	return false
+	return
 }
 
-// recursively assigns a UID to the container and all its children
-func (cont *XMLContainerBase) assignUIDs() {
-	// assign own uid
-	cont.UID = uidIndex
-	uidIndex++
-
-	// assign all the normal items a uid
-	for i := range cont.Labels {
-		cont.Labels[i].UID = uidIndex
-		uidIndex++
-	}
-	for i := range cont.Textures {
-		cont.Textures[i].UID = uidIndex
-		uidIndex++
+// reads, validates and unmarshals a style.xml file
+func readWindowFile(path string) (win XMLWindow, valid bool, err error) {
+	// open the file
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
 	}
-	for i := range cont.Unicolors {
-		cont.Unicolors[i].UID = uidIndex
-		uidIndex++
+
+	// validate xml file.
+	// it will be validated against
+	// assets/style.xsd
+	valid, err = validateXMLFile(file, "assets/style.xsd")
+	if err != nil {
+		return
 	}
-	for i := range cont.Links {
-		cont.Links[i].UID = uidIndex
-		uidIndex++
+
+	win = XMLWindow{
+		WindowType: "popup_menu",
+	}
+
+	// unmarshal the file
+	err = xml.Unmarshal(file, &win)
+
+	return
+}
+
+// reads, validates and unmarshals an extension file
+func readExtensionFile(path string) (ext *XMLExtension, err error) {
+	// read the extension file
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	// validate the extension file
+	valid, err := validateXMLFile(file, "assets/extension.xsd")
+	if err != nil {
+		return
+	}
+	if !valid {
+		return nil, errors.New("XML extension file " + path + " is not valid.")
+	}
+
+	// unmarshal the extension file
+	ext = &XMLExtension{}
+	err = xml.Unmarshal(file, ext)
+	if err != nil {
+		return nil, err
+	}
+
+	// assign uids
+	ext.XMLBaseContainer.assignUIDs()
+	compileExpressions(ext)
+
+	return ext, nil
+}
+
+// recursively loads all extensions linked by a container
+// whose paths don't depend on the backend
+func preloadLinks(cont *XMLContainerBase, loaded map[string]*XMLExtension) (err error) {
+	for _, link := range cont.Links {
+		if !isLiteral(link.Link) {
+			continue
+		}
+
+		path := resolvePath(dirpath, link.Link)
+		if _, ok := loaded[path]; ok {
+			continue
+		}
+
+		ext, err := readExtensionFile(path)
+		if err != nil {
+			return err
+		}
+		loaded[path] = ext
+
+		err = preloadLinks(&ext.XMLContainerBase, loaded)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range cont.Conts {
+		err = preloadLinks(&cont.Conts[i].XMLContainerBase, loaded)
+		if err != nil {
+			return
+		}
+	}
+	for i := range cont.ListConts {
+		err = preloadLinks(&cont.ListConts[i].XMLContainerBase, loaded)
+		if err != nil {
+			return
+		}
+	}
+	for i := range cont.HBoxes {
+		err = preloadLinks(&cont.HBoxes[i].XMLContainerBase, loaded)
+		if err != nil {
+			return
+		}
+	}
+	for i := range cont.VBoxes {
+		err = preloadLinks(&cont.VBoxes[i].XMLContainerBase, loaded)
+		if err != nil {
+			return
+		}
+	}
+	for i := range cont.Grids {
+		err = preloadLinks(&cont.Grids[i].XMLContainerBase, loaded)
+		if err != nil {
+			return
+		}
+	}
+
+	return nil
+}
+
+func validateXMLFile(file []byte, xsdpath string) (valid bool, err error) {
+	validationErrors, err := getValidationErrors(file, xsdpath)
+	if err != nil {
+		return true, err
+	}
+
+	if len(validationErrors) > 0 {
+		// there was an error in the file, not our fault
+		for _, verr := range validationErrors {
+			log.Printf("Error in line %d: %s\n", verr.Line, verr.Message)
+		}
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// gets all errors in an xml file when validated with an xsd file
+func getValidationErrors(file []byte, xsdpath string) (validationErrors []xsdvalidate.StructError, err error) {
+	// validate xml with an xsd file
+	xsdvalidate.Init()
+	defer xsdvalidate.Cleanup()
+	xsdhandler, err := xsdvalidate.NewXsdHandlerUrl(xsdpath, xsdvalidate.ParsErrDefault)
+	if err != nil {
+		return
+	}
+	defer xsdhandler.Free()
+
+	xmlhandler, err := xsdvalidate.NewXmlHandlerMem(file, xsdvalidate.ParsErrDefault)
+	if err != nil {
+		return
+	}
+	defer xmlhandler.Free()
+
+	err = xsdhandler.Validate(xmlhandler, xsdvalidate.ValidErrDefault)
+	if err != nil {
+		switch verr := err.(type) {
+		case xsdvalidate.ValidationError:
+			return verr.Errors, nil
+		default:
+			// our fault?
+			return
+		}
+	}
+
+	return nil, nil
+}
+
+// parse any item to its data.Item counterpart
+func parseItem(item XMLItem, psize data.Vector, plugin string) (data.Item, error) {
+	static, err := item.isStatic(plugin)
+	if err != nil {
+		return nil, err
+	}
+
+	if static {
+		if val, ok := staticItems[item.getUID()]; ok {
+			if testItemChange(val) {
+				(*val).SetHasChanged(false)
+			} else {
+				(*val).SetHasChanged(true)
+			}
+			prevItemContent[item.getUID()] = val
+			return *val, nil
+		} else {
+			val, err := item.parse(psize, plugin)
+			if err != nil {
+				return nil, err
+			}
+			// something definetly has changed if the item is static but not in the statics map
+			// so there's no need to check for changes
+			prevItemContent[item.getUID()] = &val
+
+			staticItems[item.getUID()] = &val
+			return val, nil
+		}
+	}
+
+	val, err := item.parse(psize, plugin)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyTransitions(item, val, plugin); err != nil {
+		return nil, err
+	}
+	if testItemChange(&val) {
+		val.SetHasChanged(false)
+	} else {
+		val.SetHasChanged(true)
+	}
+
+	prevItemContent[item.getUID()] = &val
+	return val, nil
+}
+
+// tells wether an item looks the same as in the last frame
+func testItemChange(item *data.Item) bool {
+	prev, ok := prevItemContent[(*item).GetUID()]
+
+	if ok {
+		if *prev == *item || data.SameContent(*prev, *item) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// recursively assigns a UID to the container and all its children
+func (cont *XMLContainerBase) assignUIDs() {
+	// assign own uid
+	cont.UID = uidIndex
+	uidIndex++
+
+	// assign all the normal items a uid
+	for i := range cont.Labels {
+		cont.Labels[i].UID = uidIndex
+		uidIndex++
+	}
+	for i := range cont.Texts {
+		cont.Texts[i].UID = uidIndex
+		uidIndex++
+	}
+	for i := range cont.Textures {
+		cont.Textures[i].UID = uidIndex
+		uidIndex++
+	}
+	for i := range cont.Unicolors {
+		cont.Unicolors[i].UID = uidIndex
+		uidIndex++
+	}
+	for i := range cont.Gradients {
+		cont.Gradients[i].UID = uidIndex
+		uidIndex++
+	}
+	for i := range cont.Rects {
+		cont.Rects[i].UID = uidIndex
+		uidIndex++
+	}
+	for i := range cont.Ellipses {
+		cont.Ellipses[i].UID = uidIndex
+		uidIndex++
+	}
+	for i := range cont.Lines {
+		cont.Lines[i].UID = uidIndex
+		uidIndex++
+	}
+	for i := range cont.Polygons {
+		cont.Polygons[i].UID = uidIndex
+		uidIndex++
+	}
+	for i := range cont.Progress {
+		cont.Progress[i].UID = uidIndex
+		uidIndex++
+	}
+	for i := range cont.Gauges {
+		cont.Gauges[i].UID = uidIndex
+		uidIndex++
+	}
+	for i := range cont.LevelBars {
+		cont.LevelBars[i].UID = uidIndex
+		uidIndex++
+	}
+	for i := range cont.Entries {
+		cont.Entries[i].UID = uidIndex
+		uidIndex++
+	}
+	for i := range cont.Links {
+		cont.Links[i].UID = uidIndex
+		uidIndex++
 	}
 
 	// let the containers assign uids
@@ -379,6 +1121,26 @@ func (cont *XMLContainerBase) assignUIDs() {
 		cont.ListConts[i].assignUIDs()
 		uidIndex++
 	}
+	// boxes and grids lay out their items in the order of the file,
+	// other containers keep drawing them ordered by element
+	for i := range cont.HBoxes {
+		cont.HBoxes[i].readOrder()
+		cont.HBoxes[i].assignUIDs()
+		uidIndex++
+	}
+	for i := range cont.VBoxes {
+		cont.VBoxes[i].readOrder()
+		cont.VBoxes[i].assignUIDs()
+		uidIndex++
+	}
+	for i := range cont.Grids {
+		cont.Grids[i].readOrder()
+		cont.Grids[i].assignUIDs()
+		uidIndex++
+	}
+
+	// the content is only needed to read the order
+	cont.Inner = ""
 }
 
 /*
@@ -387,154 +1149,659 @@ func (cont *XMLContainerBase) assignUIDs() {
 ###########################################
 */
 
+// SetBackend sets the path of the backend used by style.xml.
+// Defaults to app.so in the module directory
+func SetBackend(path string) {
+	mainplugin = path
+}
+
+// gets the backend of style.xml
+func getMainPlugin() string {
+	if mainplugin == "" {
+		return dirpath + "/app.so"
+	}
+
+	return mainplugin
+}
+
 // Parse gets a drawable data.Container from an XMLWindow
-func (win *XMLWindow) Parse() (maincont data.Container) {
-	bgcolor = parseColor(win.Color, dirpath+"/app.so")
-	return win.parseToCont(data.Vector{X: bounds.W, Y: bounds.H}, dirpath+"/app.so")
+func (win *XMLWindow) Parse() (maincont data.Container, err error) {
+	// a window that can't be parsed is not drawn, so the next
+	// frame has to be compared to the items that were drawn last
+	drawn := make(map[uint]*data.Item, len(prevItemContent))
+	for uid, item := range prevItemContent {
+		drawn[uid] = item
+	}
+
+	// only the entries of items parsed in this frame are kept
+	parsedEntries = make(map[uint]bool)
+
+	defer func() {
+		if err != nil {
+			prevItemContent = drawn
+		}
+		err = setErrorFile(err, dirpath+"/style.xml")
+	}()
+
+	// the window is black if it has no color
+	bgcolor, err = parseShapeColor(win.Color, opaqueBlack, getMainPlugin())
+	if err != nil {
+		return nil, win.attrError("color", err)
+	}
+
+	// the theme of the user is used if the module doesn't choose one
+	icontheme, err = evalText(win.IconTheme, getMainPlugin())
+	if err != nil {
+		return nil, win.attrError("icontheme", err)
+	}
+	if icontheme == "" {
+		icontheme = image.DefaultIconTheme()
+	}
+
+	maincont, err = win.parseToCont(data.Vector{X: bounds.W, Y: bounds.H}, getMainPlugin())
+	if err != nil {
+		return
+	}
+
+	pruneEntries()
+	return maincont, nil
 }
 
 // converts XMLContainer to data.Container
-func (cont XMLBaseContainer) parseToCont(psize data.Vector, plugin string) (container data.Container) {
-	list, size := cont.getItemList(psize, plugin)
+func (cont XMLBaseContainer) parseToCont(psize data.Vector, plugin string) (container data.Container, err error) {
+	contbase, err := cont.parseContainerBase(psize, plugin)
+	if err != nil {
+		return
+	}
 
 	// Construct container
 	return &data.BaseContainer{
-		ContainerBase: data.ContainerBase{
-			ItemBase: data.ItemBase{
-				Position: parseXY(cont.X, cont.Y, psize, plugin),
-				Size:     size,
-				Events:   parseEvents(cont.OnEvent, plugin),
-			},
-			BGcolor: parseColor(cont.Color, plugin),
-			Items:   list,
-			IsLink:  false,
-		},
-	}
+		ContainerBase: contbase,
+	}, nil
 }
 
 // converts XMLContainer to data.Item
-func (cont XMLBaseContainer) parse(psize data.Vector, plugin string) (container data.Item) {
+func (cont XMLBaseContainer) parse(psize data.Vector, plugin string) (container data.Item, err error) {
 	return cont.parseToCont(psize, plugin)
 }
 
 // converts XMLListContainer to data.Container
-func (cont XMLListContainer) parseToCont(psize data.Vector, plugin string) (listcontainer data.Container) {
-	list, size := cont.getItemList(psize, plugin)
+func (cont XMLListContainer) parseToCont(psize data.Vector, plugin string) (listcontainer data.Container, err error) {
+	contbase, err := cont.parseContainerBase(psize, plugin)
+	if err != nil {
+		return
+	}
 
 	// Construct container
 	return &data.ListContainer{
-		ContainerBase: data.ContainerBase{
-			ItemBase: data.ItemBase{
-				Position: parseXY(cont.X, cont.Y, psize, plugin),
-				Size:     size,
-				Events:   parseEvents(cont.OnEvent, plugin),
-			},
-			BGcolor: parseColor(cont.Color, plugin),
-			Items:   list,
-			IsLink:  false,
-		},
-	}
+		ContainerBase: contbase,
+	}, nil
 }
 
 // converts XMLListContainer to data.Item
-func (cont XMLListContainer) parse(psize data.Vector, plugin string) (listcontainer data.Item) {
+func (cont XMLListContainer) parse(psize data.Vector, plugin string) (listcontainer data.Item, err error) {
 	return cont.parseToCont(psize, plugin)
 }
 
+// converts XMLHBox to data.Container
+func (box XMLHBox) parseToCont(psize data.Vector, plugin string) (container data.Container, err error) {
+	return box.parseBox(false, psize, plugin)
+}
+
+// converts XMLHBox to data.Item
+func (box XMLHBox) parse(psize data.Vector, plugin string) (container data.Item, err error) {
+	return box.parseToCont(psize, plugin)
+}
+
+// converts XMLVBox to data.Container
+func (box XMLVBox) parseToCont(psize data.Vector, plugin string) (container data.Container, err error) {
+	return box.parseBox(true, psize, plugin)
+}
+
+// converts XMLVBox to data.Item
+func (box XMLVBox) parse(psize data.Vector, plugin string) (container data.Item, err error) {
+	return box.parseToCont(psize, plugin)
+}
+
+// converts XMLBox to data.Box
+func (box XMLBox) parseBox(vertical bool, psize data.Vector, plugin string) (container data.Container, err error) {
+	prev := box.previousItems()
+
+	contbase, err := box.parseContainerBase(psize, plugin)
+	if err != nil {
+		return
+	}
+
+	result := &data.Box{ContainerBase: contbase, Vertical: vertical}
+
+	spacing, err := parseInt(box.Spacing, plugin)
+	if err != nil {
+		return nil, box.attrError("spacing", err)
+	}
+	result.Spacing = int32(spacing)
+
+	if result.Align, err = parseBoxAlign(box.Align, plugin); err != nil {
+		return nil, box.attrError("align", err)
+	}
+	if result.Justify, err = parseJustify(box.Justify, plugin); err != nil {
+		return nil, box.attrError("justify", err)
+	}
+
+	// the box sizes its items
+	result.Layout()
+	markResizedItems(result, prev)
+
+	return result, nil
+}
+
+// converts XMLGrid to data.Container
+func (grid XMLGrid) parseToCont(psize data.Vector, plugin string) (container data.Container, err error) {
+	prev := grid.previousItems()
+
+	contbase, err := grid.parseContainerBase(psize, plugin)
+	if err != nil {
+		return
+	}
+
+	result := &data.Grid{ContainerBase: contbase}
+
+	if result.Columns, err = parseInt(grid.Columns, plugin); err != nil {
+		return nil, grid.attrError("columns", err)
+	}
+	if result.Rows, err = parseInt(grid.Rows, plugin); err != nil {
+		return nil, grid.attrError("rows", err)
+	}
+
+	columngap, err := parseInt(grid.ColumnGap, plugin)
+	if err != nil {
+		return nil, grid.attrError("columngap", err)
+	}
+	result.ColumnGap = int32(columngap)
+
+	rowgap, err := parseInt(grid.RowGap, plugin)
+	if err != nil {
+		return nil, grid.attrError("rowgap", err)
+	}
+	result.RowGap = int32(rowgap)
+
+	// the grid sizes its items
+	result.Layout()
+	markResizedItems(result, prev)
+
+	return result, nil
+}
+
+// converts XMLGrid to data.Item
+func (grid XMLGrid) parse(psize data.Vector, plugin string) (container data.Item, err error) {
+	return grid.parseToCont(psize, plugin)
+}
+
+// gets the items of the last frame, before parsing replaces them
+func (base XMLContainerBase) previousItems() (prev map[uint]*data.Item) {
+	prev = make(map[uint]*data.Item)
+	for _, item := range base.items() {
+		prev[item.getUID()] = prevItemContent[item.getUID()]
+	}
+
+	return
+}
+
+// checks again wether the items of a container that sizes its items
+// changed, as they were compared before they got their size
+func markResizedItems(cont data.Container, prev map[uint]*data.Item) {
+	for _, item := range cont.GetItems() {
+		if p := prev[item.GetUID()]; p != nil && *p != item {
+			item.SetHasChanged(!data.SameContent(*p, item))
+		}
+	}
+}
+
+// parses the attributes and items every container has
+func (cont XMLContainerBase) parseContainerBase(psize data.Vector, plugin string) (contbase data.ContainerBase, err error) {
+	list, size, err := cont.getItemList(psize, plugin)
+	if err != nil {
+		return
+	}
+
+	contbase.ItemBase, err = cont.parseItemBase(psize, plugin)
+	if err != nil {
+		return
+	}
+	contbase.Size = size
+
+	contbase.BGcolor, err = parseColor(cont.Color, plugin)
+	if err != nil {
+		return contbase, cont.attrError("color", err)
+	}
+
+	contbase.Scroll, err = parseScrollMode(cont.Scroll, plugin)
+	if err != nil {
+		return contbase, cont.attrError("scroll", err)
+	}
+
+	contbase.Scrollbar, err = parseBool(cont.Scrollbar, plugin)
+	if err != nil {
+		return contbase, cont.attrError("scrollbar", err)
+	}
+
+	contbase.Items = list
+	contbase.IsLink = false
+
+	return contbase, nil
+}
+
 // converts XMLUnicolor to data.Unicolor
-func (uni XMLUnicolor) parse(psize data.Vector, plugin string) (unicolor data.Item) {
+func (uni XMLUnicolor) parse(psize data.Vector, plugin string) (unicolor data.Item, err error) {
+	itembase, err := uni.parseItemBase(psize, plugin)
+	if err != nil {
+		return
+	}
+
+	color, err := parseColor(uni.Color, plugin)
+	if err != nil {
+		return nil, uni.attrError(contentAttribute, err)
+	}
+
 	// Construct Unicolor
 	return &data.Unicolor{
-		ItemBase: data.ItemBase{
-			Position: parseXY(uni.X, uni.Y, psize, plugin),
-			Size:     parseWH(uni.Width, uni.Height, psize, plugin),
-			Events:   parseEvents(uni.OnEvent, plugin),
-		},
-		Color: parseColor(uni.Color, plugin),
+		ItemBase: itembase,
+		Color:    color,
+	}, nil
+}
+
+// converts XMLGradient to data.Gradient
+func (grad XMLGradient) parse(psize data.Vector, plugin string) (gradient data.Item, err error) {
+	itembase, err := grad.parseItemBase(psize, plugin)
+	if err != nil {
+		return
+	}
+
+	result := &data.Gradient{ItemBase: itembase}
+
+	kind, err := evalText(grad.Type, plugin)
+	if err != nil {
+		return nil, grad.attrError("type", err)
+	}
+
+	switch cleanString(kind) {
+	case "", "linear":
+	case "radial":
+		result.Radial = true
+	default:
+		return nil, grad.attrError("type", errors.New("Invalid gradient type: "+kind))
+	}
+
+	if result.Angle, err = parseAngle(grad.Angle, plugin); err != nil {
+		return nil, grad.attrError("angle", err)
+	}
+
+	for i, stop := range grad.Stops {
+		var s data.Stop
+
+		// stops without an offset are spread evenly
+		if cleanString(stop.Offset) == "" {
+			if len(grad.Stops) > 1 {
+				s.Offset = float64(i) / float64(len(grad.Stops)-1)
+			}
+		} else if s.Offset, err = parseFactor(stop.Offset, plugin); err != nil {
+			return nil, grad.attrError("offset", err)
+		}
+
+		// a stop can't be before the previous one
+		if i > 0 {
+			s.Offset = math.Max(s.Offset, result.Stops[i-1].Offset)
+		}
+
+		if s.Color, err = parseColor(stop.Color, plugin); err != nil {
+			return nil, grad.attrError("color", err)
+		}
+
+		result.Stops = append(result.Stops, s)
+	}
+
+	return result, nil
+}
+
+// parses the fill and stroke of a geometric item
+func (shape XMLShape) parseShape(plugin string) (result data.Shape, err error) {
+	// shapes are filled black and have no stroke by default
+	if result.Fill, err = parseShapeColor(shape.Fill, opaqueBlack, plugin); err != nil {
+		return result, shape.attrError("fill", err)
+	}
+	if result.Stroke, err = parseShapeColor(shape.Stroke, 0, plugin); err != nil {
+		return result, shape.attrError("stroke", err)
+	}
+
+	result.StrokeWidth = 1
+	if cleanString(shape.StrokeWidth) != "" {
+		if result.StrokeWidth, err = parseFactor(shape.StrokeWidth, plugin); err != nil {
+			return result, shape.attrError("stroke-width", err)
+		}
+	}
+
+	return result, nil
+}
+
+// converts XMLRect to data.Rectangle
+func (rect XMLRect) parse(psize data.Vector, plugin string) (rectangle data.Item, err error) {
+	itembase, err := rect.parseItemBase(psize, plugin)
+	if err != nil {
+		return
+	}
+
+	shape, err := rect.parseShape(plugin)
+	if err != nil {
+		return
+	}
+
+	return &data.Rectangle{ItemBase: itembase, Shape: shape}, nil
+}
+
+// converts XMLEllipse to data.Ellipse
+func (ell XMLEllipse) parse(psize data.Vector, plugin string) (ellipse data.Item, err error) {
+	itembase, err := ell.parseItemBase(psize, plugin)
+	if err != nil {
+		return
+	}
+
+	shape, err := ell.parseShape(plugin)
+	if err != nil {
+		return
+	}
+
+	return &data.Ellipse{ItemBase: itembase, Shape: shape}, nil
+}
+
+// converts XMLLine to data.Line.
+// The ends are relative to the content area of the line
+func (line XMLLine) parse(psize data.Vector, plugin string) (item data.Item, err error) {
+	itembase, err := line.parseItemBase(psize, plugin)
+	if err != nil {
+		return
+	}
+
+	shape, err := line.parseShape(plugin)
+	if err != nil {
+		return
+	}
+
+	// a line is drawn with its stroke, which is black by default
+	shape.Stroke, err = parseShapeColor(line.Stroke, opaqueBlack, plugin)
+	if err != nil {
+		return nil, line.attrError("stroke", err)
+	}
+
+	result := &data.Line{ItemBase: itembase, Shape: shape}
+	content := itembase.Style.Content(itembase.Size)
+	csize := data.Vector{X: content.W, Y: content.H}
+
+	if result.From, err = parseXY(line.X1, line.Y1, csize, plugin); err != nil {
+		return nil, line.attrError("", err)
+	}
+	if result.To, err = parseXY(line.X2, line.Y2, csize, plugin); err != nil {
+		return nil, line.attrError("", err)
+	}
+
+	return result, nil
+}
+
+// converts XMLPolygon to data.Polygon.
+// The points are relative to the content area of the polygon
+func (poly XMLPolygon) parse(psize data.Vector, plugin string) (polygon data.Item, err error) {
+	itembase, err := poly.parseItemBase(psize, plugin)
+	if err != nil {
+		return
+	}
+
+	shape, err := poly.parseShape(plugin)
+	if err != nil {
+		return
+	}
+
+	content := itembase.Style.Content(itembase.Size)
+	points, err := parsePoints(poly.Points, data.Vector{X: content.W, Y: content.H}, plugin)
+	if err != nil {
+		return nil, poly.attrError("points", err)
+	}
+
+	return &data.Polygon{ItemBase: itembase, Shape: shape, Points: points}, nil
+}
+
+// converts XMLLabel to data.Label
+func (lab XMLLabel) parse(psize data.Vector, plugin string) (label data.Item, err error) {
+	return lab.parseLabel(psize, plugin)
+}
+
+// parses the attributes shared by labels and texts
+func (lab XMLLabel) parseLabel(psize data.Vector, plugin string) (result *data.Label, err error) {
+	itembase, err := lab.parseItemBase(psize, plugin)
+	if err != nil {
+		return
+	}
+
+	result = &data.Label{ItemBase: itembase}
+
+	// parse the remaining attributes
+	if result.Text, err = parseText(lab.Text, plugin); err != nil {
+		return nil, lab.attrError(contentAttribute, err)
+	}
+	if result.Textsize, err = parseInt(lab.TextSize, plugin); err != nil {
+		return nil, lab.attrError("textsize", err)
+	}
+	if result.Valign, err = parseAlign(lab.VAlign, plugin); err != nil {
+		return nil, lab.attrError("valign", err)
+	}
+	if result.Halign, err = parseAlign(lab.HAlign, plugin); err != nil {
+		return nil, lab.attrError("halign", err)
+	}
+	if result.Color, err = parseColor(lab.FGColor, plugin); err != nil {
+		return nil, lab.attrError("fgcolor", err)
+	}
+	if result.BGcolor, err = parseColor(lab.BGColor, plugin); err != nil {
+		return nil, lab.attrError("bgcolor", err)
+	}
+	if result.Bold, err = parseBool(lab.Bold, plugin); err != nil {
+		return nil, lab.attrError("bold", err)
+	}
+	if result.Font, err = parseFont(lab.Font, plugin); err != nil {
+		return nil, lab.attrError("font", err)
+	}
+	if result.Weight, err = parseWeight(lab.Weight, plugin); err != nil {
+		return nil, lab.attrError("weight", err)
+	}
+	if result.Italic, err = parseBool(lab.Italic, plugin); err != nil {
+		return nil, lab.attrError("italic", err)
+	}
+
+	// the text may contain styled spans
+	if hasMarkup(lab.Markup) {
+		base := data.Span{
+			Textsize: result.Textsize,
+			Color:    result.Color,
+			Font:     result.Font,
+			Weight:   result.FontWeight(),
+			Italic:   result.Italic,
+		}
+
+		if result.Spans, err = parseMarkup(lab.Markup, base, plugin); err != nil {
+			return nil, lab.attrError(contentAttribute, err)
+		}
+
+		result.Text = ""
+		for _, span := range result.Spans {
+			result.Text += span.Text
+		}
+	}
+
+	return result, nil
+}
+
+// converts XMLText to data.Text
+func (txt XMLText) parse(psize data.Vector, plugin string) (text data.Item, err error) {
+	// texts are drawn in one style, only labels can contain markup
+	if hasMarkup(txt.Markup) {
+		return nil, txt.attrError(contentAttribute, errors.New("Texts can't contain markup, use a label"))
+	}
+
+	label, err := txt.parseLabel(psize, plugin)
+	if err != nil {
+		return
+	}
+
+	result := &data.Text{Label: *label}
+
+	if result.Maxlines, err = parseInt(txt.Maxlines, plugin); err != nil {
+		return nil, txt.attrError("maxlines", err)
+	}
+	if result.LineHeight, err = parseFactor(txt.LineHeight, plugin); err != nil {
+		return nil, txt.attrError("lineheight", err)
+	}
+	if result.Justify, err = parseBool(txt.Justify, plugin); err != nil {
+		return nil, txt.attrError("justify", err)
+	}
+
+	// without a height the text is as high as its lines
+	if cleanString(txt.Height) == "" {
+		result.AutoHeight = true
+		if result.Size.Y, err = result.Measure(); err != nil {
+			return nil, txt.attrError("height", err)
+		}
+	}
+
+	return result, nil
+}
+
+// converts XMLTexture to data.Texture
+func (tex XMLTexture) parse(psize data.Vector, plugin string) (texture data.Item, err error) {
+	itembase, err := tex.parseItemBase(psize, plugin)
+	if err != nil {
+		return
+	}
+
+	result := &data.Texture{ItemBase: itembase}
+
+	scaledown, err := parseBool(tex.ScaleDown, plugin)
+	if err != nil {
+		return nil, tex.attrError("scaledown", err)
+	}
+
+	result.Fit, err = parseFit(tex.Fit, scaledown, plugin)
+	if err != nil {
+		return nil, tex.attrError("fit", err)
+	}
+
+	result.Valign, err = parseAlign(tex.VAlign, plugin)
+	if err != nil {
+		return nil, tex.attrError("valign", err)
+	}
+
+	result.Halign, err = parseAlign(tex.HAlign, plugin)
+	if err != nil {
+		return nil, tex.attrError("halign", err)
+	}
+
+	result.Slice, err = parseEdges(tex.Slice, plugin)
+	if err != nil {
+		return nil, tex.attrError("slice", err)
+	}
+
+	// svg images are drawn at the size they are shown at,
+	// nine-patches at their own size as they are stretched in parts
+	content := itembase.Style.Content(itembase.Size)
+	fit := result.Fit
+	if result.Slice != (data.Edges{}) {
+		fit = data.FitNone
+	}
+
+	if cleanString(tex.Icon) != "" {
+		result.Texture, err = tex.parseIcon(content, plugin)
+		if err != nil {
+			return
+		}
+	} else {
+		result.Texture, err = tex.parseFrame(data.Vector{X: content.W, Y: content.H}, fit, plugin)
+		if err != nil {
+			return
+		}
+	}
+
+	slice := result.Slice
+	if slice.Left+slice.Right > result.Texture.W || slice.Top+slice.Bottom > result.Texture.H {
+		return nil, tex.attrError("slice", errors.New("The slice is bigger than the texture"))
+	}
+
+	return result, nil
+}
+
+// looks up the icon of a texture in the icon theme and loads it at its size.
+// The size defaults to the smaller side of the content area
+func (tex XMLTexture) parseIcon(content sdl.Rect, plugin string) (result *sdl.Surface, err error) {
+	name, err := evalText(tex.Icon, plugin)
+	if err != nil {
+		return nil, tex.attrError("icon", err)
+	}
+
+	size := int(content.W)
+	if content.H < content.W {
+		size = int(content.H)
+	}
+	if cleanString(tex.IconSize) != "" {
+		if size, err = parseInt(tex.IconSize, plugin); err != nil {
+			return nil, tex.attrError("size", err)
+		}
 	}
-}
 
-// converts XMLLabel to data.Label
-func (lab XMLLabel) parse(psize data.Vector, plugin string) (label data.Item) {
-	// Construct Label
-	return &data.Label{
-		ItemBase: data.ItemBase{
-			Position: parseXY(lab.X, lab.Y, psize, plugin),
-			Size:     parseWH(lab.Width, lab.Height, psize, plugin),
-			Events:   parseEvents(lab.OnEvent, plugin),
-		},
-		Text:     parseText(lab.Text, plugin),
-		Textsize: parseInt(lab.TextSize, plugin),
-		Valign:   parseAlign(lab.VAlign, plugin),
-		Halign:   parseAlign(lab.HAlign, plugin),
-		Color:    parseColor(lab.FGColor, plugin),
-		BGcolor:  parseColor(lab.BGColor, plugin),
-		Bold:     parseBool(lab.Bold, plugin),
+	scale := 1
+	if cleanString(tex.IconScale) != "" {
+		if scale, err = parseInt(tex.IconScale, plugin); err != nil {
+			return nil, tex.attrError("scale", err)
+		}
 	}
-}
 
-// converts XMLTexture to data.Texture
-func (tex XMLTexture) parse(psize data.Vector, plugin string) (texture data.Item) {
-	// Construct Texture
-	return &data.Texture{
-		ItemBase: data.ItemBase{
-			Position: parseXY(tex.X, tex.Y, psize, plugin),
-			Size:     parseWH(tex.Width, tex.Height, psize, plugin),
-			Events:   parseEvents(tex.OnEvent, plugin),
-		},
-		Texture: parseImage(tex.Texture, parseXY(tex.Width, tex.Height, psize, plugin),
-			parseBool(tex.ScaleDown, plugin), plugin),
+	if size <= 0 || scale <= 0 {
+		return nil, tex.attrError("size", errors.New("Icons need a positive size and scale"))
+	}
+
+	path, err := image.LookupIcon(name, icontheme, size, scale)
+	if err != nil {
+		return nil, tex.attrError("icon", err)
 	}
+
+	// the icon found can have another size, so it is scaled
+	pixels := int32(size * scale)
+	return loadImage(path, data.Vector{X: pixels, Y: pixels}, data.FitContain, true)
 }
 
 var links = make(map[string]*XMLExtension)
 
 // converts XMLLink to data.Container
-func (link XMLLink) parse(psize data.Vector, plugin string) (cont data.Item) {
-	filepath := parsePath(link.Link, plugin)
+func (link XMLLink) parse(psize data.Vector, plugin string) (cont data.Item, err error) {
+	filepath, err := parsePath(link.Link, plugin)
+	if err != nil {
+		return nil, link.attrError(contentAttribute, err)
+	}
 
 	ext, ok := links[filepath]
-	var newplug string
 	// if extension wasn't read already
 	if !ok {
-		// read the extension file
-		file, err := ioutil.ReadFile(filepath)
-		if err != nil {
-			log.Fatal(err)
-			return
-		}
-
-		// validate the extension file
-		valid, err := validateXMLFile(file, "assets/extension.xsd")
-		if err != nil {
-			log.Fatal(err)
-			return
-		}
-		if !valid {
-			log.Fatal("XML extension file ", filepath, " is not valid.")
-			return
-		}
-
-		// unmarshal the extension file
-		ext = &XMLExtension{}
-		err = xml.Unmarshal(file, ext)
+		ext, err = readExtensionFile(filepath)
 		if err != nil {
-			log.Fatal(err)
-			return
+			return nil, link.attrError(contentAttribute, err)
 		}
 
-		// assign uids
-		ext.XMLBaseContainer.assignUIDs()
-
 		// save work for later
 		links[filepath] = ext
+	}
 
-		newplug = parsePath(ext.Backend, plugin)
+	newplug, err := parsePath(ext.Backend, plugin)
+	if err != nil {
+		return nil, setErrorFile(ext.attrError("backend", err), filepath)
+	}
 
-		// register backend
-		backend.AddPlugin(newplug)
-	} else {
-		newplug = parsePath(ext.Backend, plugin)
+	// register backend
+	err = backend.AddPlugin(newplug)
+	if err != nil {
+		return nil, setErrorFile(ext.attrError("backend", err), filepath)
 	}
 
 	// needs to be set so child elements can
@@ -542,16 +1809,27 @@ func (link XMLLink) parse(psize data.Vector, plugin string) (cont data.Item) {
 	ext.XMLBaseContainer.Width = link.Width
 	ext.XMLBaseContainer.Height = link.Height
 
-	datacont := ext.XMLBaseContainer.parseToCont(psize, newplug)
+	datacont, err := ext.XMLBaseContainer.parseToCont(psize, newplug)
+	if err != nil {
+		return nil, setErrorFile(err, filepath)
+	}
 
 	// overwrite x, y, width, height
-	datacont.SetPosition(parseXY(link.X, link.Y, psize, plugin))
-	datacont.SetSize(parseWH(link.Width, link.Height, psize, plugin))
+	position, err := parseXY(link.X, link.Y, psize, plugin)
+	if err != nil {
+		return nil, link.attrError("", err)
+	}
+	size, err := parseWH(link.Width, link.Height, psize, plugin)
+	if err != nil {
+		return nil, link.attrError("", err)
+	}
+	datacont.SetPosition(position)
+	datacont.SetSize(size)
 
 	// make the container a link
 	datacont.SetLink(true)
 
-	return datacont
+	return datacont, nil
 }
 
 /*
@@ -560,23 +1838,28 @@ func (link XMLLink) parse(psize data.Vector, plugin string) (cont data.Item) {
 ###########################################
 */
 
-// parses a string containing a hex color to a uint32
-// representing said color
+// parses a string containing a hex color (#RRGGBB or #RRGGBBAA) to a uint32
+// representing said color. Colors without alpha are opaque.
 // defaults to bgcolor
 // if you want sdl to draw the right color, you'll have to use parseColor(),
 // which does the same exept it swaps some bytes
-func parseColor(color string, plugin string) (result uint32) {
+func parseColor(color string, plugin string) (result uint32, err error) {
 	// return default if not specified
 	if color == "" {
-		return bgcolor
+		return bgcolor, nil
 	}
 
-	// remove whitespaces
-	color = cleanString(color)
-
 	// preprocess
-	color = backend.GetPlugin(plugin).PreParseString(color)
+	color, err = evalText(color, plugin)
+	if err != nil {
+		return bgcolor, err
+	}
+
+	return decodeColor(color)
+}
 
+// decodes an already evaluated color like #RRGGBB or #RRGGBBAA
+func decodeColor(color string) (result uint32, err error) {
 	if strings.HasPrefix(color, "#") {
 		// Remove # prefix
 		color = color[1:]
@@ -585,117 +1868,231 @@ func parseColor(color string, plugin string) (result uint32) {
 	// decode hex string to byte array
 	val, err := hex.DecodeString(color)
 	if err != nil {
-		log.Fatal(err)
-		return bgcolor
+		return bgcolor, errors.New("Invalid color value: " + color)
 	}
 
 	// if no alpha value specified
 	if len(val) == 3 {
-		val = append(val, 0)
+		val = append(val, 0xFF)
+	}
+	if len(val) != 4 {
+		return bgcolor, errors.New("Invalid color value: " + color)
 	}
 
 	// return the byte array as uint32 (use imgtools to swap color into correct order)
-	return binary.LittleEndian.Uint32(val)
+	return binary.LittleEndian.Uint32(val), nil
 }
 
-// parses a string to a bool value. Defaults to false if string is empty
-func parseBool(b string, plugin string) (result bool) {
-	b = cleanString(b)
+// parses the color of the fill or stroke of a shape.
+// none is transparent, empty strings give def
+func parseShapeColor(color string, def uint32, plugin string) (result uint32, err error) {
+	color, err = evalText(color, plugin)
+	if err != nil {
+		return
+	}
+
+	// the evaluated color is decoded directly, as values
+	// of the backend must not be compiled as expressions
+	color = cleanString(color)
+	switch color {
+	case "":
+		return def, nil
+	case "none":
+		return 0, nil
+	}
 
+	return decodeColor(color)
+}
+
+// parses a string to a bool value. Defaults to false if string is empty
+func parseBool(b string, plugin string) (result bool, err error) {
 	// preprocess
-	b = backend.GetPlugin(plugin).PreParseString(b)
+	b, err = evalText(b, plugin)
+	if err != nil {
+		return
+	}
 
 	switch b {
 	case "false":
-		return false
+		return false, nil
 	case "true":
-		return true
+		return true, nil
 	case "": // default false for empty string
-		return false
+		return false, nil
 	default:
-		log.Fatal(errors.New("Invalid boolean value: " + b))
-		return false
+		return false, errors.New("Invalid boolean value: " + b)
 	}
 }
 
-// parses a string to a data.Align value. Defaults to CENTER if string is empty
-func parseAlign(align string, plugin string) (result data.Align) {
-	align = cleanString(align)
+// parses a comma separated list of font families and files.
+// Font files are made absolute
+func parseFont(fonts string, plugin string) (result string, err error) {
+	// preprocess
+	fonts, err = evalText(fonts, plugin)
+	if err != nil || fonts == "" {
+		return
+	}
+
+	entries := strings.Split(fonts, ",")
+	for i, entry := range entries {
+		entries[i] = strings.TrimSpace(entry)
+		if font.IsFontFile(entries[i]) {
+			entries[i] = resolvePath(dirpath, entries[i])
+		}
+	}
+
+	return strings.Join(entries, ","), nil
+}
+
+// parses a font weight given as name or number. Defaults to 0 (normal) if string is empty
+func parseWeight(weight string, plugin string) (result int, err error) {
+	// preprocess
+	weight, err = evalText(weight, plugin)
+	if err != nil || weight == "" {
+		return
+	}
+
+	return font.ParseWeight(weight)
+}
 
+// parses a string to a data.Align value. Defaults to CENTER if string is empty
+func parseAlign(align string, plugin string) (result data.Align, err error) {
 	// preprocess
-	align = backend.GetPlugin(plugin).PreParseString(align)
+	align, err = evalText(align, plugin)
+	if err != nil {
+		return data.CENTER, err
+	}
 
 	switch align {
 	case "top":
-		return data.TOP
+		return data.TOP, nil
 	case "center":
-		return data.CENTER
+		return data.CENTER, nil
 	case "bottom":
-		return data.BOTTOM
+		return data.BOTTOM, nil
 	case "right":
-		return data.RIGHT
+		return data.RIGHT, nil
 	case "left":
-		return data.LEFT
+		return data.LEFT, nil
 	case "": // default center for empty string
-		return data.CENTER
+		return data.CENTER, nil
 	default:
-		log.Fatal(errors.New("Invalid Align Value: " + align))
-		return data.CENTER
+		return data.CENTER, errors.New("Invalid Align Value: " + align)
 	}
 }
 
-// parses x and y strings to a data.Vector position. Defaults to 0 if string is empty.
-// for width and height use parseWH.
-// Uses parentSize for percentual interpretation
-func parseXY(x string, y string, parentSize data.Vector, plugin string) (result data.Vector) {
-	x = cleanString(x)
-	y = cleanString(y)
+// parses the alignment of items in a box. Defaults to STRETCH if string is empty
+func parseBoxAlign(align string, plugin string) (result data.Align, err error) {
+	// preprocess
+	align, err = evalText(align, plugin)
+	if err != nil {
+		return data.STRETCH, err
+	}
 
-	plug := backend.GetPlugin(plugin)
+	switch align {
+	case "start":
+		return data.LEFT, nil
+	case "center":
+		return data.CENTER, nil
+	case "end":
+		return data.RIGHT, nil
+	case "stretch", "": // default stretch for empty string
+		return data.STRETCH, nil
+	default:
+		return data.STRETCH, errors.New("Invalid Align Value: " + align)
+	}
+}
 
+// parses a string to a data.ScrollMode value. Defaults to ScrollNone if string is empty
+func parseScrollMode(scroll string, plugin string) (result data.ScrollMode, err error) {
 	// preprocess
-	x = plug.PreParseString(x)
-	y = plug.PreParseString(y)
-	x = preparseNumberString(x, plugin)
-	y = preparseNumberString(y, plugin)
+	scroll, err = evalText(scroll, plugin)
+	if err != nil {
+		return data.ScrollNone, err
+	}
 
-	// function for parsing 1 dimension
-	strparse := func(v string, pv int32) (res int32) {
-		if strings.HasSuffix(v, "%") {
-			// get the int value of the string without the trailing '%'
-			vi, e := strconv.Atoi(v[:len(v)-1])
-			if e != nil {
-				log.Fatal(e)
-				return res
-			}
+	switch scroll {
+	case "none", "":
+		return data.ScrollNone, nil
+	case "vertical":
+		return data.ScrollVertical, nil
+	case "horizontal":
+		return data.ScrollHorizontal, nil
+	case "both":
+		return data.ScrollBoth, nil
+	default:
+		return data.ScrollNone, errors.New("Invalid Scroll Value: " + scroll)
+	}
+}
 
-			return int32((float32(vi) / float32(100)) * float32(pv))
-		} else if v == "" {
-			return 0
-		} else {
-			// normal, no percentage
-			vi, e := strconv.Atoi(v)
-			if e != nil {
-				log.Fatal(e)
-				return res
-			}
+// parses a string to a data.Justify value. Defaults to JustifyStart if string is empty
+func parseJustify(justify string, plugin string) (result data.Justify, err error) {
+	// preprocess
+	justify, err = evalText(justify, plugin)
+	if err != nil {
+		return data.JustifyStart, err
+	}
+
+	switch justify {
+	case "start", "":
+		return data.JustifyStart, nil
+	case "center":
+		return data.JustifyCenter, nil
+	case "end":
+		return data.JustifyEnd, nil
+	case "space-between":
+		return data.JustifySpaceBetween, nil
+	case "space-around":
+		return data.JustifySpaceAround, nil
+	case "space-evenly":
+		return data.JustifySpaceEvenly, nil
+	default:
+		return data.JustifyStart, errors.New("Invalid Justify Value: " + justify)
+	}
+}
 
-			return int32(vi)
+// parses x and y strings to a data.Vector position. Defaults to 0 if string is empty.
+// for width and height use parseWH.
+// Uses parentSize for percentual interpretation
+func parseXY(x string, y string, parentSize data.Vector, plugin string) (result data.Vector, err error) {
+	// function for parsing 1 dimension
+	strparse := func(v string, pv int32) (res int32, err error) {
+		value, err := evalNumber(v, plugin)
+		if err != nil {
+			return
 		}
+
+		return int32(math.Round(value.Resolve(float64(pv)))), nil
 	}
 
 	// calculate x
-	xi := strparse(x, parentSize.X)
+	result.X, err = strparse(x, parentSize.X)
+	if err != nil {
+		return result, &ParseError{Attribute: "x", Err: err}
+	}
 
 	// calculate y
-	yi := strparse(y, parentSize.Y)
+	result.Y, err = strparse(y, parentSize.Y)
+	if err != nil {
+		return result, &ParseError{Attribute: "y", Err: err}
+	}
 
-	return data.Vector{X: xi, Y: yi}
+	return result, nil
 }
 
 // same as parseXY, except it defaults to 100%
-func parseWH(w string, h string, parentSize data.Vector, plugin string) (result data.Vector) {
-	result = parseXY(w, h, parentSize, plugin)
+func parseWH(w string, h string, parentSize data.Vector, plugin string) (result data.Vector, err error) {
+	result, err = parseXY(w, h, parentSize, plugin)
+	if err != nil {
+		// the attributes are called width and height here
+		perr := err.(*ParseError)
+		if perr.Attribute == "x" {
+			perr.Attribute = "width"
+		} else {
+			perr.Attribute = "height"
+		}
+		return
+	}
 
 	if result.X == 0 {
 		result.X = parentSize.X
@@ -707,103 +2104,352 @@ func parseWH(w string, h string, parentSize data.Vector, plugin string) (result
 	return
 }
 
+// parses the values for the sides of a rectangle, given like in css:
+// "all", "vertical horizontal", "top horizontal bottom" or "top right bottom left".
+// Defaults to 0 if empty
+func parseEdges(edges string, plugin string) (result data.Edges, err error) {
+	// each value can be a reference, unless all of them are given by one.
+	// Values of the backend are decoded directly, as they must
+	// not be compiled as expressions
+	parse := func(field string) (int, error) {
+		return parseInt(field, plugin)
+	}
+	if getExpression(edges).isReference() {
+		parse = decodeInt
+	}
+
+	edges, err = evalText(edges, plugin)
+	if err != nil {
+		return
+	}
+
+	fields := strings.Fields(edges)
+	values := make([]int32, len(fields))
+	for i, field := range fields {
+		value, err := parse(field)
+		if err != nil {
+			return result, err
+		}
+		if value < 0 {
+			return result, errors.New("Negative value: " + field)
+		}
+		values[i] = int32(value)
+	}
+
+	switch len(values) {
+	case 0:
+		return result, nil
+	case 1:
+		return data.Edges{Top: values[0], Right: values[0], Bottom: values[0], Left: values[0]}, nil
+	case 2:
+		return data.Edges{Top: values[0], Right: values[1], Bottom: values[0], Left: values[1]}, nil
+	case 3:
+		return data.Edges{Top: values[0], Right: values[1], Bottom: values[2], Left: values[1]}, nil
+	case 4:
+		return data.Edges{Top: values[0], Right: values[1], Bottom: values[2], Left: values[3]}, nil
+	}
+
+	return result, errors.New("Too many values: " + edges)
+}
+
+// parses a list of points like "x,y; x,y; ...".
+// The coordinates can be relative to size and be expressions,
+// so only commas outside of parentheses separate them
+func parsePoints(points string, size data.Vector, plugin string) (result []data.Vector, err error) {
+	for _, point := range strings.Split(points, ";") {
+		if strings.TrimSpace(point) == "" {
+			continue
+		}
+
+		coordinates := splitOutsideBrackets(point, ',')
+		if len(coordinates) != 2 {
+			return nil, errors.New("Invalid point: " + point)
+		}
+
+		vector, err := parseXY(coordinates[0], coordinates[1], size, plugin)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, vector)
+	}
+
+	return result, nil
+}
+
+// splits s at every sep that is not inside of parentheses
+func splitOutsideBrackets(s string, sep rune) (parts []string) {
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+// parses an angle in degrees. Defaults to 0 if empty
+func parseAngle(angle string, plugin string) (result float64, err error) {
+	value, err := evalNumber(angle, plugin)
+	if err != nil {
+		return
+	}
+
+	if value.IsRelative() {
+		return 0, errors.New("Relative values can't be used here: " + value.String())
+	}
+
+	return value.Number, nil
+}
+
+// parses a string to a float. Defaults to 0 if empty
+func parseFloat(float string, plugin string) (result float64, err error) {
+	value, err := evalNumber(float, plugin)
+	if err != nil {
+		return
+	}
+
+	if value.IsRelative() {
+		return 0, errors.New("Relative values can't be used here: " + value.String())
+	}
+
+	return value.Number, nil
+}
+
 // parses a string to an int. Defaults to 0 if empty
-func parseInt(integer string, plugin string) (result int) {
-	integer = cleanString(integer)
+func parseInt(integer string, plugin string) (result int, err error) {
+	value, err := evalNumber(integer, plugin)
+	if err != nil {
+		return
+	}
 
-	// preprocess
-	integer = backend.GetPlugin(plugin).PreParseString(integer)
+	return intOf(value)
+}
+
+// decodes an already evaluated int
+func decodeInt(integer string) (result int, err error) {
+	value, err := backend.StringOf(integer).ToNumber()
+	if err != nil {
+		return
+	}
 
-	integer = preparseNumberString(integer, plugin)
+	return intOf(value)
+}
 
-	if integer == "" {
-		return 0
+// rounds a number to an int, relative numbers can't be used
+func intOf(value backend.Value) (result int, err error) {
+	if value.IsRelative() {
+		return 0, errors.New("Relative values can't be used here: " + value.String())
 	}
 
-	result, err := strconv.Atoi(integer)
+	return int(math.Round(value.Number)), nil
+}
+
+// parses a positive factor, given as number (1.5) or percentage (150%).
+// Defaults to 0 if empty
+func parseFactor(factor string, plugin string) (result float64, err error) {
+	value, err := evalNumber(factor, plugin)
 	if err != nil {
-		log.Fatal(err)
-		return result
+		return
+	}
+
+	result = value.Resolve(1)
+	if result < 0 {
+		return 0, errors.New("Negative factor: " + value.String())
 	}
 
-	return result
+	return result, nil
+}
+
+// the most images kept loaded and the most sizes an image is kept at.
+// A cache that is full is emptied, so images with changing paths
+// or sizes, e.g. while a size is animated, don't fill the caches.
+// The surfaces are not freed, as items could still be using them
+const (
+	maxLoadedImages = 128
+	maxImageSizes   = 16
+)
+
+// a map of images already loaded and converted to surfaces,
+// static images are animations with a single frame
+var loadedimages = make(map[string]*image.Animation)
+
+// a map of the sizes images were already scaled to
+var scaledimages = make(map[string]map[data.Vector]*sdl.Surface)
+
+// svgImage is a loaded svg image and the sizes it was rasterized at
+type svgImage struct {
+	svg   *image.SVG
+	sizes map[data.Vector]*sdl.Surface
 }
 
-// a map of images already loaded and converted to a surface
-var loadedimages = make(map[string]*sdl.Surface)
+// a map of svg images already loaded
+var loadedsvgs = make(map[string]*svgImage)
+
+// loads an image file as *sdl.Surface. Svg images are rasterized at
+// the size they have when fitted into size. Other images keep their
+// size, unless they should be scaled too
+func loadImage(imagepath string, size data.Vector, fit data.Fit, scale bool) (result *sdl.Surface, err error) {
+	if image.IsSVG(imagepath) {
+		return parseSVG(imagepath, size, fit)
+	}
+
+	anim, err := loadAnimation(imagepath)
+	if err != nil {
+		return
+	}
+	result = anim.Frames[0]
+
+	if !scale {
+		return result, nil
+	}
+
+	size = fit.Size(data.Vector{X: result.W, Y: result.H}, size)
+	if size.X <= 0 || size.Y <= 0 || (size.X == result.W && size.Y == result.H) {
+		return result, nil
+	}
+
+	if scaled, ok := scaledimages[imagepath][size]; ok {
+		return scaled, nil
+	}
+
+	scaled, err := image.ScaleSurface(result, size.X, size.Y)
+	if err != nil {
+		return
+	}
 
-// parses a string (path) to an *sdl.Surface. Defaults to a unicolored surface if empty
-func parseImage(imagepath string, size data.Vector, downscale bool, plugin string) (result *sdl.Surface) {
-	imagepath = parsePath(imagepath, plugin)
+	if scaledimages[imagepath] == nil {
+		scaledimages[imagepath] = make(map[data.Vector]*sdl.Surface)
+	}
+	cacheSize(scaledimages[imagepath], size, scaled)
+	return scaled, nil
+}
 
+// loads all frames of an image file
+func loadAnimation(imagepath string) (anim *image.Animation, err error) {
 	// return the already processed value of that image
 	// if existent
 	if val, ok := loadedimages[imagepath]; ok {
-		return val
+		return val, nil
 	}
 
-	// get image object from file
-	img, err := image.FromFile(imagepath)
-	if err != nil {
-		log.Fatal(err)
-		result, err := sdl.CreateRGBSurface(0, size.X, size.Y, 32, 0, 0, 0, 0)
+	// go can't decode xpm images, which are still used for icons
+	if strings.EqualFold(filepath.Ext(imagepath), ".xpm") {
+		surface, err := image.SurfaceFromFile(imagepath)
 		if err != nil {
-			log.Fatal(err)
-			return result
+			return nil, err
+		}
+		anim = &image.Animation{Frames: []*sdl.Surface{surface}, Delays: []time.Duration{0}}
+	} else {
+		anim, err = image.AnimationFromFile(imagepath)
+		if err != nil {
+			return
 		}
+	}
 
-		result.FillRect(nil, bgcolor)
-		return result
+	// also save the image in a map of already loaded images
+	if len(loadedimages) >= maxLoadedImages {
+		loadedimages = make(map[string]*image.Animation)
+		scaledimages = make(map[string]map[data.Vector]*sdl.Surface)
+		spritesheets = make(map[spriteSheet]*image.Animation)
 	}
+	loadedimages[imagepath] = anim
+	return anim, nil
+}
 
-	// convert image object to surface
-	surf, err := image.ImgToSurface(img)
-	if err != nil {
-		log.Fatal(err)
-		result, err := sdl.CreateRGBSurface(0, size.X, size.Y, 32, 0, 0, 0, 0)
+// rasterizes an svg image at the size it has when fitted into size
+func parseSVG(imagepath string, size data.Vector, fit data.Fit) (result *sdl.Surface, err error) {
+	loaded, ok := loadedsvgs[imagepath]
+	if !ok {
+		svg, err := image.SVGFromFile(imagepath)
 		if err != nil {
-			log.Fatal(err)
-			return result
+			return nil, err
 		}
 
-		result.FillRect(nil, bgcolor)
-		return result
+		if len(loadedsvgs) >= maxLoadedImages {
+			loadedsvgs = make(map[string]*svgImage)
+		}
+		loaded = &svgImage{svg: svg, sizes: make(map[data.Vector]*sdl.Surface)}
+		loadedsvgs[imagepath] = loaded
 	}
 
-	// scale surface if needed
-	if downscale {
-		result, err = image.ResizeSurface(surf, size.X, size.Y)
-		if err != nil {
-			log.Fatal(err)
-			result, err := sdl.CreateRGBSurface(0, size.X, size.Y, 32, 0, 0, 0, 0)
-			if err != nil {
-				log.Fatal(err)
-				return result
-			}
+	own := data.Vector{X: int32(math.Ceil(loaded.svg.W)), Y: int32(math.Ceil(loaded.svg.H))}
+	size = fit.Size(own, size)
+	if size.X <= 0 || size.Y <= 0 {
+		size = own
+	}
+
+	if result, ok := loaded.sizes[size]; ok {
+		return result, nil
+	}
+
+	result, err = loaded.svg.Rasterize(size.X, size.Y)
+	if err != nil {
+		return
+	}
 
-			result.FillRect(nil, bgcolor)
-			return result
+	cacheSize(loaded.sizes, size, result)
+	return result, nil
+}
+
+// keeps an image at a size, dropping the other sizes
+// if there are too many of them
+func cacheSize(sizes map[data.Vector]*sdl.Surface, size data.Vector, surface *sdl.Surface) {
+	if len(sizes) >= maxImageSizes {
+		for key := range sizes {
+			delete(sizes, key)
 		}
-	} else {
-		result = surf
 	}
 
-	// also save the image in a map of already loaded images
-	loadedimages[imagepath] = result
+	sizes[size] = surface
+}
+
+// parses how a texture is scaled. Defaults to FitNone if the string is empty,
+// or to FitScaleDown if the texture should be scaled down
+func parseFit(fit string, scaledown bool, plugin string) (result data.Fit, err error) {
+	fit, err = evalText(fit, plugin)
+	if err != nil {
+		return
+	}
+
+	switch fit {
+	case "":
+		if scaledown {
+			return data.FitScaleDown, nil
+		}
+		return data.FitNone, nil
+	case "none":
+		return data.FitNone, nil
+	case "contain":
+		return data.FitContain, nil
+	case "cover":
+		return data.FitCover, nil
+	case "fill":
+		return data.FitFill, nil
+	case "scale-down":
+		return data.FitScaleDown, nil
+	}
 
-	return result
+	return data.FitNone, errors.New("Invalid fit: " + fit)
 }
 
 // parses a string to a string (removes whitespace before and after)
-func parseText(text string, plugin string) (result string) {
-	text = cleanString(text)
-	return backend.GetPlugin(plugin).PreParseString(text)
+func parseText(text string, plugin string) (result string, err error) {
+	return evalText(text, plugin)
 }
 
 // parses an ItemEvents struct to a map of events understood by the data package
 // Entries may look like this:
 // onevent="click:func1,rightclick:func2"
-func parseEvents(onevent string, plugin string) (result map[string]string) {
+func parseEvents(onevent string, plugin string) (result map[string]string, err error) {
 	result = make(map[string]string)
 
 	onevent = cleanString(onevent)
@@ -814,25 +2460,37 @@ func parseEvents(onevent string, plugin string) (result map[string]string) {
 		data := strings.Split(entry, ":")
 
 		if len(data) == 2 {
-			data[0] = parseText(data[0], plugin)
-			data[1] = parseText(data[1], plugin)
+			if data[0], err = parseText(data[0], plugin); err != nil {
+				return
+			}
+			if data[1], err = parseText(data[1], plugin); err != nil {
+				return
+			}
 			result[data[0]] = plugin + "/" + data[1]
 		}
 	}
 
-	return
+	return result, nil
 }
 
 // parses  a string containing a path
 // into an absolute path
-func parsePath(path string, plugin string) (result string) {
-	path = cleanString(path)
-
+func parsePath(path string, plugin string) (result string, err error) {
 	// preprocess
-	path = backend.GetPlugin(plugin).PreParseString(path)
+	path, err = evalText(path, plugin)
+	if err != nil {
+		return
+	}
+
+	return resolvePath(dirpath, path), nil
+}
+
+// makes a path relative to dir absolute
+func resolvePath(dir string, path string) (result string) {
+	path = cleanString(path)
 
 	if !strings.HasPrefix(path, "/") {
-		return dirpath + "/" + path
+		return dir + "/" + path
 	}
 
 	return path
@@ -845,32 +2503,14 @@ func cleanString(s string) (cleaned string) {
 	}
 
 	// remove whitespaces before
-	for strings.ContainsAny(string(s[0]), "\a\f\t\n\r\v") {
+	for s != "" && strings.ContainsAny(string(s[0]), "\a\f\t\n\r\v") {
 		s = s[1:]
 	}
 
 	// remove whitespaces after
-	for strings.ContainsAny(string(s[len(s)-1]), "\a\f\t\n\r\v") {
+	for s != "" && strings.ContainsAny(string(s[len(s)-1]), "\a\f\t\n\r\v") {
 		s = s[:len(s)-1]
 	}
 
 	return s
 }
-
-// preparses a string containing numbers or operations
-// if there is something to be calculated, e.g. 3*2.5
-// it will be done here
-func preparseNumberString(s string, plugin string) (result string) {
-	// if the string is empty, give back an empty string
-	if s == "" {
-		return ""
-	}
-
-	// if there are any operands in the string
-	// or the string starts with brackets
-	if strings.ContainsAny(s, "+-*/^") || string(s[0]) == "(" {
-		return backend.CalculateValue(s, backend.GetPlugin(plugin))
-	}
-
-	return s
-}
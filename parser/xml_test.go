@@ -1 +1,943 @@
 package parser
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/phoenixdevelops/fliw/backend"
+	"github.com/phoenixdevelops/fliw/data"
+	"github.com/phoenixdevelops/fliw/input"
+	"github.com/phoenixdevelops/fliw/transition"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const testPlugin = "/test/app.so"
+
+// the text of entries and the text the backend was called with
+var query, searched string
+
+func init() {
+	width := "50%"
+	bold := "true"
+	pad := "4"
+	size := "12"
+	date := "2024-01-05"
+	price := "1.50"
+
+	backend.RegisterBackend(testPlugin, &backend.Memory{
+		Variables: map[string]*string{"width": &width, "bold": &bold, "pad": &pad, "size": &size, "query": &query,
+			"date": &date, "price": &price},
+		Functions: map[string]func(...string) string{
+			"GetAlign": func(...string) string { return "right" },
+			"Search":   func(args ...string) string { searched = args[0]; return "" },
+		},
+	})
+}
+
+func TestParseXY(t *testing.T) {
+	psize := data.Vector{X: 200, Y: 100}
+
+	result, err := parseXY("$width", "25%", psize, testPlugin)
+	expected := data.Vector{X: 100, Y: 25}
+	if err != nil || result != expected {
+		t.Error("Expected ", expected, ", gave ", result)
+	}
+
+	// width and height default to the parent size
+	result, err = parseWH("", "10", psize, testPlugin)
+	expected = data.Vector{X: 200, Y: 10}
+	if err != nil || result != expected {
+		t.Error("Expected ", expected, ", gave ", result)
+	}
+}
+
+func TestParseBoolAndAlign(t *testing.T) {
+	if b, err := parseBool("$bold", testPlugin); err != nil || !b {
+		t.Error("Expected true, gave ", b, err)
+	}
+	if b, _ := parseBool("", testPlugin); b {
+		t.Error("Expected false for an empty string")
+	}
+	if align, err := parseAlign("@GetAlign()", testPlugin); err != nil || align != data.RIGHT {
+		t.Error("Expected ", data.RIGHT, ", gave ", align, err)
+	}
+	if _, err := parseBool("maybe", testPlugin); err == nil {
+		t.Error("Expected an error for an invalid boolean")
+	}
+}
+
+func TestParseFit(t *testing.T) {
+	if fit, err := parseFit("", true, testPlugin); err != nil || fit != data.FitScaleDown {
+		t.Error("Expected ", data.FitScaleDown, " for scaledown, gave ", fit, err)
+	}
+	if fit, err := parseFit("cover", true, testPlugin); err != nil || fit != data.FitCover {
+		t.Error("Expected ", data.FitCover, ", gave ", fit, err)
+	}
+	if _, err := parseFit("stretch", false, testPlugin); err == nil {
+		t.Error("Expected an error for an invalid fit")
+	}
+}
+
+func TestParseFont(t *testing.T) {
+	defer func(prev string) { dirpath = prev }(dirpath)
+	dirpath = "/module"
+
+	result, err := parseFont("Sans, fonts/x.ttf ,\tSerif", testPlugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "Sans,/module/fonts/x.ttf,Serif"
+	if result != expected {
+		t.Error("Expected ", expected, ", gave ", result)
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	// colors without alpha are opaque
+	if color, err := parseColor("#FF0000", testPlugin); err != nil || color != 0xFF0000FF {
+		t.Error("Expected 0xFF0000FF, gave ", color, err)
+	}
+	if color, err := parseColor("#00FF0080", testPlugin); err != nil || color != 0x8000FF00 {
+		t.Error("Expected 0x8000FF00, gave ", color, err)
+	}
+
+	style, err := XMLBase{Opacity: "25%"}.parseStyle(testPlugin)
+	if err != nil || style.Transparency != 0.75 {
+		t.Error("Expected a transparency of 0.75, gave ", style.Transparency, err)
+	}
+	if _, err := (XMLBase{Opacity: "2"}).parseStyle(testPlugin); err == nil {
+		t.Error("Expected an error for an opacity above 1")
+	}
+}
+
+func TestParseEdges(t *testing.T) {
+	tests := []struct {
+		edges    string
+		expected data.Edges
+	}{
+		{"", data.Edges{}},
+		{"$pad", data.Edges{Top: 4, Right: 4, Bottom: 4, Left: 4}},
+		{"1 2", data.Edges{Top: 1, Right: 2, Bottom: 1, Left: 2}},
+		{"1 2 3", data.Edges{Top: 1, Right: 2, Bottom: 3, Left: 2}},
+		{"1 $pad 3 5", data.Edges{Top: 1, Right: 4, Bottom: 3, Left: 5}},
+	}
+
+	for _, test := range tests {
+		if edges, err := parseEdges(test.edges, testPlugin); err != nil || edges != test.expected {
+			t.Error("Expected ", test.expected, ", gave ", edges, err)
+		}
+	}
+
+	if _, err := parseEdges("1 2 3 4 5", testPlugin); err == nil {
+		t.Error("Expected an error for too many values")
+	}
+	if _, err := parseEdges("-1", testPlugin); err == nil {
+		t.Error("Expected an error for a negative value")
+	}
+}
+
+func TestParseError(t *testing.T) {
+	lab := XMLLabel{XMLBase: XMLBase{UID: 7, Height: "$missing"}}
+
+	_, err := lab.parse(data.Vector{X: 100, Y: 100}, testPlugin)
+	perr, ok := err.(*ParseError)
+	if !ok {
+		t.Fatal("Expected a *ParseError, gave ", err)
+	}
+	if perr.UID != 7 || perr.Attribute != "height" {
+		t.Error("Expected element 7, attribute height, gave ", perr.UID, ", ", perr.Attribute)
+	}
+
+	lab = XMLLabel{XMLBase: XMLBase{UID: 8}, Bold: "yes"}
+	_, err = lab.parse(data.Vector{X: 100, Y: 100}, testPlugin)
+	if perr, ok := err.(*ParseError); !ok || perr.Attribute != "bold" {
+		t.Error("Expected an error in attribute bold, gave ", err)
+	}
+}
+
+func TestParseText(t *testing.T) {
+	txt := XMLText{
+		XMLLabel:   XMLLabel{XMLBase: XMLBase{UID: 3, Height: "40"}, Text: "some text"},
+		Maxlines:   "$size / 4",
+		LineHeight: "150%",
+		Justify:    "true",
+	}
+
+	item, err := txt.parse(data.Vector{X: 100, Y: 100}, testPlugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text, ok := item.(*data.Text)
+	if !ok {
+		t.Fatal("Expected a *data.Text, gave ", item)
+	}
+	if text.Maxlines != 3 || text.LineHeight != 1.5 || !text.Justify || text.Size.Y != 40 {
+		t.Error("Expected 3 lines, 1.5 line height, justify and height 40, gave ",
+			text.Maxlines, ", ", text.LineHeight, ", ", text.Justify, ", ", text.Size.Y)
+	}
+
+	txt.LineHeight = "-1"
+	if _, err := txt.parse(data.Vector{X: 100, Y: 100}, testPlugin); err == nil {
+		t.Error("Expected an error for a negative line height")
+	}
+}
+
+func TestParseMarkup(t *testing.T) {
+	lab := XMLLabel{
+		XMLBase:  XMLBase{UID: 4},
+		TextSize: "10",
+		FGColor:  "#FFFFFF",
+		Markup:   "\n\tCPU <span color=\"#FF0000\" size=\"150%\"> $size <b>%</b></span>\n",
+	}
+
+	item, err := lab.parse(data.Vector{X: 100, Y: 100}, testPlugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	label := item.(*data.Label)
+	if label.Text != "CPU 12 %" {
+		t.Error("Expected CPU 12 %, gave ", label.Text)
+	}
+
+	expected := []data.Span{
+		{Text: "CPU ", Textsize: 10, Color: 0xFFFFFFFF, Weight: 400},
+		{Text: "12 ", Textsize: 15, Color: 0xFF0000FF, Weight: 400},
+		{Text: "%", Textsize: 15, Color: 0xFF0000FF, Weight: 700},
+	}
+	if len(label.Spans) != len(expected) {
+		t.Fatal("Expected ", expected, ", gave ", label.Spans)
+	}
+	for i := range expected {
+		if label.Spans[i] != expected[i] {
+			t.Error("Expected ", expected[i], ", gave ", label.Spans[i])
+		}
+	}
+
+	// references are replaced inside of the text of a span
+	lab.Markup = "<b>size $size, @GetAlign() </b>$ 5 and $size*2"
+	item, err = lab.parse(data.Vector{X: 100, Y: 100}, testPlugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := item.(*data.Label).Text; text != "size 12, right $ 5 and 12*2" {
+		t.Error("Expected size 12, right $ 5 and 12*2, gave ", text)
+	}
+
+	lab.Markup = "<blink>no</blink>"
+	if _, err := lab.parse(data.Vector{X: 100, Y: 100}, testPlugin); err == nil {
+		t.Error("Expected an error for an unknown markup element")
+	}
+
+	// texts don't draw spans, so they can't have any
+	txt := XMLText{XMLLabel: lab}
+	txt.Markup = "a <b>b</b>"
+	if _, err := txt.parse(data.Vector{X: 100, Y: 100}, testPlugin); err == nil {
+		t.Error("Expected an error for markup in a text")
+	}
+}
+
+func TestParseBox(t *testing.T) {
+	staticItems = make(map[uint]*data.Item)
+	prevItemContent = make(map[uint]*data.Item)
+
+	var win XMLWindow
+	err := xml.Unmarshal([]byte(`<window>
+		<hbox width="100" height="20" spacing="$pad" align="start" justify="end">
+			<unicolor width="10" grow="1" basis="20%">#FFFFFF</unicolor>
+			<label width="30" shrink="0" textsize="10" fgcolor="#000000">a</label>
+			<unicolor width="10">#000000</unicolor>
+		</hbox>
+	</window>`), &win)
+	if err != nil {
+		t.Fatal(err)
+	}
+	win.XMLBaseContainer.assignUIDs()
+
+	cont, err := win.parseToCont(data.Vector{X: 200, Y: 100}, testPlugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	box, ok := cont.GetItem(0).(*data.Box)
+	if !ok {
+		t.Fatal("Expected a *data.Box, gave ", cont.GetItem(0))
+	}
+	if box.Vertical || box.Spacing != 4 || box.Align != data.LEFT || box.Justify != data.JustifyEnd {
+		t.Error("Expected a horizontal box with spacing 4, aligned at the start, gave ", box)
+	}
+
+	// the items are in the order of the file
+	if _, ok := box.GetItem(1).(*data.Label); !ok {
+		t.Error("Expected the label to be the second item, gave ", box.GetItem(1))
+	}
+
+	// the first item grows from its basis to fill the box
+	if size := box.GetItem(0).GetSize(); size.X != 52 {
+		t.Error("Expected the first item to be 52 wide, gave ", size.X)
+	}
+
+	// parsing again doesn't mark the resized items as changed
+	cont, _ = win.parseToCont(data.Vector{X: 200, Y: 100}, testPlugin)
+	if cont.GetItem(0).(*data.Box).GetItem(0).HasChanged() {
+		t.Error("Expected the first item to be unchanged")
+	}
+}
+
+func TestItemOrder(t *testing.T) {
+	var win XMLWindow
+	err := xml.Unmarshal([]byte(`<window>
+		<unicolor>#FFFFFF</unicolor>
+		<vbox>
+			<unicolor>#FFFFFF</unicolor>
+			<label>a</label>
+		</vbox>
+		<label>b</label>
+	</window>`), &win)
+	if err != nil {
+		t.Fatal(err)
+	}
+	win.XMLBaseContainer.assignUIDs()
+
+	// containers draw their items ordered by element, so
+	// labels are drawn below everything else
+	items := win.items()
+	if _, ok := items[0].(XMLLabel); !ok {
+		t.Error("Expected the label to be the first item of the window, gave ", items)
+	}
+
+	// boxes lay out their items in the order of the file
+	items = win.VBoxes[0].items()
+	if _, ok := items[0].(XMLUnicolor); !ok {
+		t.Error("Expected the unicolor to be the first item of the box, gave ", items)
+	}
+}
+
+func TestParseFailureKeepsChanges(t *testing.T) {
+	staticItems = make(map[uint]*data.Item)
+	prevItemContent = make(map[uint]*data.Item)
+	bounds.W, bounds.H = 200, 100
+
+	shade, width := "#FF0000", "10"
+	backend.RegisterBackend("/test/failing.so", &backend.Memory{
+		Variables: map[string]*string{"shade": &shade, "width": &width},
+	})
+	defer func(prev string) { mainplugin = prev }(mainplugin)
+	SetBackend("/test/failing.so")
+
+	var win XMLWindow
+	err := xml.Unmarshal([]byte(`<window>
+		<unicolor width="10" height="10">$shade</unicolor>
+		<unicolor width="$width" height="10">#000000</unicolor>
+	</window>`), &win)
+	if err != nil {
+		t.Fatal(err)
+	}
+	win.XMLBaseContainer.assignUIDs()
+
+	if _, err := win.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the window can't be parsed, so the red item stays on screen
+	shade, width = "#00FF00", "wide"
+	if _, err := win.Parse(); err == nil {
+		t.Fatal("Expected an error for an invalid width")
+	}
+
+	width = "10"
+	cont, err := win.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cont.GetItem(0).HasChanged() {
+		t.Error("Expected the item to be changed compared to the last drawn frame")
+	}
+}
+
+func TestParseGrid(t *testing.T) {
+	grid := XMLGrid{Columns: "3", ColumnGap: "$pad"}
+	grid.Unicolors = []XMLUnicolor{
+		{XMLBase: XMLBase{UID: 1, ColSpan: "2", Row: "2"}},
+	}
+
+	cont, err := grid.parseToCont(data.Vector{X: 100, Y: 100}, testPlugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := cont.(*data.Grid)
+	if result.Columns != 3 || result.ColumnGap != 4 {
+		t.Error("Expected 3 columns with a gap of 4, gave ", result.Columns, ", ", result.ColumnGap)
+	}
+
+	cell := result.GetItem(0).GetGridCell()
+	if cell != (data.GridCell{Row: 2, ColumnSpan: 2}) {
+		t.Error("Expected row 2 spanning 2 columns, gave ", cell)
+	}
+
+	grid.Unicolors[0].RowSpan = "-1"
+	if _, err := grid.parseToCont(data.Vector{X: 100, Y: 100}, testPlugin); err == nil {
+		t.Error("Expected an error for a negative span")
+	}
+}
+
+func TestParseShapes(t *testing.T) {
+	psize := data.Vector{X: 100, Y: 50}
+
+	poly := XMLPolygon{Points: "0,0; $width,100%; 100% - 10,min(10, 20);"}
+	poly.Height = "20"
+	item, err := poly.parse(psize, testPlugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	polygon := item.(*data.Polygon)
+	expected := []data.Vector{{X: 0, Y: 0}, {X: 50, Y: 20}, {X: 90, Y: 10}}
+	for i := range expected {
+		if i >= len(polygon.Points) || polygon.Points[i] != expected[i] {
+			t.Fatal("Expected ", expected, ", gave ", polygon.Points)
+		}
+	}
+	if polygon.Fill != 0xFF000000 || polygon.Stroke != 0 || polygon.StrokeWidth != 1 {
+		t.Error("Expected a black fill without stroke, gave ", polygon.Shape)
+	}
+
+	// lines have a black stroke by default
+	line, err := XMLLine{X2: "100%", Y2: "50%"}.parse(psize, testPlugin)
+	if err != nil || line.(*data.Line).To != (data.Vector{X: 100, Y: 25}) || line.(*data.Line).Stroke != 0xFF000000 {
+		t.Error("Expected a black line to {100 25}, gave ", line, err)
+	}
+
+	grad := XMLGradient{Type: "radial", Angle: "45", Stops: []XMLStop{{Color: "#FF0000"}, {Color: "#00FF00"}, {Offset: "25%", Color: "#0000FF"}}}
+	item, err = grad.parse(psize, testPlugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stops without offset are spread, stops can't go back
+	gradient := item.(*data.Gradient)
+	if !gradient.Radial || gradient.Angle != 45 || len(gradient.Stops) != 3 ||
+		gradient.Stops[1].Offset != 0.5 || gradient.Stops[2].Offset != 0.5 {
+		t.Error("Expected a radial gradient with stops at 0, 0.5 and 0.5, gave ", gradient)
+	}
+
+	if _, err := (XMLPolygon{Points: "1,2,3"}).parse(psize, testPlugin); err == nil {
+		t.Error("Expected an error for an invalid point")
+	}
+	if _, err := (XMLGradient{Type: "@missing()"}).parse(psize, testPlugin); err == nil {
+		t.Error("Expected an error for an invalid gradient type")
+	}
+}
+
+func TestParseMeter(t *testing.T) {
+	psize := data.Vector{X: 100, Y: 20}
+
+	// percentages are relative to the range, thresholds are sorted
+	prog := XMLProgress{Orientation: "vertical"}
+	prog.Value, prog.Min, prog.Max = "$size", "10", "$size * 3"
+	prog.Thresholds = []XMLThreshold{{Below: "50%", Color: "#00FF00"}, {Below: "25%", Color: "#FF0000"}}
+	item, err := prog.parse(psize, testPlugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	progress := item.(*data.Progress)
+	if !progress.Vertical || progress.Value != 12 || progress.Min != 10 || progress.Max != 36 {
+		t.Error("Expected a vertical bar at 12 of 10 to 36, gave ", progress)
+	}
+	if len(progress.Thresholds) != 2 || progress.Thresholds[0].Below != 16.5 || progress.Thresholds[1].Below != 23 {
+		t.Error("Expected thresholds below 16.5 and 23, gave ", progress.Thresholds)
+	}
+	if progress.FillColor() != 0xFF0000FF {
+		t.Error("Expected red, gave ", progress.FillColor())
+	}
+
+	gauge := XMLGauge{Sweep: "180"}
+	gauge.Value = "50%"
+	item, err = gauge.parse(psize, testPlugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := item.(*data.Gauge); g.Value != 50 || g.Start != 135 || g.Sweep != 180 || g.Color != 0xFFFFFFFF {
+		t.Error("Expected a white gauge at 50 from 135 over 180 degrees, gave ", g)
+	}
+
+	item, err = XMLLevelBar{}.parse(psize, testPlugin)
+	if err != nil || item.(*data.LevelBar).Blocks != 5 {
+		t.Error("Expected 5 blocks, gave ", item, err)
+	}
+
+	if _, err := (XMLProgress{Orientation: "diagonal"}).parse(psize, testPlugin); err == nil {
+		t.Error("Expected an error for an invalid orientation")
+	}
+}
+
+func TestEvalText(t *testing.T) {
+	tests := map[string]string{
+		"\tplain\n":         "plain",
+		"$width":            "50%",
+		"@GetAlign()":       "right",
+		"$size > 10 ? 1:2":  "1",
+		"not $ a reference": "not $ a reference",
+
+		// values of the backend looking like numbers stay unchanged
+		"$date":  "2024-01-05",
+		"$price": "1.50",
+	}
+
+	for source, expected := range tests {
+		result, err := evalText(source, testPlugin)
+		if err != nil || result != expected {
+			t.Error("Expected ", expected, ", gave ", result, err)
+		}
+	}
+
+	// compiling twice gives the same expression
+	if getExpression("$width") != getExpression("$width") {
+		t.Error("Expected the compiled expression to be reused")
+	}
+
+	// a missing backend is an error, not a crash
+	if _, err := evalText("$width", "/test/missing.so"); err == nil {
+		t.Error("Expected an error for a missing backend")
+	}
+}
+
+// builds a window with 10 containers of 49 items each, 500 elements in total.
+// All values are different, so no element can reuse the expressions of another
+func newBenchmarkWindow() (win XMLWindow) {
+	for i := 0; i < 10; i++ {
+		var cont XMLBaseContainer
+		cont.Y = strconv.Itoa(i*10) + "%"
+		cont.Height = "10%"
+		cont.Color = "#202020"
+
+		for j := 0; j < 24; j++ {
+			n := strconv.Itoa(i*100 + j)
+
+			var lab XMLLabel
+			lab.X = "$pad + " + n
+			lab.Width = "clamp(2*$size, " + n + ", 40)"
+			lab.TextSize = "$size + " + n
+			lab.FGColor = "#ffffff"
+			lab.HAlign = "@GetAlign(" + n + ")"
+			lab.Text = "$width"
+			cont.Labels = append(cont.Labels, lab)
+
+			var uni XMLUnicolor
+			uni.X = n + "% + $pad"
+			uni.Width = "50% - " + n + "*$pad"
+			uni.Color = "#ff0000"
+			cont.Unicolors = append(cont.Unicolors, uni)
+		}
+
+		var lab XMLLabel
+		lab.Bold = "$bold"
+		lab.Text = "container " + strconv.Itoa(i)
+		cont.Labels = append(cont.Labels, lab)
+
+		win.Conts = append(win.Conts, cont)
+	}
+
+	win.XMLBaseContainer.assignUIDs()
+	return
+}
+
+func setupBenchmark(b *testing.B) (win XMLWindow) {
+	SetBackend(testPlugin)
+	bounds.W, bounds.H = 1920, 1080
+	staticItems = make(map[uint]*data.Item)
+	prevItemContent = make(map[uint]*data.Item)
+
+	win = newBenchmarkWindow()
+	compileExpressions(&win)
+
+	if _, err := win.Parse(); err != nil {
+		b.Fatal(err)
+	}
+
+	return
+}
+
+// parses a window the way the main loop does every frame
+func BenchmarkParse(b *testing.B) {
+	win := setupBenchmark(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		win.Parse()
+	}
+}
+
+// gets all values of the benchmark window which are valid expressions
+func getBenchmarkSources(win *XMLWindow) (sources []string) {
+	walkSources(reflect.ValueOf(win), func(source string) {
+		if expr := getExpression(source); expr.expr != nil {
+			sources = append(sources, source)
+		}
+	})
+
+	return
+}
+
+// evaluates all values of the window the way Parse does every frame
+func BenchmarkEval(b *testing.B) {
+	win := setupBenchmark(b)
+	sources := getBenchmarkSources(&win)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, source := range sources {
+			getExpression(source).eval(testPlugin)
+		}
+	}
+}
+
+// evaluates all values of the window, parsing them again every time
+// like it was done before expressions were compiled
+func BenchmarkEvalUncompiled(b *testing.B) {
+	win := setupBenchmark(b)
+	sources := getBenchmarkSources(&win)
+	plug, err := backend.GetBackend(testPlugin)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, source := range sources {
+			backend.Evaluate(cleanString(source), plug)
+		}
+	}
+}
+
+func TestPlayAnimation(t *testing.T) {
+	current := time.Unix(0, 0)
+	now = func() time.Time { return current }
+	defer func() { now = transition.Now }()
+
+	playAnimation(1, "a.gif", true)
+	current = current.Add(time.Second)
+
+	// paused animations keep the time they were played
+	if elapsed := playAnimation(1, "a.gif", false); elapsed != time.Second {
+		t.Error("Expected 1s, gave ", elapsed)
+	}
+	current = current.Add(time.Second)
+	if elapsed := playAnimation(1, "a.gif", true); elapsed != time.Second {
+		t.Error("Expected 1s while paused, gave ", elapsed)
+	}
+
+	// another image starts again
+	current = current.Add(time.Second)
+	if elapsed := playAnimation(1, "b.gif", true); elapsed != 0 {
+		t.Error("Expected 0 for another image, gave ", elapsed)
+	}
+}
+
+func TestParseTransitions(t *testing.T) {
+	base := XMLBase{Transition: "x 200ms ease-out, color 1.5s cubic-bezier(0.4, 0, 0.2, 1) 50ms"}
+	timings, err := base.parseTransitions(testPlugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if x := timings["x"]; x.Duration != 200*time.Millisecond || x.Delay != 0 || x.Easing == nil {
+		t.Error("Expected 200ms eased out, gave ", x)
+	}
+	if color := timings["color"]; color.Duration != 1500*time.Millisecond || color.Delay != 50*time.Millisecond {
+		t.Error("Expected 1.5s after 50ms, gave ", color)
+	}
+
+	base.Transition = "all 1s"
+	if timings, err = base.parseTransitions(testPlugin); err != nil || len(timings) != len(transitionProperties) {
+		t.Error("Expected all properties, gave ", timings, err)
+	}
+
+	for _, invalid := range []string{"rotation 1s", "x", "x 1s 2s 3s", "x 1h", "x 1s cubic-bezier(2, 0, 0, 1)"} {
+		base.Transition = invalid
+		if _, err := base.parseTransitions(testPlugin); err == nil {
+			t.Error("Expected an error for ", invalid)
+		}
+	}
+}
+
+func TestApplyTransitions(t *testing.T) {
+	current := time.Unix(0, 0)
+	now = func() time.Time { return current }
+	defer func() { now = transition.Now }()
+	transitions = make(map[uint]map[string]*propertyTransition)
+
+	xmlitem := XMLUnicolor{}
+	xmlitem.Transition = "x 1s linear, color 1s linear"
+	frame := func(x int32, color uint32) *data.Unicolor {
+		item := &data.Unicolor{Color: color}
+		item.UID = 1
+		item.Position = data.Vector{X: x}
+		if err := applyTransitions(xmlitem, item, testPlugin); err != nil {
+			t.Fatal(err)
+		}
+		return item
+	}
+
+	// the first frame shows its values right away
+	if item := frame(0, 0xFF0000FF); item.Position.X != 0 || item.Color != 0xFF0000FF {
+		t.Error("Expected red at 0, gave ", item)
+	}
+
+	current = current.Add(500 * time.Millisecond)
+	frame(100, 0xFFFF0000)
+	current = current.Add(500 * time.Millisecond)
+	if item := frame(100, 0xFFFF0000); item.Position.X != 50 || item.Color != 0xFF800080 {
+		t.Error("Expected purple at 50, gave ", item)
+	}
+
+	// a new target starts from the value shown
+	frame(0, 0xFFFF0000)
+	current = current.Add(500 * time.Millisecond)
+	if item := frame(0, 0xFFFF0000); item.Position.X != 25 {
+		t.Error("Expected 25, gave ", item.Position.X)
+	}
+
+	current = current.Add(time.Second)
+	if item := frame(0, 0xFFFF0000); item.Position.X != 0 || item.Color != 0xFFFF0000 {
+		t.Error("Expected blue at 0, gave ", item)
+	}
+}
+
+func TestParseEntry(t *testing.T) {
+	entryContents = make(map[uint]string)
+	input.ResetEntries()
+	defer input.ResetEntries()
+
+	ent := XMLEntry{TextSize: "12", Password: "true", OnChange: "Search", Text: "$query"}
+	ent.UID = 1
+	query = "abc"
+
+	parse := func() *data.Entry {
+		item, err := ent.parse(data.Vector{X: 100, Y: 20}, testPlugin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return item.(*data.Entry)
+	}
+
+	entry := parse()
+	if entry.Text != "abc" || entry.Cursor != 3 || entry.Focused || !entry.Password {
+		t.Error("Expected the password abc with the cursor at 3, gave ", entry)
+	}
+	if entry.PlaceholderColor != 0x7F000000 {
+		t.Errorf("Expected a half transparent placeholder, gave 0x%x", entry.PlaceholderColor)
+	}
+
+	// typed text is kept and reported to the backend
+	input.Focus(1)
+	input.FocusedEntry().Type("d")
+	if entry = parse(); entry.Text != "abcd" || !entry.Focused || searched != "abcd" {
+		t.Error("Expected abcd to be typed, gave ", entry.Text, " and ", searched)
+	}
+
+	// the backend storing the typed text doesn't move the cursor
+	input.FocusedEntry().MoveTo(1, false)
+	input.FocusedEntry().Type("0")
+	query = searched
+	if entry = parse(); entry.Text != "a0bcd" || entry.Cursor != 2 {
+		t.Error("Expected a0bcd with the cursor at 2, gave ", entry.Text, " at ", entry.Cursor)
+	}
+
+	// a new content replaces the typed text
+	query = "xyz"
+	if entry = parse(); entry.Text != "xyz" {
+		t.Error("Expected xyz, gave ", entry.Text)
+	}
+
+	// the entry is kept while it is parsed and removed once it isn't
+	pruneEntries()
+	if !input.IsFocused(1) {
+		t.Error("Expected the parsed entry to stay focused")
+	}
+	pruneEntries()
+	if _, ok := entryContents[1]; ok || input.IsFocused(1) {
+		t.Error("Expected the entry to be removed")
+	}
+}
+
+func TestCacheSize(t *testing.T) {
+	sizes := make(map[data.Vector]*sdl.Surface)
+	for i := int32(0); i < maxImageSizes; i++ {
+		cacheSize(sizes, data.Vector{X: i, Y: i}, nil)
+	}
+	if len(sizes) != maxImageSizes {
+		t.Fatal("Expected ", maxImageSizes, " sizes, gave ", len(sizes))
+	}
+
+	// a full cache is emptied before the next size is kept
+	cacheSize(sizes, data.Vector{X: -1, Y: -1}, nil)
+	if _, ok := sizes[data.Vector{X: -1, Y: -1}]; len(sizes) != 1 || !ok {
+		t.Error("Expected only the last size to be kept, gave ", sizes)
+	}
+}
+
+func TestReloadXMLFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fliwreload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the schemas are looked up relative to the root of the repository
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir("..")
+
+	defer func(prev string) { dirpath = prev }(dirpath)
+	dirpath = dir
+	links = make(map[string]*XMLExtension)
+
+	write := func(name string, content string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("style.xml", `<window><link>ext.xml</link></window>`)
+	write("ext.xml", `<extension backend="ext.so"><unicolor>#FF0000</unicolor></extension>`)
+	if _, err := ReloadXMLFile(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	extpath := resolvePath(dir, "ext.xml")
+	ext := links[extpath]
+	if len(links) != 1 || ext == nil {
+		t.Fatal("Expected the extension to be loaded, gave ", links)
+	}
+
+	// files that are not valid change nothing
+	unchanged := func(reason string) {
+		if len(links) != 1 || links[extpath] != ext {
+			t.Error("Expected the links to be kept ", reason, ", gave ", links)
+		}
+		if _, ok := expressions["#00FF00"]; ok {
+			t.Error("Expected the expressions to be kept ", reason)
+		}
+	}
+
+	write("style.xml", `<window><unknown>#00FF00</unknown></window>`)
+	if _, err := ReloadXMLFile([]string{filepath.Join(dir, "style.xml")}); err == nil {
+		t.Error("Expected an error for a window that is not valid")
+	}
+	unchanged("for a window that is not valid")
+
+	write("style.xml", `<window><unicolor>#00FF00</unicolor><link>ext.xml</link></window>`)
+	write("ext.xml", `<extension backend="ext.so"><unknown/></extension>`)
+	if _, err := ReloadXMLFile([]string{filepath.Join(dir, "ext.xml")}); err == nil {
+		t.Error("Expected an error for an extension that is not valid")
+	}
+	unchanged("for an extension that is not valid")
+
+	// links in boxes and grids are checked too
+	for _, parent := range []string{"hbox", "vbox", "grid"} {
+		write("style.xml", `<window><`+parent+`><link>ext.xml</link></`+parent+`></window>`)
+		if _, err := ReloadXMLFile([]string{filepath.Join(dir, "style.xml")}); err == nil {
+			t.Error("Expected an error for an extension in a ", parent, " that is not valid")
+		}
+		unchanged("for an extension in a " + parent + " that is not valid")
+	}
+
+	write("ext.xml", `<extension backend="ext.so"><unicolor>#0000FF</unicolor></extension>`)
+	if _, err := ReloadXMLFile([]string{filepath.Join(dir, "ext.xml")}); err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 1 || links[extpath] == nil || links[extpath] == ext {
+		t.Error("Expected the extension in the grid to be loaded again, gave ", links)
+	}
+
+	// the events of the reloaded window go to its new handlers
+	var called string
+	backend.RegisterBackend("/test/reload.so", &backend.Memory{Functions: map[string]func(...string) string{
+		"First":  func(...string) string { called = "First"; return "" },
+		"Second": func(...string) string { called = "Second"; return "" },
+	}})
+	defer func(prev string) { mainplugin = prev }(mainplugin)
+	SetBackend("/test/reload.so")
+	staticItems = make(map[uint]*data.Item)
+	prevItemContent = make(map[uint]*data.Item)
+
+	for _, function := range []string{"First", "Second"} {
+		write("style.xml", `<window onevent="mouseclick:`+function+`()"/>`)
+		window, err := ReloadXMLFile([]string{filepath.Join(dir, "style.xml")})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cont, err := window.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		backend.Init(cont.(*data.BaseContainer))
+		backend.Invoke(backend.Event{Name: backend.MouseclickEvent})
+
+		if called != function {
+			t.Error("Expected ", function, " to be called, gave ", called)
+		}
+	}
+}
+
+func TestBackendValuesNotCompiled(t *testing.T) {
+	color, padding := "", ""
+	backend.RegisterBackend("/test/dynamic.so", &backend.Memory{
+		Variables: map[string]*string{"color": &color, "padding": &padding},
+	})
+
+	// values of the backend changing every frame don't fill the cache
+	compiled := -1
+	for i := 0; i < 3; i++ {
+		color, padding = "#0000"+strconv.Itoa(10+i), strconv.Itoa(i)+" "+strconv.Itoa(i+1)
+
+		if result, err := parseShapeColor("$color", 0, "/test/dynamic.so"); err != nil || result != uint32(0xFF100000+i<<16) {
+			t.Errorf("Expected 0x%x, gave 0x%x %v", 0xFF100000+i<<16, result, err)
+		}
+		expected := data.Edges{Top: int32(i), Right: int32(i + 1), Bottom: int32(i), Left: int32(i + 1)}
+		if edges, err := parseEdges("$padding", "/test/dynamic.so"); err != nil || edges != expected {
+			t.Error("Expected ", expected, ", gave ", edges, err)
+		}
+
+		if compiled >= 0 && len(expressions) != compiled {
+			t.Error("Expected ", compiled, " compiled expressions, gave ", len(expressions))
+		}
+		compiled = len(expressions)
+	}
+}
+
+func TestParseWindowColor(t *testing.T) {
+	staticItems = make(map[uint]*data.Item)
+	prevItemContent = make(map[uint]*data.Item)
+	defer func(prev string) { mainplugin = prev }(mainplugin)
+	SetBackend(testPlugin)
+
+	// the color of the last window parsed is not kept
+	tests := map[string]uint32{"#FF0000": 0xFF0000FF, "": opaqueBlack}
+	for color, expected := range tests {
+		bgcolor = 0x12345678
+
+		var win XMLWindow
+		win.Color = color
+
+		cont, err := win.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cont.GetBGcolor() != expected {
+			t.Errorf("Expected 0x%x for '%s', gave 0x%x", expected, color, cont.GetBGcolor())
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package transition
+
+import (
+	"math"
+	"time"
+)
+
+/*
+##############################################################
+# Section: Frame clock
+##############################################################
+*/
+
+// the time of the frame being drawn, zero if no frame was drawn yet
+var frameTime time.Time
+
+// Tick sets the time of the frame that is drawn next.
+// Everything changing over time uses this time while the frame is drawn,
+// so all items of a frame move in step
+func Tick(t time.Time) {
+	frameTime = t
+}
+
+// Now gets the time of the frame being drawn,
+// or the current time if there is no frame yet
+func Now() time.Time {
+	if frameTime.IsZero() {
+		return time.Now()
+	}
+
+	return frameTime
+}
+
+/*
+##############################################################
+# Section: Easing
+##############################################################
+*/
+
+// Easing maps how much of the time of a transition has passed
+// to how far the value has moved, both from 0 to 1
+type Easing func(t float64) float64
+
+// the easing functions of css
+var (
+	Linear    Easing = func(t float64) float64 { return t }
+	Ease             = CubicBezier(0.25, 0.1, 0.25, 1)
+	EaseIn           = CubicBezier(0.42, 0, 1, 1)
+	EaseOut          = CubicBezier(0, 0, 0.58, 1)
+	EaseInOut        = CubicBezier(0.42, 0, 0.58, 1)
+)
+
+// Easings are the easing functions by their css name
+var Easings = map[string]Easing{
+	"linear":      Linear,
+	"ease":        Ease,
+	"ease-in":     EaseIn,
+	"ease-out":    EaseOut,
+	"ease-in-out": EaseInOut,
+}
+
+// CubicBezier gets an easing function following a cubic bézier curve
+// from (0, 0) to (1, 1) with the control points (x1, y1) and (x2, y2),
+// like cubic-bezier() in css. x1 and x2 have to be between 0 and 1
+func CubicBezier(x1 float64, y1 float64, x2 float64, y2 float64) Easing {
+	// the coordinate of the curve at parameter s
+	curve := func(s float64, p1 float64, p2 float64) float64 {
+		return 3*(1-s)*(1-s)*s*p1 + 3*(1-s)*s*s*p2 + s*s*s
+	}
+
+	return func(t float64) float64 {
+		if t <= 0 || t >= 1 {
+			return math.Max(0, math.Min(1, t))
+		}
+
+		// find the parameter at which the curve is at t by bisection,
+		// which works as x only grows along the curve
+		low, high := 0.0, 1.0
+		for i := 0; i < 32; i++ {
+			mid := (low + high) / 2
+			if curve(mid, x1, x2) < t {
+				low = mid
+			} else {
+				high = mid
+			}
+		}
+
+		return curve((low+high)/2, y1, y2)
+	}
+}
+
+/*
+##############################################################
+# Section: Timing & Tweens
+##############################################################
+*/
+
+// Timing is how long a transition takes and how its value moves
+type Timing struct {
+	Duration time.Duration
+
+	// the time waited before the transition starts
+	Delay time.Duration
+
+	// the easing of the value, Ease if nil
+	Easing Easing
+}
+
+// Progress gets how far a transition started at start has moved at now,
+// from 0 to 1. Some easing functions can go a bit beyond that
+func (timing Timing) Progress(start time.Time, now time.Time) float64 {
+	elapsed := now.Sub(start) - timing.Delay
+	if elapsed <= 0 {
+		return 0
+	}
+	if elapsed >= timing.Duration {
+		return 1
+	}
+
+	easing := timing.Easing
+	if easing == nil {
+		easing = Ease
+	}
+
+	return easing(float64(elapsed) / float64(timing.Duration))
+}
+
+// Done tells wether a transition started at start is finished at now
+func (timing Timing) Done(start time.Time, now time.Time) bool {
+	return now.Sub(start) >= timing.Delay+timing.Duration
+}
+
+// Lerp gets the value a share t of the way from a to b
+func Lerp(a float64, b float64, t float64) float64 {
+	return a + (b-a)*t
+}
+
+// Tween is a number moving from one value to another over time.
+// Backends can return its value from a variable, e.g.
+//
+//	tween := transition.NewTween(0, 100, transition.Timing{Duration: time.Second})
+//	func GetWidth() string { return fmt.Sprint(tween.Value()) }
+type Tween struct {
+	From  float64
+	To    float64
+	Start time.Time
+	Timing
+}
+
+// NewTween creates a tween starting at the time of the current frame
+func NewTween(from float64, to float64, timing Timing) *Tween {
+	return &Tween{From: from, To: to, Start: Now(), Timing: timing}
+}
+
+// Value gets the value of the tween at the time of the current frame
+func (tween *Tween) Value() float64 {
+	return Lerp(tween.From, tween.To, tween.Progress(tween.Start, Now()))
+}
+
+// Done tells wether the tween reached its end value
+func (tween *Tween) Done() bool {
+	return tween.Timing.Done(tween.Start, Now())
+}
+
+// Retarget lets the tween move from its current value to another one,
+// starting at the time of the current frame
+func (tween *Tween) Retarget(to float64) {
+	tween.From, tween.To, tween.Start = tween.Value(), to, Now()
+}
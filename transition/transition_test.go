@@ -0,0 +1,61 @@
+package transition
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEasings(t *testing.T) {
+	for name, easing := range Easings {
+		if easing(0) != 0 || easing(1) != 1 {
+			t.Error("Expected ", name, " to go from 0 to 1, gave ", easing(0), " to ", easing(1))
+		}
+	}
+
+	if value := EaseInOut(0.5); math.Abs(value-0.5) > 0.001 {
+		t.Error("Expected 0.5, gave ", value)
+	}
+	if EaseIn(0.25) >= 0.25 || EaseOut(0.25) <= 0.25 {
+		t.Error("Expected ease-in to start slow and ease-out fast, gave ", EaseIn(0.25), " and ", EaseOut(0.25))
+	}
+}
+
+func TestProgress(t *testing.T) {
+	start := time.Unix(0, 0)
+	timing := Timing{Duration: time.Second, Delay: time.Second, Easing: Linear}
+
+	if progress := timing.Progress(start, start.Add(time.Second)); progress != 0 {
+		t.Error("Expected 0 after the delay, gave ", progress)
+	}
+	if progress := timing.Progress(start, start.Add(1500*time.Millisecond)); progress != 0.5 {
+		t.Error("Expected 0.5, gave ", progress)
+	}
+	if timing.Done(start, start.Add(1500*time.Millisecond)) || !timing.Done(start, start.Add(2*time.Second)) {
+		t.Error("Expected to be done after 2s")
+	}
+}
+
+func TestTween(t *testing.T) {
+	start := time.Unix(0, 0)
+	Tick(start)
+	defer Tick(time.Time{})
+
+	tween := NewTween(0, 100, Timing{Duration: time.Second, Easing: Linear})
+	Tick(start.Add(500 * time.Millisecond))
+	if value := tween.Value(); value != 50 {
+		t.Error("Expected 50, gave ", value)
+	}
+
+	// a new target is moved to from the current value
+	tween.Retarget(0)
+	Tick(start.Add(time.Second))
+	if value := tween.Value(); value != 25 {
+		t.Error("Expected 25, gave ", value)
+	}
+
+	Tick(start.Add(1500 * time.Millisecond))
+	if value := tween.Value(); value != 0 || !tween.Done() {
+		t.Error("Expected 0 when done, gave ", value)
+	}
+}
@@ -0,0 +1,48 @@
+package xdg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+finds the directories of the user the way the xdg base directory
+specification describes, see
+https://specifications.freedesktop.org/basedir-spec/latest/
+*/
+
+// ExpandHome replaces a leading ~ of a path with the home directory
+// of the user. The path is kept if there is no home directory
+func ExpandHome(path string) string {
+	if !strings.HasPrefix(path, "~") {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, path[1:])
+}
+
+// DataHome gets the directory user specific data is stored in
+func DataHome() string {
+	return dirFromEnv("XDG_DATA_HOME", "~/.local/share")
+}
+
+// ConfigHome gets the directory user specific configs are stored in
+func ConfigHome() string {
+	return dirFromEnv("XDG_CONFIG_HOME", "~/.config")
+}
+
+// gets the directory set in an environment variable,
+// or def if it isn't set
+func dirFromEnv(name string, def string) string {
+	if dir := os.Getenv(name); dir != "" {
+		return dir
+	}
+
+	return ExpandHome(def)
+}
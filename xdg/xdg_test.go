@@ -0,0 +1,41 @@
+package xdg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandHome(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	tests := map[string]string{
+		"~":           home,
+		"~/.config":   filepath.Join(home, ".config"),
+		"/usr/share":  "/usr/share",
+		"fonts/~test": "fonts/~test",
+	}
+
+	for path, expected := range tests {
+		if expanded := ExpandHome(path); expanded != expected {
+			t.Error("Expected ", expected, ", gave ", expanded)
+		}
+	}
+}
+
+func TestDataHome(t *testing.T) {
+	defer os.Setenv("XDG_DATA_HOME", os.Getenv("XDG_DATA_HOME"))
+
+	os.Setenv("XDG_DATA_HOME", "/tmp/data")
+	if dir := DataHome(); dir != "/tmp/data" {
+		t.Error("Expected /tmp/data, gave ", dir)
+	}
+
+	os.Setenv("XDG_DATA_HOME", "")
+	if dir := DataHome(); dir != ExpandHome("~/.local/share") {
+		t.Error("Expected ", ExpandHome("~/.local/share"), ", gave ", dir)
+	}
+}